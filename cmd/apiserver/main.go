@@ -1,10 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/abhigod/k8s-lite/internal/apiserver"
 	"github.com/abhigod/k8s-lite/internal/storage"
@@ -14,6 +24,22 @@ func main() {
 	var dataFile string
 	flag.StringVar(&dataFile, "data-file", "k8s-lite.db", "Path to data file for persistence")
 
+	var storageBackend string
+	flag.StringVar(&storageBackend, "storage-backend", "memory", "Storage backend: memory (in-memory map with a write-ahead log), bolt (embedded bbolt database) or raft (replicated across apiservers)")
+
+	var raftID, raftBind, raftDir, raftPeers, raftSecretFile string
+	flag.StringVar(&raftID, "raft-id", "", "Raft member ID of this apiserver")
+	flag.StringVar(&raftBind, "raft-bind", "127.0.0.1:7000", "Address the raft members talk to this one on; connections from hosts that aren't members, or that don't know the raft secret, are refused")
+	flag.StringVar(&raftDir, "raft-dir", "k8s-lite-raft", "Directory for the raft log and snapshots")
+	flag.StringVar(&raftPeers, "raft-peers", "", "Comma-separated raft members as id@raftAddr, including this one")
+	flag.StringVar(&raftSecretFile, "raft-secret-file", "", "File holding the secret every raft member shares")
+
+	var restoreFrom string
+	var restoreDryRun, restoreForce bool
+	flag.StringVar(&restoreFrom, "restore-from", "", "Replace the stored state with this backup (from kubectl-lite backup) before serving")
+	flag.BoolVar(&restoreDryRun, "restore-dry-run", false, "With --restore-from, only validate the backup and report what it holds")
+	flag.BoolVar(&restoreForce, "restore-force", false, "With --restore-from, restore even over a memory or bolt store that already holds objects, discarding them")
+
 	var tlsCert, tlsKey, tlsCA string
 	flag.StringVar(&tlsCert, "tls-cert", "", "Path to server certificate")
 	flag.StringVar(&tlsKey, "tls-key", "", "Path to server key")
@@ -23,11 +49,90 @@ func main() {
 
 	log.Println("Starting K8s-Lite API Server...")
 
+	var backup *storage.Backup
+	if restoreFrom != "" {
+		var err error
+		if backup, err = readBackup(restoreFrom); err != nil {
+			log.Fatalf("Failed to read backup %s: %v", restoreFrom, err)
+		}
+		log.Printf("Backup %s holds %d objects at revision %d", restoreFrom, len(backup.Objects), backup.Revision)
+		counts := backup.Counts()
+		var resources []string
+		for r := range counts {
+			resources = append(resources, r)
+		}
+		sort.Strings(resources)
+		for _, r := range resources {
+			log.Printf("  %s: %d", r, counts[r])
+		}
+		if restoreDryRun {
+			log.Println("Dry run, not restoring")
+			return
+		}
+	}
+
 	// 1. Initialize Storage (File-backed)
-	store := storage.NewMemoryStore(dataFile)
+	var store storage.Store
+	var err error
+	switch storageBackend {
+	case "memory":
+		store, err = storage.NewMemoryStore(dataFile)
+	case "bolt":
+		store, err = storage.NewBoltStore(dataFile)
+	case "raft":
+		config := storage.RaftConfig{ID: raftID, BindAddr: raftBind, Dir: raftDir}
+		if config.ID == "" {
+			log.Fatalf("--raft-id is required with the raft storage backend")
+		}
+		if config.Peers, err = parseRaftPeers(raftPeers); err != nil {
+			log.Fatalf("Invalid --raft-peers: %v", err)
+		}
+		if raftSecretFile == "" {
+			log.Fatalf("--raft-secret-file is required with the raft storage backend")
+		}
+		secret, err := os.ReadFile(raftSecretFile)
+		if err != nil {
+			log.Fatalf("Failed to read the raft secret: %v", err)
+		}
+		if config.Secret = bytes.TrimSpace(secret); len(config.Secret) == 0 {
+			log.Fatalf("The raft secret in %s is empty", raftSecretFile)
+		}
+		log.Printf("Joining raft cluster as %s on %s...", raftID, raftBind)
+		store, err = storage.NewRaftStore(config)
+		dataFile = raftDir
+	default:
+		log.Fatalf("Unknown storage backend %q", storageBackend)
+	}
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	log.Printf("Using %s storage at %s", storageBackend, dataFile)
+
+	if backup != nil {
+		// A raft store refuses on its own to restore over existing state.
+		if storageBackend != "raft" {
+			current, err := store.Backup(context.Background())
+			if err != nil {
+				log.Fatalf("Failed to read the existing state: %v", err)
+			}
+			if len(current.Objects) > 0 {
+				if !restoreForce {
+					log.Fatalf("Refusing to restore: %s already holds %d objects at revision %d; pass --restore-force to discard them", dataFile, len(current.Objects), current.Revision)
+				}
+				log.Printf("Discarding %d objects at revision %d in %s", len(current.Objects), current.Revision, dataFile)
+			}
+		}
+		if err := store.Restore(context.Background(), backup); err != nil {
+			log.Fatalf("Failed to restore %s: %v", restoreFrom, err)
+		}
+		log.Printf("Restored %d objects at revision %d from %s", len(backup.Objects), backup.Revision, restoreFrom)
+	}
 
 	// 2. Initialize API Server
 	server := apiserver.NewServer(store)
+	if err := server.MigrateLegacyKeys(context.Background()); err != nil {
+		log.Fatalf("Failed to migrate objects stored before namespaces: %v", err)
+	}
 
 	// 3. Start HTTP Server
 	port := os.Getenv("PORT")
@@ -37,20 +142,73 @@ func main() {
 
 	log.Printf("Listening on port %s", port)
 
+	// Request contexts derive from ctx, so cancelling it ends open watches,
+	// which Shutdown would otherwise wait on.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	httpServer := &http.Server{Addr: ":" + port, Handler: server.Router}
 	if tlsCert != "" && tlsKey != "" && tlsCA != "" {
-		log.Println("Serving with TLS (mTLS enabled)...")
-		if err := server.ServeTLS(":"+port, tlsCert, tlsKey, tlsCA); err != nil {
-			log.Fatalf("Server failed: %v", err)
+		if httpServer, err = server.TLSServer(":"+port, tlsCA); err != nil {
+			log.Fatalf("Failed to configure TLS: %v", err)
 		}
+	}
+	httpServer.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	// Handle signals for graceful shutdown
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		<-c
+		log.Println("Shutting down API Server...")
+		cancel()
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer shutdownCancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to drain requests: %v", err)
+		}
+	}()
+
+	if httpServer.TLSConfig != nil {
+		log.Println("Serving with TLS (mTLS enabled)...")
+		err = httpServer.ListenAndServeTLS(tlsCert, tlsKey)
 	} else {
 		log.Println("Serving insecurely (HTTP)...")
-		if err := http.ListenAndServe(":"+port, server.Router); err != nil {
-			log.Fatalf("Server failed: %v", err)
-		}
+		err = httpServer.ListenAndServe()
 	}
-}
-
-
+	if !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
+	}
+	<-stopped
 
+	if err := store.Close(); err != nil {
+		log.Fatalf("Failed to close storage: %v", err)
+	}
+	log.Println("Storage closed")
+}
 
+// parseRaftPeers parses a comma-separated list of id@raftAddr.
+func parseRaftPeers(s string) ([]storage.RaftPeer, error) {
+	var peers []storage.RaftPeer
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		parts := strings.Split(item, "@")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%q is not id@raftAddr", item)
+		}
+		peers = append(peers, storage.RaftPeer{ID: parts[0], Addr: parts[1]})
+	}
+	return peers, nil
+}
 
+func readBackup(path string) (*storage.Backup, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return storage.ReadBackup(f)
+}
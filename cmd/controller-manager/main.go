@@ -11,9 +11,12 @@ import (
 	"time"
 
 	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/controller"
 	"github.com/abhigod/k8s-lite/internal/controller/deployment"
+	"github.com/abhigod/k8s-lite/internal/controller/namespace"
 	"github.com/abhigod/k8s-lite/internal/controller/replicaset"
 	"github.com/abhigod/k8s-lite/internal/controller/service"
+	"github.com/abhigod/k8s-lite/internal/informer"
 	"github.com/abhigod/k8s-lite/internal/leaderelection"
 )
 
@@ -23,22 +26,30 @@ func main() {
 	tlsKey := flag.String("tls-key", "", "Path to client key")
 	tlsCA := flag.String("tls-ca", "", "Path to CA certificate")
 	leaderElect := flag.Bool("leader-elect", false, "Enable leader election")
+	workers := flag.Int("workers", 2, "Number of workers per controller")
+	resync := flag.Duration("resync-period", 30*time.Second, "How often informers replay their cache to controllers")
 	flag.Parse()
 
 	cli := client.New(*apiURL, *tlsCert, *tlsKey, *tlsCA)
 
 	runControllers := func(ctx context.Context) {
-		// ReplicaSet Controller
-		rsController := replicaset.New(cli)
-		go rsController.Start(ctx)
-
-		// Deployment Controller
-		depController := deployment.New(cli)
-		go depController.Start(ctx)
-
-		// Service Controller
-		svcController := service.NewController(cli)
-		go svcController.Run(ctx)
+		// All controllers share one set of informers.
+		informers := informer.NewFactory(cli, *resync)
+		controllers := []controller.Controller{
+			replicaset.New(cli, informers),
+			deployment.New(cli, informers),
+			service.NewController(cli, informers),
+			namespace.New(cli, informers),
+		}
+
+		informers.Start(ctx)
+		if !informers.WaitForCacheSync(ctx) {
+			return
+		}
+
+		for _, c := range controllers {
+			go c.Run(ctx, *workers)
+		}
 
 		log.Println("Controllers started")
 		<-ctx.Done()
@@ -79,9 +90,3 @@ func main() {
 		runControllers(ctx)
 	}
 }
-
-
-
-
-
-
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/storage"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: kubectl-lite [flags] <command> [args]
+
+Commands:
+  api-resources         List the resources the server serves
+  get RESOURCE [NAME]   List objects by name, or print one as JSON
+                        [-n NAMESPACE] [-A] [-o json] [-chunk-size N]
+  delete RESOURCE NAME  Delete an object [-n NAMESPACE]
+  apply -f FILE         Apply the object in FILE (JSON) on the server
+                        [-field-manager NAME] [-force]
+  backup -o FILE        Save a point-in-time copy of the cluster state
+
+RESOURCE is a resource as listed by api-resources: its name, short name or
+kind, e.g. deployments, deploy or Deployment.
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	apiURL := flag.String("api-url", "http://localhost:8080", "URL of API Server")
+	tlsCert := flag.String("tls-cert", "", "Path to client certificate")
+	tlsKey := flag.String("tls-key", "", "Path to client key")
+	tlsCA := flag.String("tls-ca", "", "Path to CA certificate")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	c := client.New(*apiURL, *tlsCert, *tlsKey, *tlsCA)
+	ctx := context.Background()
+
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "api-resources":
+		apiResources(ctx, c)
+	case "get":
+		get(ctx, c, args)
+	case "delete":
+		deleteObject(ctx, c, args)
+	case "apply":
+		apply(ctx, c, args)
+	case "backup":
+		backup(ctx, c, args)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+}
+
+func apiResources(ctx context.Context, c *client.Client) {
+	resources, err := c.ServerResources(ctx)
+	if err != nil {
+		log.Fatalf("Discovery failed: %v", err)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSHORTNAMES\tAPIVERSION\tNAMESPACED\tKIND\tVERBS")
+	for _, r := range resources {
+		if r.IsSubresource() {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\t%s\n", r.Name, strings.Join(r.ShortNames, ","), r.GroupVersion, r.Namespaced, r.Kind, strings.Join(r.Verbs, ","))
+	}
+	tw.Flush()
+}
+
+func get(ctx context.Context, c *client.Client, args []string) {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	namespace := fs.String("n", api.NamespaceDefault, "Namespace of the objects")
+	all := fs.Bool("A", false, "List the objects in every namespace")
+	output := fs.String("o", "", "Output format of lists: json, or a table of names if empty")
+	chunkSize := fs.Int64("chunk-size", 500, "Fetch lists this many objects at a time; 0 fetches them all at once")
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		log.Fatalf("get: usage: get [-n NAMESPACE] [-A] [-o json] [-chunk-size N] RESOURCE [NAME]")
+	}
+
+	res, err := c.FindResource(ctx, fs.Arg(0))
+	if err != nil {
+		log.Fatalf("get: %v", err)
+	}
+
+	if name := fs.Arg(1); name != "" {
+		var obj json.RawMessage
+		if err := c.Get(ctx, res, *namespace, name, &obj); err != nil {
+			log.Fatalf("Get failed: %v", err)
+		}
+		printJSON(obj)
+		return
+	}
+
+	ns := *namespace
+	if *all {
+		ns = api.NamespaceAll
+	}
+	opts := client.ListOptions{Limit: *chunkSize}
+	if *output == "json" {
+		printJSONList(ctx, c, res, ns, opts)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+	found := 0
+	err = c.ForEach(ctx, res, ns, opts, func(item json.RawMessage) error {
+		if found == 0 {
+			if *all && res.Namespaced {
+				fmt.Fprintln(tw, "NAMESPACE\tNAME")
+			} else {
+				fmt.Fprintln(tw, "NAME")
+			}
+		}
+		var obj struct {
+			Metadata api.ObjectMeta `json:"metadata"`
+		}
+		json.Unmarshal(item, &obj)
+		if *all && res.Namespaced {
+			fmt.Fprintf(tw, "%s\t%s\n", obj.Metadata.Namespace, obj.Metadata.Name)
+		} else {
+			fmt.Fprintln(tw, obj.Metadata.Name)
+		}
+		// Columns are aligned one page at a time, so the table never holds
+		// more than a page.
+		if found++; *chunkSize > 0 && int64(found)%*chunkSize == 0 {
+			return tw.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("List failed: %v", err)
+	}
+	if found == 0 {
+		fmt.Println("No resources found")
+		return
+	}
+	tw.Flush()
+}
+
+// printJSONList prints the objects of res as a JSON array, one page at a
+// time.
+func printJSONList(ctx context.Context, c *client.Client, res client.Resource, namespace string, opts client.ListOptions) {
+	sep := "[\n"
+	err := c.ForEach(ctx, res, namespace, opts, func(item json.RawMessage) error {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, item, "  ", "  "); err != nil {
+			return err
+		}
+		fmt.Print(sep + "  " + buf.String())
+		sep = ",\n"
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("List failed: %v", err)
+	}
+	if sep == "[\n" {
+		fmt.Println("[]")
+		return
+	}
+	fmt.Println("\n]")
+}
+
+func deleteObject(ctx context.Context, c *client.Client, args []string) {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	namespace := fs.String("n", api.NamespaceDefault, "Namespace of the object")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		log.Fatalf("delete: usage: delete [-n NAMESPACE] RESOURCE NAME")
+	}
+
+	res, err := c.FindResource(ctx, fs.Arg(0))
+	if err != nil {
+		log.Fatalf("delete: %v", err)
+	}
+	if err := c.Delete(ctx, res, *namespace, fs.Arg(1)); err != nil {
+		log.Fatalf("Delete failed: %v", err)
+	}
+	fmt.Printf("%s/%s deleted\n", res.Name, fs.Arg(1))
+}
+
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode output: %v", err)
+	}
+	fmt.Println(string(data))
+}
+
+func apply(ctx context.Context, c *client.Client, args []string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	file := fs.String("f", "", "File holding the object to apply")
+	manager := fs.String("field-manager", "kubectl-lite", "Manager the applied fields are recorded under")
+	force := fs.Bool("force", false, "Take over fields that other managers own")
+	fs.Parse(args)
+	if *file == "" {
+		log.Fatalf("apply: -f is required")
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+	var obj struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		log.Fatalf("Failed to parse %s: %v", *file, err)
+	}
+	if obj.Kind == "" {
+		log.Fatalf("apply: kind is required")
+	}
+	res, err := c.FindResource(ctx, obj.Kind)
+	if err != nil {
+		log.Fatalf("apply: %v", err)
+	}
+	if obj.APIVersion != "" && obj.APIVersion != res.GroupVersion {
+		log.Fatalf("apply: kind %s is served in %s, not %s", obj.Kind, res.GroupVersion, obj.APIVersion)
+	}
+	if obj.Metadata.Name == "" {
+		log.Fatalf("apply: metadata.name is required")
+	}
+
+	opts := client.ApplyOptions{FieldManager: *manager, Force: *force}
+	var applied json.RawMessage
+	if res.Namespaced {
+		err = c.Apply(ctx, res.Path(), res.Name, obj.Metadata.Namespace, obj.Metadata.Name, data, opts, &applied)
+	} else {
+		err = c.ApplyClusterObject(ctx, res.Path(), res.Name, obj.Metadata.Name, data, opts, &applied)
+	}
+	if err != nil {
+		log.Fatalf("Apply failed: %v", err)
+	}
+	fmt.Printf("%s/%s applied\n", res.Name, obj.Metadata.Name)
+}
+
+func backup(ctx context.Context, c *client.Client, args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("o", "", "File to write the backup to")
+	fs.Parse(args)
+	if *out == "" {
+		log.Fatalf("backup: -o is required")
+	}
+
+	var buf bytes.Buffer
+	if err := c.Backup(ctx, &buf); err != nil {
+		log.Fatalf("Backup failed: %v", err)
+	}
+	// Check the backup before it replaces anything at out.
+	b, err := storage.ReadBackup(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		log.Fatalf("Backup failed: %v", err)
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0600); err != nil {
+		log.Fatalf("Failed to write backup: %v", err)
+	}
+
+	fmt.Printf("Backed up %d objects at revision %d to %s\n", len(b.Objects), b.Revision, *out)
+	printCounts(b.Counts())
+}
+
+func printCounts(counts map[string]int) {
+	var resources []string
+	for r := range counts {
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+	for _, r := range resources {
+		fmt.Printf("  %-12s %d\n", r, counts[r])
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/abhigod/k8s-lite/internal/kubelet"
 )
@@ -17,8 +18,9 @@ func main() {
 	flag.Parse()
 
 	if *nodeName == "" {
-		// Default to hostname
+		// Default to hostname, lowercased to make a valid node name
 		host, _ := os.Hostname()
+		host = strings.ToLower(host)
 		nodeName = &host
 	}
 
@@ -35,7 +35,7 @@ func main() {
 	ctx := context.Background()
 
 	// 1. Connectivity & Empty List
-	pods, err := c.ListPods(ctx, "")
+	pods, err := c.ListPods(ctx, api.NamespaceAll, client.ListOptions{})
 	if err != nil {
 		log.Fatalf("FAIL: Failed to connect to API: %v", err)
 	}
@@ -46,7 +46,7 @@ func main() {
 	fmt.Println("Waiting for Node registration...")
 	nodeFound := false
 	for i := 0; i < 10; i++ {
-		nodes, err := c.ListNodes(ctx)
+		nodes, err := c.ListNodes(ctx, client.ListOptions{})
 		if err == nil {
 			for _, n := range nodes {
 				if n.Name == "node1" {
@@ -88,10 +88,9 @@ func main() {
 	fmt.Println("Waiting for Pod scheduling...")
 	podsScheduled := false
 	for i := 0; i < 15; i++ {
-		pods, err := c.ListPods(ctx, "")
+		// Only count pods belonging to this deployment
+		pods, err := c.ListPods(ctx, api.NamespaceDefault, client.ListOptions{LabelSelector: "app=nginx"})
 		if err == nil && len(pods) > 0 {
-			// Check if any pod belongs to this deployment
-			// In a real k8s this is label matching, here we just check if any pod exists for MVP
 			podsScheduled = true
 			break
 		}
@@ -101,5 +100,3 @@ func main() {
 
 	fmt.Println("=== E2E SUITE PASSED ===")
 }
-
-
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/exec"
+)
+
+// Runs the storage conformance suite against every backend, under the race
+// detector. The suite itself is in the tests of internal/storage; this only
+// picks the backends to run.
+func main() {
+	var only string
+	flag.StringVar(&only, "backend", "", "Run only this backend (memory, bolt or raft)")
+	flag.Parse()
+
+	tests := map[string]string{
+		"":       "StoreConformance",
+		"memory": "TestMemoryStoreConformance",
+		"bolt":   "TestBoltStoreConformance",
+		"raft":   "TestRaftStoreConformance",
+	}
+	run, ok := tests[only]
+	if !ok {
+		log.Fatalf("Unknown backend %q", only)
+	}
+
+	cmd := exec.Command("go", "test", "-race", "-v", "-count=1", "-run", run, "./internal/storage")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			os.Exit(exit.ExitCode())
+		}
+		log.Fatalf("Failed to run go test: %v", err)
+	}
+}
@@ -21,7 +21,7 @@ function Test-Endpoint {
 Start-Sleep -Seconds 2
 
 # 1. List Pods (Empty)
-$pods = Test-Endpoint -Name "List Pods (Empty)" -Method Get -Url "http://localhost:8080/api/v1/pods"
+$pods = Test-Endpoint -Name "List Pods (Empty)" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods"
 if ($pods.items.Count -ne 0) { Write-Error "Expected 0 pods"; exit 1 }
 
 # 2. Create Pod
@@ -31,23 +31,23 @@ $podJson = '{
     "metadata": { "name": "test-pod", "labels": {"app": "foo"} },
     "spec": { "containers": [{ "name": "c1", "image": "nginx" }] }
 }'
-$created = Test-Endpoint -Name "Create Pod" -Method Post -Url "http://localhost:8080/api/v1/pods" -Body $podJson
+$created = Test-Endpoint -Name "Create Pod" -Method Post -Url "http://localhost:8080/api/v1/namespaces/default/pods" -Body $podJson
 
 # 3. Get Pod
-$got = Test-Endpoint -Name "Get Pod" -Method Get -Url "http://localhost:8080/api/v1/pods/test-pod"
+$got = Test-Endpoint -Name "Get Pod" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods/test-pod"
 if ($got.metadata.name -ne "test-pod") { Write-Error "Name mismatch"; exit 1 }
 
 # 4. List Pods (1)
-$pods = Test-Endpoint -Name "List Pods (One)" -Method Get -Url "http://localhost:8080/api/v1/pods"
+$pods = Test-Endpoint -Name "List Pods (One)" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods"
 if ($pods.items.Count -ne 1) { Write-Error "Expected 1 pod"; exit 1 }
 
 # 5. Delete Pod
-Test-Endpoint -Name "Delete Pod" -Method Delete -Url "http://localhost:8080/api/v1/pods/test-pod"
+Test-Endpoint -Name "Delete Pod" -Method Delete -Url "http://localhost:8080/api/v1/namespaces/default/pods/test-pod"
 
 # 6. Get Pod (404)
 Write-Host "Testing Get Deleted Pod..." -NoNewline
 try {
-    Invoke-RestMethod -Method Get -Uri "http://localhost:8080/api/v1/pods/test-pod" | Out-Null
+    Invoke-RestMethod -Method Get -Uri "http://localhost:8080/api/v1/namespaces/default/pods/test-pod" | Out-Null
     Write-Host " FAILED (Should be 404)" -ForegroundColor Red
     exit 1
 } catch {
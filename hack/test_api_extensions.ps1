@@ -36,7 +36,7 @@ try {
             "ports": [{ "port": 80, "targetPort": 80, "nodePort": 30080 }]
         }
     }'
-    Test-Endpoint -Name "Create Service" -Method Post -Url "http://localhost:8080/api/v1/services" -Body $svcJson
+    Test-Endpoint -Name "Create Service" -Method Post -Url "http://localhost:8080/api/v1/namespaces/default/services" -Body $svcJson
 
     # 3. Create Endpoints
     $epJson = '{
@@ -48,13 +48,13 @@ try {
             "ports": [{ "port": 80 }]
         }]
     }'
-    Test-Endpoint -Name "Create Endpoints" -Method Post -Url "http://localhost:8080/api/v1/endpoints" -Body $epJson
+    Test-Endpoint -Name "Create Endpoints" -Method Post -Url "http://localhost:8080/api/v1/namespaces/default/endpoints" -Body $epJson
 
     # 4. Verify
-    $svc = Test-Endpoint -Name "Get Service" -Method Get -Url "http://localhost:8080/api/v1/services/nginx-svc"
+    $svc = Test-Endpoint -Name "Get Service" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/services/nginx-svc"
     if ($svc.spec.ports[0].nodePort -ne 30080) { Write-Error "Service Port Mismatch" }
 
-    $ep = Test-Endpoint -Name "Get Endpoints" -Method Get -Url "http://localhost:8080/api/v1/endpoints/nginx-svc"
+    $ep = Test-Endpoint -Name "Get Endpoints" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/endpoints/nginx-svc"
     if ($ep.subsets[0].addresses[0].ip -ne "1.2.3.4") { Write-Error "Endpoint IP Mismatch" }
 
     Write-Host "Service & Endpoints API Verified!" -ForegroundColor Cyan
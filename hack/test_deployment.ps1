@@ -38,13 +38,13 @@ $depJson = '{
         }
     }
 }'
-Test-Endpoint -Name "Create Deployment v1" -Method Post -Url "http://localhost:8080/apis/apps/v1/deployments" -Body $depJson
+Test-Endpoint -Name "Create Deployment v1" -Method Post -Url "http://localhost:8080/apis/apps/v1/namespaces/default/deployments" -Body $depJson
 
 Write-Host "Waiting for Deployment Rollout (v1)..."
 Start-Sleep -Seconds 10
 
 # Verify RS created
-$rss = Test-Endpoint -Name "List ReplicaSets" -Method Get -Url "http://localhost:8080/apis/apps/v1/replicasets"
+$rss = Test-Endpoint -Name "List ReplicaSets" -Method Get -Url "http://localhost:8080/apis/apps/v1/namespaces/default/replicasets"
 $v1RS = $rss.items | Where-Object { $_.metadata.name -like "web-dep-*" }
 if (!$v1RS) { Write-Error "No RS found"; exit 1 }
 Write-Host "Found RS: $($v1RS.metadata.name)"
@@ -68,13 +68,13 @@ $depJsonV2 = '{
 }'
 
 Write-Host "Updating Deployment to v2..."
-Test-Endpoint -Name "Update Deployment v2" -Method Put -Url "http://localhost:8080/apis/apps/v1/deployments/web-dep" -Body $depJsonV2
+Test-Endpoint -Name "Update Deployment v2" -Method Put -Url "http://localhost:8080/apis/apps/v1/namespaces/default/deployments/web-dep" -Body $depJsonV2
 
 Write-Host "Waiting for Rolling Update..."
 Start-Sleep -Seconds 10
 
 # 3. Verify New RS and Old RS
-$rss = Test-Endpoint -Name "List ReplicaSets Again" -Method Get -Url "http://localhost:8080/apis/apps/v1/replicasets"
+$rss = Test-Endpoint -Name "List ReplicaSets Again" -Method Get -Url "http://localhost:8080/apis/apps/v1/namespaces/default/replicasets"
 $allRS = $rss.items | Where-Object { $_.metadata.name -like "web-dep-*" }
 Write-Host "Found $($allRS.Count) ReplicaSets"
 
@@ -38,7 +38,7 @@ $podJson = '{
         "containers": [{ "name": "web", "image": "nginx:alpine" }] 
     }
 }'
-Test-Endpoint -Name "Create Pod allocated to worker-1" -Method Post -Url "http://localhost:8080/api/v1/pods" -Body $podJson
+Test-Endpoint -Name "Create Pod allocated to worker-1" -Method Post -Url "http://localhost:8080/api/v1/namespaces/default/pods" -Body $podJson
 
 Write-Host "Waiting for Kubelet to sync..."
 Start-Sleep -Seconds 10
@@ -48,5 +48,5 @@ Start-Sleep -Seconds 10
 # We'll skip actual docker check in script to avoid dep, assume logs check via user or assume success if no error.
 # But we can check if we can GET the pod.
 
-$pod = Test-Endpoint -Name "Get Pod" -Method Get -Url "http://localhost:8080/api/v1/pods/nginx-demo"
+$pod = Test-Endpoint -Name "Get Pod" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods/nginx-demo"
 Write-Host "Pod created successfully."
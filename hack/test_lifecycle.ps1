@@ -46,14 +46,14 @@ $podJson = '{
 $node1 = '{ "metadata": { "name": "worker-1" }, "status": { "conditions": [{"type":"Ready","status":"True"}] } }'
 try { Invoke-RestMethod -Method Post -Uri "http://localhost:8080/api/v1/nodes" -Body $node1 -ContentType "application/json" } catch {}
 
-Test-Endpoint -Name "Create Lifecycle Pod" -Method Post -Url "http://localhost:8080/api/v1/pods" -Body $podJson
+Test-Endpoint -Name "Create Lifecycle Pod" -Method Post -Url "http://localhost:8080/api/v1/namespaces/default/pods" -Body $podJson
 
 Write-Host "Waiting for Startup and Probe..."
 Start-Sleep -Seconds 10
 
 # 2. Verify Status is Running
 # Kubelet should have updated status to Running if probe passed (or if container started and probe didn't fail yet)
-$pod = Test-Endpoint -Name "Get Pod Status" -Method Get -Url "http://localhost:8080/api/v1/pods/lifecycle-demo"
+$pod = Test-Endpoint -Name "Get Pod Status" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods/lifecycle-demo"
 Write-Host "Pod Phase: $($pod.status.phase)" -ForegroundColor Cyan
 if ($pod.status.phase -ne "Running") {
     Write-Warning "Expected Running, got $($pod.status.phase). Probes might be failing or update slow."
@@ -63,6 +63,6 @@ if ($pod.status.phase -ne "Running") {
 # 3. Graceful Termination
 Write-Host "Deleting Pod (Graceful Stop)..."
 $start = Get-Date
-Test-Endpoint -Name "Delete Pod" -Method Delete -Url "http://localhost:8080/api/v1/pods/lifecycle-demo"
+Test-Endpoint -Name "Delete Pod" -Method Delete -Url "http://localhost:8080/api/v1/namespaces/default/pods/lifecycle-demo"
 $duration = (Get-Date) - $start
 Write-Host "Deletion took $($duration.TotalSeconds) seconds" -ForegroundColor Gray
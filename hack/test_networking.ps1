@@ -62,12 +62,12 @@ try {
     }'
     # Direct nodeName to skip scheduler requirement for this test
 
-    Test-Endpoint -Name "Create Deployment" -Method Post -Url "http://localhost:8080/apis/apps/v1/deployments" -Body $depJson
+    Test-Endpoint -Name "Create Deployment" -Method Post -Url "http://localhost:8080/apis/apps/v1/namespaces/default/deployments" -Body $depJson
 
     # 3. Wait for Pods
     Write-Host "Waiting for Pods to be Running and have IPs..."
     for ($i = 0; $i -lt 30; $i++) {
-        $pods = (Test-Endpoint -Name "List Pods" -Method Get -Url "http://localhost:8080/api/v1/pods").items
+        $pods = (Test-Endpoint -Name "List Pods" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods").items
         $running = $pods | Where-Object { $_.status.phase -eq "Running" -and $_.status.podIP -ne "" }
         $count = $running.Count
         Write-Host "Running Pods with IPs: $count / 3" -ForegroundColor Yellow
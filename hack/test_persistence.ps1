@@ -36,7 +36,7 @@ $podJson = '{
     "spec": { "containers": [{ "name": "c1", "image": "busybox" }] }
 }'
 try {
-    Test-Endpoint -Name "Create Pod" -Method Post -Url "http://localhost:8080/api/v1/pods" -Body $podJson
+    Test-Endpoint -Name "Create Pod" -Method Post -Url "http://localhost:8080/api/v1/namespaces/default/pods" -Body $podJson
 }
 catch {
     Stop-Process -Id $p1.Id
@@ -61,7 +61,7 @@ Start-Sleep -Seconds 2
 
 # 5. Verify Object Exists
 try {
-    $pod = Test-Endpoint -Name "Get Preserved Pod" -Method Get -Url "http://localhost:8080/api/v1/pods/persist-pod"
+    $pod = Test-Endpoint -Name "Get Preserved Pod" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods/persist-pod"
     if ($pod.metadata.name -ne "persist-pod") {
         Write-Error "Pod name mismatch"
         exit 1
@@ -49,7 +49,7 @@ try {
             }
         }
     }'
-    Test-Endpoint -Name "Create Deployment" -Method Post -Url "http://localhost:8080/apis/apps/v1/deployments" -Body $depJson
+    Test-Endpoint -Name "Create Deployment" -Method Post -Url "http://localhost:8080/apis/apps/v1/namespaces/default/deployments" -Body $depJson
 
     # 3. Create Service
     $svcJson = '{
@@ -61,13 +61,13 @@ try {
             "ports": [{ "port": 80, "targetPort": 80, "nodePort": 30085 }]
         }
     }'
-    Test-Endpoint -Name "Create Service" -Method Post -Url "http://localhost:8080/api/v1/services" -Body $svcJson
+    Test-Endpoint -Name "Create Service" -Method Post -Url "http://localhost:8080/api/v1/namespaces/default/services" -Body $svcJson
 
     # 4. Wait for Endpoints and Proxy Port
     Write-Host "Waiting for Pods & Endpoints..."
     for ($i = 0; $i -lt 30; $i++) {
         try {
-            $ep = Invoke-RestMethod -Method Get -Uri "http://localhost:8080/api/v1/endpoints/nginx-svc" -ErrorAction Stop
+            $ep = Invoke-RestMethod -Method Get -Uri "http://localhost:8080/api/v1/namespaces/default/endpoints/nginx-svc" -ErrorAction Stop
             if ($ep -and $ep.subsets.Count -gt 0) {
                 Write-Host "Endpoints ready." -ForegroundColor Green
                 break
@@ -38,13 +38,13 @@ $rsJson = '{
         }
     }
 }'
-Test-Endpoint -Name "Create ReplicaSet (3)" -Method Post -Url "http://localhost:8080/apis/apps/v1/replicasets" -Body $rsJson
+Test-Endpoint -Name "Create ReplicaSet (3)" -Method Post -Url "http://localhost:8080/apis/apps/v1/namespaces/default/replicasets" -Body $rsJson
 
 Write-Host "Waiting for Controller..."
 Start-Sleep -Seconds 10
 
 # 2. Verify Pod Creation
-$pods = Test-Endpoint -Name "List Pods" -Method Get -Url "http://localhost:8080/api/v1/pods"
+$pods = Test-Endpoint -Name "List Pods" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods"
 $webPods = $pods.items | Where-Object { $_.metadata.labels.app -eq "web" }
 Write-Host "Found $($webPods.Count) web pods."
 if ($webPods.Count -ne 3) {
@@ -54,12 +54,12 @@ if ($webPods.Count -ne 3) {
 
 # 3. Simulate Failure (Delete one pod)
 $victim = $webPods[0].metadata.name
-Test-Endpoint -Name "Delete Victim Pod" -Method Delete -Url "http://localhost:8080/api/v1/pods/$victim"
+Test-Endpoint -Name "Delete Victim Pod" -Method Delete -Url "http://localhost:8080/api/v1/namespaces/default/pods/$victim"
 
 Write-Host "Waiting for Reconciliation (Self-Healing)..."
 Start-Sleep -Seconds 10
 
-$pods = Test-Endpoint -Name "List Pods again" -Method Get -Url "http://localhost:8080/api/v1/pods"
+$pods = Test-Endpoint -Name "List Pods again" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods"
 $webPods = $pods.items | Where-Object { $_.metadata.labels.app -eq "web" }
 Write-Host "Found $($webPods.Count) web pods."
 if ($webPods.Count -ne 3) {
@@ -50,13 +50,13 @@ $podJson = '{
         "containers": [{ "name": "pause", "image": "k8s.gcr.io/pause", "resources": {"requests": {"cpu": "500m"}} }] 
     }
 }'
-Test-Endpoint -Name "Create Unscheduled Pod" -Method Post -Url "http://localhost:8080/api/v1/pods" -Body $podJson
+Test-Endpoint -Name "Create Unscheduled Pod" -Method Post -Url "http://localhost:8080/api/v1/namespaces/default/pods" -Body $podJson
 
 Write-Host "Waiting for Scheduler..."
 Start-Sleep -Seconds 5
 
 # 3. Check Pod Assignment
-$pod = Test-Endpoint -Name "Get Pod" -Method Get -Url "http://localhost:8080/api/v1/pods/scheduler-test"
+$pod = Test-Endpoint -Name "Get Pod" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods/scheduler-test"
 if ([string]::IsNullOrEmpty($pod.spec.nodeName)) {
     Write-Error "Pod was not scheduled!"
     exit 1
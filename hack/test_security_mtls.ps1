@@ -44,7 +44,7 @@ try {
     # Invoke-RestMethod might throw "The underlying connection was closed".
     Write-Host "Testing Insecure Access (Should Fail)..." -NoNewline
     try {
-        Invoke-RestMethod -Uri "https://localhost:8080/api/v1/pods" -ErrorAction Stop -SkipCertificateCheck
+        Invoke-RestMethod -Uri "https://localhost:8080/api/v1/namespaces/default/pods" -ErrorAction Stop -SkipCertificateCheck
         Write-Error "Should have failed authentication"
     }
     catch {
@@ -54,7 +54,7 @@ try {
     # 3. Test Secure Access with Admin Cert (Should Succeed)
     # Using curl for mTLS simplicity
     Write-Host "Testing Secure Access (Admin)..." -NoNewline
-    $out = & curl.exe -s -k --cert client-admin.pem --key client-admin.key --cacert ca.pem https://localhost:8080/api/v1/pods
+    $out = & curl.exe -s -k --cert client-admin.pem --key client-admin.key --cacert ca.pem https://localhost:8080/api/v1/namespaces/default/pods
     if ($LASTEXITCODE -eq 0) {
         Write-Host " OK" -ForegroundColor Green
     }
@@ -47,12 +47,12 @@ try {
             }
         }
     }'
-    Test-Endpoint -Name "Create Deployment" -Method Post -Url "http://localhost:8080/apis/apps/v1/deployments" -Body $depJson
+    Test-Endpoint -Name "Create Deployment" -Method Post -Url "http://localhost:8080/apis/apps/v1/namespaces/default/deployments" -Body $depJson
 
     # Wait for Pods to be Running
     Write-Host "Waiting for Pods..."
     for ($i = 0; $i -lt 30; $i++) {
-        $pods = (Test-Endpoint -Name "List Pods" -Method Get -Url "http://localhost:8080/api/v1/pods").items
+        $pods = (Test-Endpoint -Name "List Pods" -Method Get -Url "http://localhost:8080/api/v1/namespaces/default/pods").items
         $running = $pods | Where-Object { $_.metadata.labels.app -eq "nginx-svc" -and $_.status.phase -eq "Running" -and $_.status.podIP -ne "" }
         if ($running.Count -ge 2) { break }
         Start-Sleep -Seconds 2
@@ -70,13 +70,13 @@ try {
     }'
     # Note: Sending explicit targetPort struct to be safe/correct with our API
     
-    Test-Endpoint -Name "Create Service" -Method Post -Url "http://localhost:8080/api/v1/services" -Body $svcJson
+    Test-Endpoint -Name "Create Service" -Method Post -Url "http://localhost:8080/api/v1/namespaces/default/services" -Body $svcJson
 
     # 4. Wait for Endpoints
     Write-Host "Waiting for Endpoints..."
     for ($i = 0; $i -lt 10; $i++) {
         try {
-            $ep = Invoke-RestMethod -Method Get -Uri "http://localhost:8080/api/v1/endpoints/nginx-svc" -ErrorAction Stop
+            $ep = Invoke-RestMethod -Method Get -Uri "http://localhost:8080/api/v1/namespaces/default/endpoints/nginx-svc" -ErrorAction Stop
             if ($ep.subsets.Count -gt 0 -and $ep.subsets[0].addresses.Count -ge 2) {
                 Write-Host "Endpoints Found: $($ep.subsets[0].addresses.ip -join ', ')" -ForegroundColor Green
                 break
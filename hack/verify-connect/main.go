@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/abhigod/k8s-lite/internal/api"
 	"github.com/abhigod/k8s-lite/internal/client"
 )
 
@@ -24,11 +25,10 @@ func main() {
 
 	c := client.New(apiURL, certFile, keyFile, caFile)
 
-	pods, err := c.ListPods(context.Background(), "")
+	pods, err := c.ListPods(context.Background(), api.NamespaceAll, client.ListOptions{})
 	if err != nil {
 		log.Fatalf("FAIL: Failed to list pods: %v", err)
 	}
 
 	fmt.Printf("SUCCESS: Connected to API Server! Found %d pods.\n", len(pods))
 }
-
@@ -14,15 +14,52 @@ type TypeMeta struct {
 
 // ObjectMeta is metadata that all persisted resources must have
 type ObjectMeta struct {
-	Name              string            `json:"name,omitempty"`
-	Namespace         string            `json:"namespace,omitempty"` // default is "default"
-	Labels            map[string]string `json:"labels,omitempty"`
-	Annotations       map[string]string `json:"annotations,omitempty"`
-	ResourceVersion   string            `json:"resourceVersion,omitempty"`
-	CreationTimestamp time.Time         `json:"creationTimestamp,omitempty"`
-	DeletionTimestamp *time.Time        `json:"deletionTimestamp,omitempty"`
+	Name              string               `json:"name,omitempty"`
+	Namespace         string               `json:"namespace,omitempty"` // default is "default"
+	Labels            map[string]string    `json:"labels,omitempty"`
+	Annotations       map[string]string    `json:"annotations,omitempty"`
+	ResourceVersion   string               `json:"resourceVersion,omitempty"`
+	Generation        int64                `json:"generation,omitempty"` // set by the server, bumped on every spec change
+	CreationTimestamp time.Time            `json:"creationTimestamp,omitempty"`
+	DeletionTimestamp *time.Time           `json:"deletionTimestamp,omitempty"`
+	ManagedFields     []ManagedFieldsEntry `json:"managedFields,omitempty"` // set by the server
+}
+
+// ManagedFieldsEntry records the fields of an object that a manager set,
+// either by applying them or by another kind of write.
+type ManagedFieldsEntry struct {
+	Manager   string    `json:"manager"`
+	Operation string    `json:"operation"`
+	Time      time.Time `json:"time"`
+	// Subresource is "status" for the fields a manager set through the status
+	// subresource, and empty for the fields of the object itself.
+	Subresource string `json:"subresource,omitempty"`
+	// Fields are JSON pointers (RFC 6901), e.g. "/spec/replicas", in order.
+	// A list is a single field.
+	Fields []string `json:"fields"`
+}
+
+const (
+	ManagedFieldsOperationApply  = "Apply"
+	ManagedFieldsOperationUpdate = "Update"
+)
+
+// Object is implemented by every top-level API type through its embedded ObjectMeta.
+type Object interface {
+	GetObjectMeta() *ObjectMeta
 }
 
+func (m *ObjectMeta) GetObjectMeta() *ObjectMeta        { return m }
+func (m *ObjectMeta) GetResourceVersion() string        { return m.ResourceVersion }
+func (m *ObjectMeta) SetResourceVersion(version string) { m.ResourceVersion = version }
+
+const (
+	// NamespaceDefault is used for namespaced objects that don't specify one.
+	NamespaceDefault = "default"
+	// NamespaceAll selects objects in every namespace when listing or watching.
+	NamespaceAll = ""
+)
+
 // Pod is a collection of containers that can run on a host.
 type Pod struct {
 	TypeMeta   `json:",inline"`
@@ -129,10 +166,13 @@ type PodStatus struct {
 }
 
 type PodCondition struct {
-	Type   string `json:"type"`   // Ready, Scheduled
+	Type   string `json:"type"`   // Ready, PodScheduled
 	Status string `json:"status"` // True, False, Unknown
 }
 
+// PodScheduled is the condition set when a pod is bound to a node.
+const PodScheduled = "PodScheduled"
+
 type ContainerStatus struct {
 	Name         string         `json:"name"`
 	State        ContainerState `json:"state"`
@@ -165,6 +205,21 @@ type ContainerStateWaiting struct {
 	Message string `json:"message,omitempty"`
 }
 
+// Binding assigns a pod to a node. It is posted to the pod's binding
+// subresource, which only binds pods that aren't bound yet.
+type Binding struct {
+	TypeMeta   `json:",inline"`
+	ObjectMeta `json:"metadata,omitempty"`
+
+	Target ObjectReference `json:"target"`
+}
+
+// ObjectReference points at another object.
+type ObjectReference struct {
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name"`
+}
+
 // Node is a worker node in the cluster
 type Node struct {
 	TypeMeta   `json:",inline"`
@@ -226,16 +281,35 @@ type ReplicaSetSpec struct {
 }
 
 type ReplicaSetStatus struct {
+	ObservedGeneration   int64 `json:"observedGeneration,omitempty"` // generation of the spec this status reflects
 	Replicas             int32 `json:"replicas"`
 	FullyLabeledReplicas int32 `json:"fullyLabeledReplicas,omitempty"`
 	ReadyReplicas        int32 `json:"readyReplicas,omitempty"`
 	AvailableReplicas    int32 `json:"availableReplicas,omitempty"`
 }
 
+// LabelSelector selects objects by label. MatchLabels and MatchExpressions
+// must all be satisfied.
 type LabelSelector struct {
-	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	MatchLabels      map[string]string          `json:"matchLabels,omitempty"`
+	MatchExpressions []LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+// LabelSelectorRequirement is a set-based condition on one label.
+type LabelSelectorRequirement struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"` // In, NotIn, Exists, DoesNotExist
+	// Values must be non-empty for In and NotIn, and empty otherwise.
+	Values []string `json:"values,omitempty"`
 }
 
+const (
+	LabelSelectorOpIn           = "In"
+	LabelSelectorOpNotIn        = "NotIn"
+	LabelSelectorOpExists       = "Exists"
+	LabelSelectorOpDoesNotExist = "DoesNotExist"
+)
+
 type PodTemplateSpec struct {
 	ObjectMeta `json:"metadata,omitempty"`
 	Spec       PodSpec `json:"spec,omitempty"`
@@ -262,8 +336,18 @@ type ReplicaSetList struct {
 
 type ListMeta struct {
 	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// Continue is set when the list was truncated by a limit. Pass it back as
+	// the continue parameter to fetch the next page.
+	Continue string `json:"continue,omitempty"`
+}
+
+// ListObject is implemented by every list type through its embedded ListMeta.
+type ListObject interface {
+	GetListMeta() *ListMeta
 }
 
+func (m *ListMeta) GetListMeta() *ListMeta { return m }
+
 // Deployment enables declarative updates for Pods and ReplicaSets.
 type Deployment struct {
 	TypeMeta   `json:",inline"`
@@ -291,7 +375,7 @@ type RollingUpdateDeployment struct {
 }
 
 type DeploymentStatus struct {
-	ObservedGeneration  int64 `json:"observedGeneration,omitempty"`
+	ObservedGeneration  int64 `json:"observedGeneration,omitempty"` // generation of the spec this status reflects
 	Replicas            int32 `json:"replicas,omitempty"`
 	UpdatedReplicas     int32 `json:"updatedReplicas,omitempty"`
 	ReadyReplicas       int32 `json:"readyReplicas,omitempty"`
@@ -301,6 +385,7 @@ type DeploymentStatus struct {
 
 type DeploymentList struct {
 	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata,omitempty"`
 	Items    []Deployment `json:"items"`
 }
 
@@ -399,6 +484,146 @@ type LeaseList struct {
 	Items    []Lease `json:"items"`
 }
 
+// Namespace provides a scope for the names of namespaced resources.
+type Namespace struct {
+	TypeMeta   `json:",inline"`
+	ObjectMeta `json:"metadata,omitempty"`
 
+	Spec   NamespaceSpec   `json:"spec,omitempty"`
+	Status NamespaceStatus `json:"status,omitempty"`
+}
+
+type NamespaceSpec struct {
+	// Finalizers must all be removed before a terminating namespace is deleted.
+	Finalizers []string `json:"finalizers,omitempty"`
+}
 
+type NamespaceStatus struct {
+	Phase string `json:"phase,omitempty"` // Active, Terminating
+}
+
+const (
+	NamespaceActive      = "Active"
+	NamespaceTerminating = "Terminating"
+
+	// FinalizerKubernetes is held by the namespace controller until every
+	// object in the namespace has been deleted.
+	FinalizerKubernetes = "kubernetes"
+)
 
+type NamespaceList struct {
+	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata,omitempty"`
+	Items    []Namespace `json:"items"`
+}
+
+// Status is returned by the apiserver for every request that fails.
+type Status struct {
+	TypeMeta `json:",inline"`
+
+	Status  string         `json:"status"`            // Failure
+	Message string         `json:"message,omitempty"` // human-readable description of the error
+	Reason  StatusReason   `json:"reason,omitempty"`  // machine-readable category of the error
+	Details *StatusDetails `json:"details,omitempty"`
+	Code    int32          `json:"code"` // HTTP status code
+}
+
+const StatusFailure = "Failure"
+
+// StatusReason says why a request failed, independent of the message.
+type StatusReason string
+
+const (
+	StatusReasonBadRequest           StatusReason = "BadRequest"
+	StatusReasonForbidden            StatusReason = "Forbidden"
+	StatusReasonNotFound             StatusReason = "NotFound"
+	StatusReasonAlreadyExists        StatusReason = "AlreadyExists"
+	StatusReasonConflict             StatusReason = "Conflict"
+	StatusReasonUnsupportedMediaType StatusReason = "UnsupportedMediaType"
+	StatusReasonInvalid              StatusReason = "Invalid"
+	StatusReasonExpired              StatusReason = "Expired" // 410: the resourceVersion is older than the history kept
+	StatusReasonGone                 StatusReason = "Gone"    // 410: anything else that is no longer there
+	StatusReasonInternalError        StatusReason = "InternalError"
+	StatusReasonUnknown              StatusReason = ""
+)
+
+// StatusDetails identifies the object a request failed on, and for an
+// invalid object every field that is wrong with it.
+type StatusDetails struct {
+	Name   string        `json:"name,omitempty"`
+	Group  string        `json:"group,omitempty"`
+	Kind   string        `json:"kind,omitempty"` // the kind for Invalid, the resource otherwise, e.g. "pods"
+	Causes []StatusCause `json:"causes,omitempty"`
+}
+
+// StatusCause is one reason a request failed, e.g. an invalid field.
+type StatusCause struct {
+	Type    CauseType `json:"reason"`
+	Message string    `json:"message"`
+	Field   string    `json:"field,omitempty"` // e.g. spec.containers[0].name
+}
+
+// CauseType says what is wrong in a StatusCause. The values are the reasons
+// Kubernetes reports.
+type CauseType string
+
+const (
+	// The field causes of an Invalid status, see validation.Error.
+	CauseTypeFieldValueRequired     CauseType = "FieldValueRequired"
+	CauseTypeFieldValueInvalid      CauseType = "FieldValueInvalid"
+	CauseTypeFieldValueNotSupported CauseType = "FieldValueNotSupported"
+	CauseTypeFieldValueDuplicate    CauseType = "FieldValueDuplicate"
+	CauseTypeFieldValueForbidden    CauseType = "FieldValueForbidden"
+
+	// CauseTypeFieldManagerConflict is the cause of an apply conflict: a
+	// field another manager owns.
+	CauseTypeFieldManagerConflict CauseType = "FieldManagerConflict"
+)
+
+// APIVersions lists the versions of the core API group, served at /api.
+type APIVersions struct {
+	TypeMeta `json:",inline"`
+
+	Versions []string `json:"versions"`
+}
+
+// APIGroupList lists the named API groups, served at /apis.
+type APIGroupList struct {
+	TypeMeta `json:",inline"`
+
+	Groups []APIGroup `json:"groups"`
+}
+
+// APIGroup is an API group and the versions it is served in, e.g. apps.
+type APIGroup struct {
+	TypeMeta `json:",inline"`
+
+	Name             string                     `json:"name"`
+	Versions         []GroupVersionForDiscovery `json:"versions"`
+	PreferredVersion GroupVersionForDiscovery   `json:"preferredVersion"`
+}
+
+// GroupVersionForDiscovery is a version of an API group.
+type GroupVersionForDiscovery struct {
+	GroupVersion string `json:"groupVersion"` // e.g. apps/v1
+	Version      string `json:"version"`      // e.g. v1
+}
+
+// APIResourceList lists the resources of a group version, served at its
+// path, e.g. /apis/apps/v1.
+type APIResourceList struct {
+	TypeMeta `json:",inline"`
+
+	GroupVersion string        `json:"groupVersion"`
+	Resources    []APIResource `json:"resources"`
+}
+
+// APIResource describes a resource, or a subresource such as pods/status.
+type APIResource struct {
+	Name         string   `json:"name"` // plural, e.g. "deployments", or "pods/status"
+	SingularName string   `json:"singularName"`
+	Namespaced   bool     `json:"namespaced"`
+	Kind         string   `json:"kind"`
+	Verbs        []string `json:"verbs"` // e.g. get, list, watch, create, update, patch, delete
+	ShortNames   []string `json:"shortNames,omitempty"`
+}
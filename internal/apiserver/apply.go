@@ -0,0 +1,151 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// applyPatchType is the Content-Type of an apply configuration.
+const applyPatchType = "application/apply-patch+json"
+
+// handleApply is server-side apply: PATCH .../{name} with an apply
+// configuration, a partial object holding only the fields the caller cares
+// about, on behalf of the manager named by the fieldManager parameter. The
+// configuration is merged into the stored object, or creates it, and the
+// manager owns the fields in it from then on, see applyFields. If that would
+// change a field another manager owns, the apply fails with a conflict
+// naming each field and its owner, unless force=true.
+func (s *Server) handleApply(res *resourceInfo, w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	key := objectKey(r, res.Resource, name)
+
+	manager := r.URL.Query().Get("fieldManager")
+	if manager == "" {
+		render.Render(w, r, ErrInvalidRequest(fmt.Errorf("fieldManager is required for apply")))
+		return
+	}
+	force := r.URL.Query().Get("force") == "true"
+
+	var config map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil || config == nil {
+		render.Render(w, r, ErrInvalidRequest(fmt.Errorf("invalid apply configuration: the body must be a JSON object")))
+		return
+	}
+	var configMeta api.ObjectMeta
+	data, _ := json.Marshal(config["metadata"])
+	json.Unmarshal(data, &configMeta)
+	if configMeta.Name != "" && configMeta.Name != name {
+		render.Render(w, r, ErrInvalidRequest(fmt.Errorf("the name of the object (%s) does not match the name on the URL (%s)", configMeta.Name, name)))
+		return
+	}
+	if err := setNamespace(r, &configMeta); err != nil {
+		render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+
+	// apply turns live, the decoded object as stored, into the object to store.
+	apply := func(live map[string]interface{}) (interface{}, error) {
+		entries, err := applyFields(live, config, manager, force)
+		if err != nil {
+			return nil, err
+		}
+		data, _ := json.Marshal(live)
+		obj := res.New()
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(obj); err != nil {
+			return nil, errInvalidUpdate{fmt.Errorf("invalid apply configuration: %v", err)}
+		}
+		setManagedFields(obj, entries)
+		return obj, nil
+	}
+
+	for {
+		updated, err := s.guaranteedUpdate(r.Context(), key, res, "", func(existing interface{}) (interface{}, error) {
+			existingMeta, _ := getObjectMeta(existing)
+			if configMeta.ResourceVersion != "" && configMeta.ResourceVersion != existingMeta.ResourceVersion {
+				return nil, storage.ErrConflict
+			}
+			obj, err := apply(toUnstructured(existing))
+			if err != nil {
+				return nil, err
+			}
+			if err := admitUpdate(res, obj, existing); err != nil {
+				return nil, err
+			}
+			return obj, nil
+		})
+		if err == nil {
+			render.JSON(w, r, updated)
+			return
+		}
+		if err != storage.ErrNotFound {
+			renderApplyError(w, r, res.groupResource(), err)
+			return
+		}
+
+		// Nothing stored yet: apply to an empty object and create it.
+		if configMeta.ResourceVersion != "" {
+			render.Render(w, r, ErrNotFound(res.groupResource(), name))
+			return
+		}
+		live := map[string]interface{}{"metadata": map[string]interface{}{"name": name}}
+		for _, k := range []string{"apiVersion", "kind"} {
+			if v, ok := config[k]; ok {
+				live[k] = v
+			}
+		}
+		obj, err := apply(live)
+		if err != nil {
+			renderApplyError(w, r, res.groupResource(), err)
+			return
+		}
+		if errResp := s.admitCreate(r, res, obj); errResp != nil {
+			render.Render(w, r, errResp)
+			return
+		}
+		err = s.Store.Create(r.Context(), key, obj)
+		if err == storage.ErrAlreadyExists {
+			// Created concurrently; apply to that instead.
+			continue
+		}
+		if err != nil {
+			render.Render(w, r, ErrInternal(err))
+			return
+		}
+		if errResp := s.confirmNamespace(r, res, key); errResp != nil {
+			render.Render(w, r, errResp)
+			return
+		}
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, obj)
+		return
+	}
+}
+
+// renderApplyError responds with an error from handleApply. A conflict
+// lists each contested field as a cause, naming its manager.
+func renderApplyError(w http.ResponseWriter, r *http.Request, gr groupResource, err error) {
+	conflicts, ok := err.(errApplyConflicts)
+	if !ok {
+		renderUpdateError(w, r, gr, err)
+		return
+	}
+	errResp := ErrConflict(gr, chi.URLParam(r, "name"), conflicts).(*ErrResponse)
+	errResp.Message = conflicts.Error()
+	for _, c := range conflicts {
+		errResp.Details.Causes = append(errResp.Details.Causes, api.StatusCause{
+			Type:    api.CauseTypeFieldManagerConflict,
+			Message: fmt.Sprintf("conflict with %q", c.Manager),
+			Field:   c.Field,
+		})
+	}
+	render.Render(w, r, errResp)
+}
@@ -0,0 +1,21 @@
+package apiserver
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// handleBackup streams a point-in-time copy of the whole store, in the
+// format the apiserver's --restore-from flag reads. Writes keep going while
+// it is taken.
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	b, err := s.Store.Backup(r.Context())
+	if err != nil {
+		render.Render(w, r, ErrInternal(err))
+		return
+	}
+	log.Printf("Backup of %d objects at revision %d", len(b.Objects), b.Revision)
+	render.JSON(w, r, b)
+}
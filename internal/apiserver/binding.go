@@ -0,0 +1,90 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// errPodBound rejects a binding for a pod that already has a node.
+var errPodBound = errors.New("pod is already bound")
+
+// handleBinding binds a pod to the node named by the posted api.Binding. The
+// node is only set if the pod has none yet, so concurrent schedulers can't
+// both bind it and nobody can move a pod once it has been placed. Otherwise
+// spec.nodeName can't be changed, see checkPodUpdate.
+func (s *Server) handleBinding(res *resourceInfo, w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	key := objectKey(r, res.Resource, name)
+
+	var binding api.Binding
+	if err := json.NewDecoder(r.Body).Decode(&binding); err != nil {
+		render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	if binding.Name != "" && binding.Name != name {
+		render.Render(w, r, ErrInvalidRequest(fmt.Errorf("the name of the binding (%s) does not match the name on the URL (%s)", binding.Name, name)))
+		return
+	}
+	if binding.Target.Name == "" {
+		render.Render(w, r, ErrInvalidRequest(fmt.Errorf("target.name is required")))
+		return
+	}
+
+	var boundTo string
+	updated, err := s.guaranteedUpdate(r.Context(), key, res, binding.ResourceVersion, func(existing interface{}) (interface{}, error) {
+		pod := existing.(*api.Pod)
+		if pod.Spec.NodeName != "" {
+			boundTo = pod.Spec.NodeName
+			return nil, errPodBound
+		}
+		// setPodCondition changes the conditions in place, so the copy
+		// needs its own.
+		unbound := *pod
+		unbound.Status.Conditions = append([]api.PodCondition(nil), pod.Status.Conditions...)
+		pod.Spec.NodeName = binding.Target.Name
+		setPodCondition(&pod.Status, api.PodCondition{Type: api.PodScheduled, Status: "True"})
+		pod.Generation = nextGeneration(&unbound, pod)
+		recordUpdate(pod, &unbound, fieldManager(r))
+		// The condition is recorded on top of what recordUpdate recorded.
+		unbound.ManagedFields = pod.ManagedFields
+		recordStatusUpdate(pod, &unbound, fieldManager(r))
+		return pod, nil
+	})
+	if err != nil {
+		if err == errPodBound {
+			render.Render(w, r, ErrConflict(res.groupResource(), name, fmt.Errorf("pod %s is already assigned to node %q", name, boundTo)))
+		} else {
+			renderUpdateError(w, r, res.groupResource(), err)
+		}
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, updated)
+}
+
+// setPodCondition adds cond to status, replacing any condition of its type.
+func setPodCondition(status *api.PodStatus, cond api.PodCondition) {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == cond.Type {
+			status.Conditions[i] = cond
+			return
+		}
+	}
+	status.Conditions = append(status.Conditions, cond)
+}
+
+// checkPodUpdate rejects updates that change what only the binding
+// subresource may change.
+func checkPodUpdate(pod, existing *api.Pod) error {
+	if pod.Spec.NodeName != existing.Spec.NodeName {
+		return fmt.Errorf("spec.nodeName can't be changed by an update, use the binding subresource to assign a node")
+	}
+	return nil
+}
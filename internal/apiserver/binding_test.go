@@ -0,0 +1,104 @@
+package apiserver
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+func TestHandleBinding(t *testing.T) {
+	const pods = "/api/v1/namespaces/default/pods"
+	tests := []struct {
+		name       string
+		nodeName   string // the pod is bound to before the binding
+		binding    interface{}
+		want       int
+		wantNode   string
+		generation int64 // binding sets spec.nodeName, a spec change
+	}{
+		{
+			name:       "unbound",
+			binding:    api.Binding{Target: api.ObjectReference{Name: "node1"}},
+			want:       http.StatusCreated,
+			wantNode:   "node1",
+			generation: 2,
+		},
+		{
+			name:       "already bound",
+			nodeName:   "node2",
+			binding:    api.Binding{Target: api.ObjectReference{Name: "node1"}},
+			want:       http.StatusConflict,
+			wantNode:   "node2",
+			generation: 1,
+		},
+		{
+			name:       "no target",
+			binding:    api.Binding{},
+			want:       http.StatusBadRequest,
+			generation: 1,
+		},
+		{
+			name:       "other pod",
+			binding:    api.Binding{ObjectMeta: api.ObjectMeta{Name: "other"}, Target: api.ObjectReference{Name: "node1"}},
+			want:       http.StatusBadRequest,
+			generation: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			pod := api.Pod{
+				ObjectMeta: api.ObjectMeta{Name: "web"},
+				Spec: api.PodSpec{
+					NodeName:   tt.nodeName,
+					Containers: []api.Container{{Name: "web", Image: "nginx"}},
+				},
+			}
+			expectCode(t, do(t, s, "POST", pods, pod), http.StatusCreated)
+
+			w := do(t, s, "POST", pods+"/web/binding", tt.binding)
+			expectCode(t, w, tt.want)
+
+			var got api.Pod
+			decode(t, do(t, s, "GET", pods+"/web", nil), &got)
+			if got.Spec.NodeName != tt.wantNode {
+				t.Errorf("pod is bound to %q, want %q", got.Spec.NodeName, tt.wantNode)
+			}
+			if got.Generation != tt.generation {
+				t.Errorf("got generation %d, want %d", got.Generation, tt.generation)
+			}
+		})
+	}
+}
+
+// A binding records its manager as the owner of what it changed, including
+// a condition that replaces one the pod already had.
+func TestHandleBindingManagedFields(t *testing.T) {
+	const pods = "/api/v1/namespaces/default/pods"
+	s := newTestServer(t)
+	pod := api.Pod{
+		ObjectMeta: api.ObjectMeta{Name: "web"},
+		Spec:       api.PodSpec{Containers: []api.Container{{Name: "web", Image: "nginx"}}},
+	}
+	expectCode(t, do(t, s, "POST", pods, pod), http.StatusCreated)
+	pod.Status.Conditions = []api.PodCondition{{Type: api.PodScheduled, Status: "False"}}
+	expectCode(t, do(t, s, "PUT", pods+"/web/status?fieldManager=kubelet", pod), http.StatusOK)
+
+	w := do(t, s, "POST", pods+"/web/binding?fieldManager=scheduler", api.Binding{Target: api.ObjectReference{Name: "node1"}})
+	expectCode(t, w, http.StatusCreated)
+
+	var bound api.Pod
+	decode(t, w, &bound)
+	owners := make(map[string]string)
+	for _, e := range bound.ManagedFields {
+		for _, f := range e.Fields {
+			owners[f] = e.Manager
+		}
+	}
+	for _, f := range []string{"/spec/nodeName", "/status/conditions"} {
+		if owners[f] != "scheduler" {
+			t.Errorf("%s is owned by %q, want scheduler", f, owners[f])
+		}
+	}
+}
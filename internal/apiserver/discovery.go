@@ -0,0 +1,93 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/go-chi/render"
+)
+
+// API discovery: GET /api lists the versions of the core group and GET /apis
+// the named groups, e.g. apps. GET on a group version, e.g. /apis/apps/v1,
+// lists its resources with their kinds, whether they are namespaced, the
+// verbs they support and their short names, so clients can look up where a
+// kind is served instead of knowing it in advance. The documents describe
+// the routes as registered.
+
+// discovery collects the discovery documents while resources are registered.
+type discovery struct {
+	coreVersions []string
+	groups       []*api.APIGroup
+	// lists are the resources of each group version, by its path.
+	lists map[string]*api.APIResourceList
+	paths []string
+}
+
+func newDiscovery() *discovery {
+	return &discovery{lists: make(map[string]*api.APIResourceList)}
+}
+
+// add records resources, registered for res, in the document of its group
+// version.
+func (d *discovery) add(res *resourceInfo, resources []api.APIResource) {
+	if list, ok := d.lists[res.prefix()]; ok {
+		list.Resources = append(list.Resources, resources...)
+		return
+	}
+	d.lists[res.prefix()] = &api.APIResourceList{
+		TypeMeta:     api.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: res.groupVersion(),
+		Resources:    resources,
+	}
+	d.paths = append(d.paths, res.prefix())
+
+	version := api.GroupVersionForDiscovery{GroupVersion: res.groupVersion(), Version: res.Version}
+	if res.Group == "" {
+		d.coreVersions = append(d.coreVersions, res.Version)
+		return
+	}
+	for _, g := range d.groups {
+		if g.Name == res.Group {
+			g.Versions = append(g.Versions, version)
+			return
+		}
+	}
+	// The first version registered is the preferred one.
+	d.groups = append(d.groups, &api.APIGroup{
+		TypeMeta:         api.TypeMeta{Kind: "APIGroup", APIVersion: "v1"},
+		Name:             res.Group,
+		Versions:         []api.GroupVersionForDiscovery{version},
+		PreferredVersion: version,
+	})
+}
+
+// registerDiscoveryRoutes serves the documents collected in d.
+func (s *Server) registerDiscoveryRoutes(d *discovery) {
+	versions := &api.APIVersions{
+		TypeMeta: api.TypeMeta{Kind: "APIVersions", APIVersion: "v1"},
+		Versions: d.coreVersions,
+	}
+	s.Router.Get("/api", serveDocument(versions))
+
+	groups := &api.APIGroupList{
+		TypeMeta: api.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+		Groups:   []api.APIGroup{},
+	}
+	for _, g := range d.groups {
+		groups.Groups = append(groups.Groups, *g)
+		// e.g. /apis/apps
+		s.Router.Get("/apis/"+g.Name, serveDocument(g))
+	}
+	s.Router.Get("/apis", serveDocument(groups))
+
+	// e.g. /apis/apps/v1
+	for _, path := range d.paths {
+		s.Router.Get(path, serveDocument(d.lists[path]))
+	}
+}
+
+func serveDocument(doc interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, doc)
+	}
+}
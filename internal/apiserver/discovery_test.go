@@ -0,0 +1,161 @@
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDiscovery(t *testing.T) {
+	d := newDiscovery()
+	for _, r := range []struct {
+		res       *resourceInfo
+		resources []api.APIResource
+	}{
+		{&resourceInfo{Version: "v1", Resource: "widgets"}, []api.APIResource{{Name: "widgets"}}},
+		{&resourceInfo{Group: "shop", Version: "v1", Resource: "carts"}, []api.APIResource{{Name: "carts"}, {Name: "carts/status"}}},
+		{&resourceInfo{Group: "shop", Version: "v2", Resource: "carts"}, []api.APIResource{{Name: "carts"}}},
+		{&resourceInfo{Version: "v1", Resource: "gadgets"}, []api.APIResource{{Name: "gadgets"}}},
+		{&resourceInfo{Group: "shop", Version: "v1", Resource: "orders"}, []api.APIResource{{Name: "orders"}}},
+	} {
+		d.add(r.res, r.resources)
+	}
+	s := &Server{Router: chi.NewRouter()}
+	s.registerDiscoveryRoutes(d)
+
+	v1 := api.GroupVersionForDiscovery{GroupVersion: "shop/v1", Version: "v1"}
+	v2 := api.GroupVersionForDiscovery{GroupVersion: "shop/v2", Version: "v2"}
+	shop := api.APIGroup{
+		TypeMeta:         api.TypeMeta{Kind: "APIGroup", APIVersion: "v1"},
+		Name:             "shop",
+		Versions:         []api.GroupVersionForDiscovery{v1, v2},
+		PreferredVersion: v1, // registered first
+	}
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"/api", &api.APIVersions{
+			TypeMeta: api.TypeMeta{Kind: "APIVersions", APIVersion: "v1"},
+			Versions: []string{"v1"},
+		}},
+		{"/apis", &api.APIGroupList{
+			TypeMeta: api.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+			Groups:   []api.APIGroup{shop},
+		}},
+		{"/apis/shop", &shop},
+		{"/api/v1", &api.APIResourceList{
+			TypeMeta:     api.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: "v1",
+			Resources:    []api.APIResource{{Name: "widgets"}, {Name: "gadgets"}},
+		}},
+		{"/apis/shop/v1", &api.APIResourceList{
+			TypeMeta:     api.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: "shop/v1",
+			Resources:    []api.APIResource{{Name: "carts"}, {Name: "carts/status"}, {Name: "orders"}},
+		}},
+		{"/apis/shop/v2", &api.APIResourceList{
+			TypeMeta:     api.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+			GroupVersion: "shop/v2",
+			Resources:    []api.APIResource{{Name: "carts"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := do(t, s, http.MethodGet, tt.path, nil)
+			expectCode(t, w, http.StatusOK)
+			got := reflect.New(reflect.TypeOf(tt.want).Elem()).Interface()
+			decode(t, w, got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinDiscovery(t *testing.T) {
+	s := newTestServer(t)
+
+	var versions api.APIVersions
+	decode(t, do(t, s, http.MethodGet, "/api", nil), &versions)
+	if !reflect.DeepEqual(versions.Versions, []string{"v1"}) {
+		t.Errorf("got core versions %v, want [v1]", versions.Versions)
+	}
+	var groups api.APIGroupList
+	decode(t, do(t, s, http.MethodGet, "/apis", nil), &groups)
+	if len(groups.Groups) != 1 || groups.Groups[0].Name != "apps" || groups.Groups[0].PreferredVersion.GroupVersion != "apps/v1" {
+		t.Errorf("got groups %+v, want apps preferring apps/v1", groups.Groups)
+	}
+
+	allVerbs := []string{"create", "delete", "get", "list", "patch", "update", "watch"}
+	tests := []struct {
+		path string
+		want api.APIResource
+	}{
+		{"/api/v1", api.APIResource{Name: "pods", SingularName: "pod", Namespaced: true, Kind: "Pod", Verbs: allVerbs, ShortNames: []string{"po"}}},
+		{"/api/v1", api.APIResource{Name: "pods/status", Namespaced: true, Kind: "Pod", Verbs: []string{"update"}}},
+		{"/api/v1", api.APIResource{Name: "pods/binding", Namespaced: true, Kind: "Binding", Verbs: []string{"create"}}},
+		{"/api/v1", api.APIResource{Name: "namespaces", SingularName: "namespace", Kind: "Namespace", Verbs: allVerbs, ShortNames: []string{"ns"}}},
+		{"/api/v1", api.APIResource{Name: "namespaces/finalize", Kind: "Namespace", Verbs: []string{"update"}}},
+		{"/api/v1", api.APIResource{Name: "leases", SingularName: "lease", Namespaced: true, Kind: "Lease", Verbs: allVerbs}},
+		{"/apis/apps/v1", api.APIResource{Name: "deployments", SingularName: "deployment", Namespaced: true, Kind: "Deployment", Verbs: allVerbs, ShortNames: []string{"deploy"}}},
+		{"/apis/apps/v1", api.APIResource{Name: "deployments/status", Namespaced: true, Kind: "Deployment", Verbs: []string{"update"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want.Name, func(t *testing.T) {
+			var list api.APIResourceList
+			decode(t, do(t, s, http.MethodGet, tt.path, nil), &list)
+			for _, r := range list.Resources {
+				if r.Name == tt.want.Name {
+					if !reflect.DeepEqual(r, tt.want) {
+						t.Errorf("got %+v, want %+v", r, tt.want)
+					}
+					return
+				}
+			}
+			t.Errorf("%s not in %s", tt.want.Name, tt.path)
+		})
+	}
+}
+
+func TestFindResource(t *testing.T) {
+	srv := httptest.NewServer(newTestServer(t).Router)
+	defer srv.Close()
+	c := &client.Client{BaseURL: srv.URL, HTTP: srv.Client()}
+
+	tests := []struct {
+		name         string
+		groupVersion string
+		resource     string
+	}{
+		{"pods", "v1", "pods"},
+		{"pod", "v1", "pods"},
+		{"po", "v1", "pods"},
+		{"Pod", "v1", "pods"},
+		{"deploy", "apps/v1", "deployments"},
+		{"ReplicaSet", "apps/v1", "replicasets"},
+		{"ns", "v1", "namespaces"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.FindResource(context.Background(), tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.GroupVersion != tt.groupVersion || res.Name != tt.resource {
+				t.Errorf("got %s %s, want %s %s", res.GroupVersion, res.Name, tt.groupVersion, tt.resource)
+			}
+		})
+	}
+
+	// Subresources are never found by name.
+	if res, err := c.FindResource(context.Background(), "status"); err == nil {
+		t.Errorf("found %s %s for status", res.GroupVersion, res.Name)
+	}
+}
@@ -0,0 +1,103 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/validation"
+	"github.com/go-chi/render"
+)
+
+// ErrResponse renders a failed request as an api.Status, whose reason tells
+// clients what went wrong without parsing the message.
+type ErrResponse struct {
+	Err error `json:"-"` // low-level runtime error
+
+	api.Status
+}
+
+func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, int(e.Code))
+	return nil
+}
+
+func newErrResponse(code int, reason api.StatusReason, err error, message string, details *api.StatusDetails) *ErrResponse {
+	return &ErrResponse{
+		Err: err,
+		Status: api.Status{
+			TypeMeta: api.TypeMeta{Kind: "Status", APIVersion: "v1"},
+			Status:   api.StatusFailure,
+			Message:  message,
+			Reason:   reason,
+			Details:  details,
+			Code:     int32(code),
+		},
+	}
+}
+
+// groupResource is a resource and its API group, e.g. deployments in apps.
+type groupResource struct {
+	Group    string
+	Resource string
+}
+
+// String returns the resource qualified by its group, e.g. "deployments.apps".
+func (gr groupResource) String() string {
+	if gr.Group == "" {
+		return gr.Resource
+	}
+	return gr.Resource + "." + gr.Group
+}
+
+func ErrInvalidRequest(err error) render.Renderer {
+	return newErrResponse(http.StatusBadRequest, api.StatusReasonBadRequest, err, err.Error(), nil)
+}
+
+func ErrForbidden(err error) render.Renderer {
+	return newErrResponse(http.StatusForbidden, api.StatusReasonForbidden, err, err.Error(), nil)
+}
+
+// ErrNotFound reports that the object name of gr doesn't exist.
+func ErrNotFound(gr groupResource, name string) render.Renderer {
+	return newErrResponse(http.StatusNotFound, api.StatusReasonNotFound, nil,
+		fmt.Sprintf("%s %q not found", gr, name),
+		&api.StatusDetails{Name: name, Group: gr.Group, Kind: gr.Resource})
+}
+
+// ErrAlreadyExists reports that an object of gr is already called name.
+func ErrAlreadyExists(gr groupResource, name string) render.Renderer {
+	return newErrResponse(http.StatusConflict, api.StatusReasonAlreadyExists, nil,
+		fmt.Sprintf("%s %q already exists", gr, name),
+		&api.StatusDetails{Name: name, Group: gr.Group, Kind: gr.Resource})
+}
+
+// ErrConflict reports that a write to the object name of gr was refused
+// because of err, e.g. a stale resourceVersion.
+func ErrConflict(gr groupResource, name string, err error) render.Renderer {
+	return newErrResponse(http.StatusConflict, api.StatusReasonConflict, err,
+		fmt.Sprintf("Operation cannot be fulfilled on %s %q: %v", gr, name, err),
+		&api.StatusDetails{Name: name, Group: gr.Group, Kind: gr.Resource})
+}
+
+// errModified is the conflict of a write based on an outdated object.
+var errModified = fmt.Errorf("the object has been modified; please apply your changes to the latest version and try again")
+
+// ErrInvalid rejects obj, of a resource in group, because of errs, listing
+// every invalid field.
+func ErrInvalid(group string, obj interface{}, errs validation.ErrorList) render.Renderer {
+	meta, _ := getObjectMeta(obj)
+	kind := reflect.TypeOf(obj).Elem().Name()
+	return newErrResponse(http.StatusUnprocessableEntity, api.StatusReasonInvalid, errs,
+		fmt.Sprintf("%s %q is invalid: %v", kind, meta.Name, errs),
+		&api.StatusDetails{Name: meta.Name, Group: group, Kind: kind, Causes: errs.Causes()})
+}
+
+func ErrUnsupportedMediaType(err error) render.Renderer {
+	return newErrResponse(http.StatusUnsupportedMediaType, api.StatusReasonUnsupportedMediaType, err, err.Error(), nil)
+}
+
+func ErrInternal(err error) render.Renderer {
+	return newErrResponse(http.StatusInternalServerError, api.StatusReasonInternalError, err, err.Error(), nil)
+}
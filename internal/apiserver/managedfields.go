@@ -0,0 +1,390 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// Field ownership: every object records in metadata.managedFields which
+// manager set which of its fields. A field is named by its JSON pointer, and
+// anything that isn't an object (a string, a number, a whole list) is one
+// field. Writes through create, update and patch make their manager the
+// owner of the fields they changed; apply (see apply.go) makes its manager
+// the owner of exactly the fields it applied, and refuses to change a field
+// another manager owns unless forced. Status is only written through the
+// status subresource, whose writes are recorded in entries of their own.
+
+// fieldManager returns the manager a write is recorded under: the
+// fieldManager query parameter, or else the product in the User-Agent,
+// which the client sets to the name of the component.
+func fieldManager(r *http.Request) string {
+	if m := r.URL.Query().Get("fieldManager"); m != "" {
+		return m
+	}
+	if ua := strings.TrimSpace(r.UserAgent()); ua != "" {
+		return strings.SplitN(strings.Fields(ua)[0], "/", 2)[0]
+	}
+	return "unknown"
+}
+
+// field is a leaf of an object: its path and value.
+type field struct {
+	path  []string
+	value interface{}
+}
+
+// serverFields are set by the server and owned by nobody.
+var serverFields = []string{
+	"/apiVersion",
+	"/kind",
+	"/status",
+	"/metadata/name",
+	"/metadata/namespace",
+	"/metadata/resourceVersion",
+	"/metadata/generation",
+	"/metadata/creationTimestamp",
+	"/metadata/deletionTimestamp",
+	"/metadata/managedFields",
+}
+
+// fieldsOf returns the fields of obj, an API object or its decoded JSON, by
+// JSON pointer.
+func fieldsOf(obj interface{}) map[string]field {
+	fields := make(map[string]field)
+	collectFields(toUnstructured(obj), nil, fields)
+	return fields
+}
+
+// statusFieldsOf returns the fields under /status of obj, by JSON pointer.
+func statusFieldsOf(obj interface{}) map[string]field {
+	fields := make(map[string]field)
+	if status, ok := toUnstructured(obj)["status"].(map[string]interface{}); ok {
+		for k, child := range status {
+			collectLeaves(child, []string{"status", k}, fields)
+		}
+	}
+	return fields
+}
+
+func collectFields(v interface{}, path []string, fields map[string]field) {
+	ptr := fieldPointer(path)
+	for _, f := range serverFields {
+		if overlaps(ptr, f) && len(ptr) >= len(f) {
+			return
+		}
+	}
+	if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+		for k, child := range m {
+			collectFields(child, append(path[:len(path):len(path)], k), fields)
+		}
+		return
+	}
+	if len(path) > 0 {
+		fields[ptr] = field{path: path, value: v}
+	}
+}
+
+// collectLeaves is collectFields without skipping the server's fields.
+func collectLeaves(v interface{}, path []string, fields map[string]field) {
+	if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+		for k, child := range m {
+			collectLeaves(child, append(path[:len(path):len(path)], k), fields)
+		}
+		return
+	}
+	fields[fieldPointer(path)] = field{path: path, value: v}
+}
+
+// toUnstructured returns obj as decoded JSON.
+func toUnstructured(obj interface{}) map[string]interface{} {
+	if m, ok := obj.(map[string]interface{}); ok {
+		return m
+	}
+	data, _ := json.Marshal(obj)
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// fieldPointer returns the JSON pointer of path.
+func fieldPointer(path []string) string {
+	var b strings.Builder
+	for _, p := range path {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(p))
+	}
+	return b.String()
+}
+
+// overlaps reports whether the fields a and b are the same field or one
+// contains the other.
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// ownsAny reports whether fields contain a field overlapping ptr.
+func ownsAny(fields []string, ptr string) bool {
+	for _, f := range fields {
+		if overlaps(f, ptr) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutFields returns fields minus those overlapping any of ptrs.
+func withoutFields(fields []string, ptrs map[string]bool) []string {
+	var kept []string
+	for _, f := range fields {
+		owned := false
+		for ptr := range ptrs {
+			if overlaps(f, ptr) {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// setManagedFields stores entries as the managed fields of obj, dropping
+// entries that no longer own anything.
+func setManagedFields(obj interface{}, entries []api.ManagedFieldsEntry) {
+	var kept []api.ManagedFieldsEntry
+	for _, e := range entries {
+		if len(e.Fields) > 0 {
+			sort.Strings(e.Fields)
+			kept = append(kept, e)
+		}
+	}
+	meta, _ := getObjectMeta(obj)
+	meta.ManagedFields = kept
+}
+
+// putEntry replaces the entry of the manager, operation and subresource of
+// entry in entries, or adds it.
+func putEntry(entries []api.ManagedFieldsEntry, entry api.ManagedFieldsEntry) []api.ManagedFieldsEntry {
+	for i, e := range entries {
+		if e.Manager == entry.Manager && e.Operation == entry.Operation && e.Subresource == entry.Subresource {
+			entries[i] = entry
+			return entries
+		}
+	}
+	return append(entries, entry)
+}
+
+// findEntry returns the entry of manager for operation on subresource, if
+// there is one.
+func findEntry(entries []api.ManagedFieldsEntry, manager, operation, subresource string) api.ManagedFieldsEntry {
+	for _, e := range entries {
+		if e.Manager == manager && e.Operation == operation && e.Subresource == subresource {
+			return e
+		}
+	}
+	return api.ManagedFieldsEntry{Manager: manager, Operation: operation, Subresource: subresource}
+}
+
+// recordCreate makes manager the owner of every field of obj, a new object.
+func recordCreate(obj interface{}, manager string) {
+	var fields []string
+	for ptr := range fieldsOf(obj) {
+		fields = append(fields, ptr)
+	}
+	setManagedFields(obj, []api.ManagedFieldsEntry{{
+		Manager:   manager,
+		Operation: api.ManagedFieldsOperationUpdate,
+		Time:      now(),
+		Fields:    fields,
+	}})
+}
+
+// recordUpdate sets the managed fields of obj, which replaces existing in a
+// write by manager: manager takes over the fields it changed from whoever
+// owned them, and fields it removed are no longer owned.
+func recordUpdate(obj, existing interface{}, manager string) {
+	recordChanges(obj, existing, manager, "", fieldsOf)
+}
+
+// recordStatusUpdate is recordUpdate for a write through the status
+// subresource, which only changes the fields under /status.
+func recordStatusUpdate(obj, existing interface{}, manager string) {
+	recordChanges(obj, existing, manager, "status", statusFieldsOf)
+}
+
+// recordChanges makes manager the owner of the fields, as returned by
+// fieldsOf, that differ between existing and obj, in its entry for
+// subresource.
+func recordChanges(obj, existing interface{}, manager, subresource string, fieldsOf func(interface{}) map[string]field) {
+	before, after := fieldsOf(existing), fieldsOf(obj)
+	changed := make(map[string]bool)
+	for ptr, f := range after {
+		if old, ok := before[ptr]; !ok || !reflect.DeepEqual(old.value, f.value) {
+			changed[ptr] = true
+		}
+	}
+	for ptr := range before {
+		if _, ok := after[ptr]; !ok {
+			changed[ptr] = true
+		}
+	}
+
+	existingMeta, _ := getObjectMeta(existing)
+	var entries []api.ManagedFieldsEntry
+	for _, e := range existingMeta.ManagedFields {
+		e.Fields = withoutFields(e.Fields, changed)
+		entries = append(entries, e)
+	}
+	if len(changed) > 0 {
+		mine := findEntry(entries, manager, api.ManagedFieldsOperationUpdate, subresource)
+		for ptr := range changed {
+			if _, ok := after[ptr]; ok {
+				mine.Fields = append(mine.Fields, ptr)
+			}
+		}
+		mine.Time = now()
+		entries = putEntry(entries, mine)
+	}
+	setManagedFields(obj, entries)
+}
+
+// fieldConflict is a field an apply would change that another manager owns.
+type fieldConflict struct {
+	Manager string
+	Field   string
+}
+
+// errApplyConflicts rejects an apply that isn't forced.
+type errApplyConflicts []fieldConflict
+
+func (e errApplyConflicts) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Apply failed with %d conflict", len(e))
+	if len(e) > 1 {
+		b.WriteString("s")
+	}
+	b.WriteString(":")
+	for _, c := range e {
+		fmt.Fprintf(&b, " conflict with %q: %s;", c.Manager, c.Field)
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
+
+// applyFields merges config, an apply configuration, into live, the decoded
+// object as stored, on behalf of manager. Fields in config are set, whole,
+// and owned by manager from then on; fields manager applied before but left
+// out of config are removed unless somebody else owns them. A field owned by
+// another manager that config would change is a conflict; with force,
+// manager takes it over instead. It returns the managed fields of the
+// result.
+func applyFields(live, config map[string]interface{}, manager string, force bool) ([]api.ManagedFieldsEntry, error) {
+	var entries []api.ManagedFieldsEntry
+	if meta, ok := live["metadata"].(map[string]interface{}); ok {
+		data, _ := json.Marshal(meta["managedFields"])
+		json.Unmarshal(data, &entries)
+	}
+	liveFields, applied := fieldsOf(live), fieldsOf(config)
+
+	var conflicts errApplyConflicts
+	taken := make(map[string]bool)
+	for ptr, f := range applied {
+		if old, ok := liveFields[ptr]; ok && reflect.DeepEqual(old.value, f.value) {
+			continue
+		}
+		for _, e := range entries {
+			if e.Manager != manager && ownsAny(e.Fields, ptr) {
+				conflicts = append(conflicts, fieldConflict{Manager: e.Manager, Field: ptr})
+			}
+		}
+		taken[ptr] = true
+	}
+	if len(conflicts) > 0 && !force {
+		sort.Slice(conflicts, func(i, j int) bool {
+			if conflicts[i].Field != conflicts[j].Field {
+				return conflicts[i].Field < conflicts[j].Field
+			}
+			return conflicts[i].Manager < conflicts[j].Manager
+		})
+		return nil, conflicts
+	}
+
+	previous := findEntry(entries, manager, api.ManagedFieldsOperationApply, "")
+	for _, ptr := range previous.Fields {
+		if _, ok := applied[ptr]; ok {
+			continue
+		}
+		ownedElsewhere := false
+		for _, e := range entries {
+			if !(e.Manager == manager && e.Operation == api.ManagedFieldsOperationApply) && ownsAny(e.Fields, ptr) {
+				ownedElsewhere = true
+			}
+		}
+		if f, ok := liveFields[ptr]; ok && !ownedElsewhere {
+			removeField(live, f.path)
+		}
+	}
+
+	var fields []string
+	for ptr, f := range applied {
+		setField(live, f.path, f.value)
+		fields = append(fields, ptr)
+	}
+
+	// Changed fields move to manager; unchanged ones it now shares. Its own
+	// update entry gives up what it applies.
+	own := make(map[string]bool, len(applied))
+	for ptr := range applied {
+		own[ptr] = true
+	}
+	for i, e := range entries {
+		if e.Manager == manager {
+			entries[i].Fields = withoutFields(e.Fields, own)
+		} else {
+			entries[i].Fields = withoutFields(e.Fields, taken)
+		}
+	}
+	return putEntry(entries, api.ManagedFieldsEntry{
+		Manager:   manager,
+		Operation: api.ManagedFieldsOperationApply,
+		Time:      now(),
+		Fields:    fields,
+	}), nil
+}
+
+// setField sets the field at path in obj, creating objects along the way.
+func setField(obj map[string]interface{}, path []string, value interface{}) {
+	for _, p := range path[:len(path)-1] {
+		child, ok := obj[p].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			obj[p] = child
+		}
+		obj = child
+	}
+	obj[path[len(path)-1]] = value
+}
+
+// removeField deletes the field at path from obj.
+func removeField(obj map[string]interface{}, path []string) {
+	for _, p := range path[:len(path)-1] {
+		child, ok := obj[p].(map[string]interface{})
+		if !ok {
+			return
+		}
+		obj = child
+	}
+	delete(obj, path[len(path)-1])
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
@@ -0,0 +1,210 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// owners summarizes entries as the fields of each, by manager and operation,
+// and subresource if any.
+func owners(entries []api.ManagedFieldsEntry) map[string][]string {
+	out := make(map[string][]string)
+	for _, e := range entries {
+		key := e.Manager + "/" + e.Operation
+		if e.Subresource != "" {
+			key += "/" + e.Subresource
+		}
+		fields := append([]string(nil), e.Fields...)
+		sort.Strings(fields)
+		out[key] = fields
+	}
+	return out
+}
+
+// unstructured decodes s, failing the test if it isn't a JSON object.
+func unstructured(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return m
+}
+
+func TestApplyFields(t *testing.T) {
+	// live is owned by "creator", which created it with replicas and image,
+	// and by "applier", which applied the labels and paused.
+	const live = `{
+		"metadata": {"name": "web", "labels": {"app": "web"}, "managedFields": [
+			{"manager": "creator", "operation": "Update", "fields": ["/spec/replicas", "/spec/template/image"]},
+			{"manager": "applier", "operation": "Apply", "fields": ["/metadata/labels/app", "/spec/paused"]},
+			{"manager": "other", "operation": "Apply", "fields": ["/metadata/labels/app"]}
+		]},
+		"spec": {"replicas": 1, "paused": true, "template": {"image": "nginx"}},
+		"status": {"replicas": 1}
+	}`
+	tests := []struct {
+		name      string
+		config    string
+		force     bool
+		conflicts errApplyConflicts
+		want      string // the object after the apply, without managed fields
+		owners    map[string][]string
+	}{
+		{
+			name:      "change a field another manager owns",
+			config:    `{"metadata": {"name": "web", "labels": {"app": "web"}}, "spec": {"replicas": 3, "paused": true}}`,
+			conflicts: errApplyConflicts{{Manager: "creator", Field: "/spec/replicas"}},
+		},
+		{
+			name:   "force",
+			config: `{"metadata": {"name": "web", "labels": {"app": "web"}}, "spec": {"replicas": 3, "paused": true}}`,
+			force:  true,
+			want:   `{"metadata": {"name": "web", "labels": {"app": "web"}}, "spec": {"replicas": 3, "paused": true, "template": {"image": "nginx"}}, "status": {"replicas": 1}}`,
+			owners: map[string][]string{
+				"creator/Update": {"/spec/template/image"},
+				"applier/Apply":  {"/metadata/labels/app", "/spec/paused", "/spec/replicas"},
+				"other/Apply":    {"/metadata/labels/app"},
+			},
+		},
+		{
+			name:   "set a field to the value it has",
+			config: `{"metadata": {"name": "web", "labels": {"app": "web"}}, "spec": {"replicas": 1, "paused": true}}`,
+			want:   `{"metadata": {"name": "web", "labels": {"app": "web"}}, "spec": {"replicas": 1, "paused": true, "template": {"image": "nginx"}}, "status": {"replicas": 1}}`,
+			owners: map[string][]string{
+				"creator/Update": {"/spec/replicas", "/spec/template/image"},
+				"applier/Apply":  {"/metadata/labels/app", "/spec/paused", "/spec/replicas"},
+				"other/Apply":    {"/metadata/labels/app"},
+			},
+		},
+		{
+			name:   "leave out fields applied before",
+			config: `{"metadata": {"name": "web"}}`,
+			// paused was only the applier's and goes; the label is kept for
+			// the other manager that applied it.
+			want: `{"metadata": {"name": "web", "labels": {"app": "web"}}, "spec": {"replicas": 1, "template": {"image": "nginx"}}, "status": {"replicas": 1}}`,
+			owners: map[string][]string{
+				"creator/Update": {"/spec/replicas", "/spec/template/image"},
+				"other/Apply":    {"/metadata/labels/app"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := unstructured(t, live)
+			entries, err := applyFields(obj, unstructured(t, tt.config), "applier", tt.force)
+			if tt.conflicts != nil {
+				if !reflect.DeepEqual(err, tt.conflicts) {
+					t.Fatalf("got error %v, want %v", err, tt.conflicts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// setManagedFields drops entries left without fields.
+			var kept []api.ManagedFieldsEntry
+			for _, e := range entries {
+				if len(e.Fields) > 0 {
+					kept = append(kept, e)
+				}
+			}
+			if got := owners(kept); !reflect.DeepEqual(got, tt.owners) {
+				t.Errorf("got owners %v, want %v", got, tt.owners)
+			}
+			delete(obj["metadata"].(map[string]interface{}), "managedFields")
+			if want := unstructured(t, tt.want); !reflect.DeepEqual(obj, want) {
+				t.Errorf("got object %v, want %v", obj, want)
+			}
+		})
+	}
+}
+
+func TestRecordUpdate(t *testing.T) {
+	existing := func() *api.Pod {
+		return &api.Pod{
+			ObjectMeta: api.ObjectMeta{
+				Name:   "web",
+				Labels: map[string]string{"app": "web", "tier": "frontend"},
+				ManagedFields: []api.ManagedFieldsEntry{
+					{Manager: "creator", Operation: api.ManagedFieldsOperationUpdate, Fields: []string{"/metadata/labels/app", "/metadata/labels/tier", "/spec/containers"}},
+					{Manager: "kubelet", Operation: api.ManagedFieldsOperationUpdate, Subresource: "status", Fields: []string{"/status/phase"}},
+				},
+			},
+			Spec:   api.PodSpec{Containers: []api.Container{{Name: "web", Image: "nginx"}}},
+			Status: api.PodStatus{Phase: "Pending"},
+		}
+	}
+	tests := []struct {
+		name   string
+		status bool // record a status write instead of a regular one
+		update func(pod *api.Pod)
+		owners map[string][]string
+	}{
+		{
+			name:   "no change",
+			update: func(pod *api.Pod) {},
+			owners: map[string][]string{
+				"creator/Update":        {"/metadata/labels/app", "/metadata/labels/tier", "/spec/containers"},
+				"kubelet/Update/status": {"/status/phase"},
+			},
+		},
+		{
+			name:   "change a field",
+			update: func(pod *api.Pod) { pod.Labels["app"] = "api" },
+			owners: map[string][]string{
+				"creator/Update":        {"/metadata/labels/tier", "/spec/containers"},
+				"editor/Update":         {"/metadata/labels/app"},
+				"kubelet/Update/status": {"/status/phase"},
+			},
+		},
+		{
+			name:   "add and remove fields",
+			update: func(pod *api.Pod) { pod.Labels = map[string]string{"app": "web", "track": "canary"} },
+			owners: map[string][]string{
+				"creator/Update":        {"/metadata/labels/app", "/spec/containers"},
+				"editor/Update":         {"/metadata/labels/track"},
+				"kubelet/Update/status": {"/status/phase"},
+			},
+		},
+		{
+			name:   "status is not recorded by a regular write",
+			update: func(pod *api.Pod) { pod.Status.Phase = "Running" },
+			owners: map[string][]string{
+				"creator/Update":        {"/metadata/labels/app", "/metadata/labels/tier", "/spec/containers"},
+				"kubelet/Update/status": {"/status/phase"},
+			},
+		},
+		{
+			name:   "status write",
+			status: true,
+			update: func(pod *api.Pod) {
+				pod.Status.Phase = "Running"
+				pod.Status.PodIP = "10.0.0.5"
+			},
+			owners: map[string][]string{
+				"creator/Update":       {"/metadata/labels/app", "/metadata/labels/tier", "/spec/containers"},
+				"editor/Update/status": {"/status/phase", "/status/podIP"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := existing()
+			tt.update(obj)
+			if tt.status {
+				recordStatusUpdate(obj, existing(), "editor")
+			} else {
+				recordUpdate(obj, existing(), "editor")
+			}
+			if got := owners(obj.ManagedFields); !reflect.DeepEqual(got, tt.owners) {
+				t.Errorf("got owners %v, want %v", got, tt.owners)
+			}
+		})
+	}
+}
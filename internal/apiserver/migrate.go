@@ -0,0 +1,104 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/storage"
+)
+
+// Before namespaces, every object was stored under /registry/<resource>/<name>.
+// Namespaced objects now live under /registry/<resource>/<namespace>/<name>,
+// so an object of a namespaced resource still at its old key shows up in
+// lists across all namespaces, but no request can get, update or delete it.
+
+// MigrateLegacyKeys moves the objects of namespaced resources stored before
+// namespaces existed into the default namespace. It must run before the
+// server serves requests. Objects it can't place, because the default
+// namespace already holds an object of the same name, are left where they are
+// and reported in the error, naming their keys.
+func (s *Server) MigrateLegacyKeys(ctx context.Context) error {
+	b, err := s.Store.Backup(ctx)
+	if err != nil {
+		return err
+	}
+
+	var stuck []string
+	for _, o := range b.Objects {
+		res, name, ok := s.legacyKey(o.Key)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("/registry/%s/%s/%s", res.Resource, api.NamespaceDefault, name)
+		if err := s.migrateObject(ctx, res, o, key); err == errMigrationCollision {
+			stuck = append(stuck, o.Key)
+		} else if err != nil {
+			return fmt.Errorf("migrate %s: %v", o.Key, err)
+		} else {
+			log.Printf("Moved %s to %s", o.Key, key)
+		}
+	}
+	if len(stuck) > 0 {
+		return fmt.Errorf("objects stored before namespaces collide with objects in the %q namespace: %s", api.NamespaceDefault, strings.Join(stuck, ", "))
+	}
+	return nil
+}
+
+var errMigrationCollision = errors.New("an object of the same name already exists")
+
+// legacyKey reports whether key is the pre-namespace key of an object of a
+// namespaced resource, and returns the resource and the object's name.
+func (s *Server) legacyKey(key string) (*resourceInfo, string, bool) {
+	rest, ok := strings.CutPrefix(key, "/registry/")
+	if !ok {
+		return nil, "", false
+	}
+	resource, name, ok := strings.Cut(rest, "/")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return nil, "", false
+	}
+	for _, res := range s.resources {
+		if res.Resource == resource && res.Namespaced {
+			return res, name, true
+		}
+	}
+	return nil, "", false
+}
+
+// migrateObject stores the object o holds at key, in the default namespace,
+// and then deletes it from its old key. A copy left at key by an earlier,
+// interrupted migration counts as already stored.
+func (s *Server) migrateObject(ctx context.Context, res *resourceInfo, o storage.BackupObject, key string) error {
+	obj := res.New()
+	if err := json.Unmarshal(o.Object, obj); err != nil {
+		return err
+	}
+	meta, _ := getObjectMeta(obj)
+	meta.Namespace = api.NamespaceDefault
+	meta.ResourceVersion = ""
+
+	err := s.Store.Create(ctx, key, obj)
+	if err == storage.ErrAlreadyExists {
+		existing := res.New()
+		if err := s.Store.Get(ctx, key, existing); err != nil {
+			return err
+		}
+		existingMeta, _ := getObjectMeta(existing)
+		existingMeta.ResourceVersion = ""
+		if !reflect.DeepEqual(existing, obj) {
+			return errMigrationCollision
+		}
+	} else if err != nil {
+		return err
+	}
+	if err := s.Store.Delete(ctx, o.Key); err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/storage"
+)
+
+func TestMigrateLegacyKeys(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	legacy := func(resource string, obj interface{}) {
+		meta, _ := getObjectMeta(obj)
+		if err := s.Store.Create(ctx, "/registry/"+resource+"/"+meta.Name, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy("pods", &api.Pod{ObjectMeta: api.ObjectMeta{Name: "web", Labels: map[string]string{"app": "web"}}})
+	legacy("services", &api.Service{ObjectMeta: api.ObjectMeta{Name: "web", Namespace: "prod"}})
+	// Cluster-scoped objects keep their keys.
+	do(t, s, "POST", "/api/v1/nodes", api.Node{ObjectMeta: api.ObjectMeta{Name: "node1"}})
+
+	if err := s.MigrateLegacyKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Running it again finds nothing to do.
+	if err := s.MigrateLegacyKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var pod api.Pod
+	w := do(t, s, "GET", "/api/v1/namespaces/default/pods/web", nil)
+	expectCode(t, w, http.StatusOK)
+	decode(t, w, &pod)
+	if pod.Namespace != api.NamespaceDefault || pod.Labels["app"] != "web" {
+		t.Errorf("got migrated pod %+v, want web in the default namespace", pod.ObjectMeta)
+	}
+	expectCode(t, do(t, s, "GET", "/api/v1/namespaces/default/services/web", nil), http.StatusOK)
+	expectCode(t, do(t, s, "GET", "/api/v1/nodes/node1", nil), http.StatusOK)
+
+	// The old keys are gone, so lists across namespaces hold each object once.
+	var pods api.PodList
+	w = do(t, s, "GET", "/api/v1/pods", nil)
+	expectCode(t, w, http.StatusOK)
+	decode(t, w, &pods)
+	if len(pods.Items) != 1 || pods.Items[0].Namespace != api.NamespaceDefault {
+		t.Errorf("got pods %+v, want only the migrated one", pods.Items)
+	}
+	expectCode(t, do(t, s, "DELETE", "/api/v1/namespaces/default/pods/web", nil), http.StatusOK)
+	if err := s.Store.Get(ctx, "/registry/pods/web", &api.Pod{}); err != storage.ErrNotFound {
+		t.Errorf("legacy key after the migration: got %v, want it deleted", err)
+	}
+}
+
+func TestMigrateLegacyKeysCollision(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	expectCode(t, do(t, s, "POST", "/api/v1/namespaces/default/pods", api.Pod{
+		ObjectMeta: api.ObjectMeta{Name: "web"},
+		Spec:       api.PodSpec{Containers: []api.Container{{Name: "web", Image: "nginx"}}},
+	}), http.StatusCreated)
+	legacy := &api.Pod{ObjectMeta: api.ObjectMeta{Name: "web"}}
+	if err := s.Store.Create(ctx, "/registry/pods/web", legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.MigrateLegacyKeys(ctx)
+	if err == nil || !strings.Contains(err.Error(), "/registry/pods/web") {
+		t.Fatalf("got %v, want an error naming /registry/pods/web", err)
+	}
+	// Neither object is touched.
+	if err := s.Store.Get(ctx, "/registry/pods/web", &api.Pod{}); err != nil {
+		t.Errorf("legacy object: %v", err)
+	}
+	var pod api.Pod
+	w := do(t, s, "GET", "/api/v1/namespaces/default/pods/web", nil)
+	expectCode(t, w, http.StatusOK)
+	decode(t, w, &pod)
+	if len(pod.Spec.Containers) != 1 {
+		t.Errorf("got %+v, want the pod created in the default namespace", pod)
+	}
+}
+
+// A migration interrupted after storing the copy finishes on the next start.
+func TestMigrateLegacyKeysResumes(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	if err := s.Store.Create(ctx, "/registry/pods/web", &api.Pod{ObjectMeta: api.ObjectMeta{Name: "web"}}); err != nil {
+		t.Fatal(err)
+	}
+	moved := &api.Pod{ObjectMeta: api.ObjectMeta{Name: "web", Namespace: api.NamespaceDefault}}
+	if err := s.Store.Create(ctx, "/registry/pods/default/web", moved); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.MigrateLegacyKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Store.Get(ctx, "/registry/pods/web", &api.Pod{}); err != storage.ErrNotFound {
+		t.Errorf("legacy key after the migration: got %v, want it deleted", err)
+	}
+}
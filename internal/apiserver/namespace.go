@@ -0,0 +1,233 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Namespace lifecycle: deleting a namespace only marks it as Terminating. The
+// namespace controller then deletes everything in it and removes its
+// finalizer through the finalize subresource, which deletes the namespace
+// once no finalizers are left.
+
+// errNamespaceNotEmpty is the conflict of finalizing a namespace that still
+// holds objects.
+var errNamespaceNotEmpty = errors.New("the namespace still has content")
+
+// admitNamespace rejects new objects in a namespace that doesn't exist or is
+// being deleted. It returns nil if the object may be created.
+//
+// The check reads the namespace before the object is written, so a create
+// can still land after the namespace is marked Terminating. Creates therefore
+// check again once the object is stored, see confirmNamespace, and the
+// finalize subresource refuses to release a namespace that still holds
+// objects.
+func (s *Server) admitNamespace(r *http.Request) render.Renderer {
+	name := chi.URLParam(r, "namespace")
+	if name == "" {
+		return nil
+	}
+
+	var ns api.Namespace
+	if err := s.Store.Get(r.Context(), fmt.Sprintf("/registry/namespaces/%s", name), &ns); err != nil {
+		if err == storage.ErrNotFound {
+			return ErrNotFound(groupResource{Resource: "namespaces"}, name)
+		}
+		return ErrInternal(err)
+	}
+	if ns.Status.Phase == api.NamespaceTerminating {
+		return ErrForbidden(fmt.Errorf("unable to create new content in namespace %s because it is being terminated", name))
+	}
+	return nil
+}
+
+// confirmNamespace checks the namespace of an object just stored at key
+// again, and deletes the object if the namespace started terminating in the
+// meantime. An object that passes was stored while the namespace was still
+// active, so it is there for the namespace controller to find and the
+// namespace isn't finalized before it is gone.
+func (s *Server) confirmNamespace(r *http.Request, res *resourceInfo, key string) render.Renderer {
+	if !res.Namespaced {
+		return nil
+	}
+	errResp := s.admitNamespace(r)
+	if errResp == nil {
+		return nil
+	}
+	if err := s.Store.Delete(r.Context(), key); err != nil && err != storage.ErrNotFound {
+		return ErrInternal(err)
+	}
+	return errResp
+}
+
+// namespaceHasContent reports whether any namespaced resource has objects in
+// the namespace name.
+func (s *Server) namespaceHasContent(ctx context.Context, name string) (bool, error) {
+	for _, res := range s.resources {
+		if !res.Namespaced {
+			continue
+		}
+		_, items := res.newList()
+		prefix := fmt.Sprintf("/registry/%s/%s/", res.Resource, name)
+		if _, err := s.Store.List(ctx, prefix, storage.ListOptions{Limit: 1}, items); err != nil {
+			return false, err
+		}
+		if reflect.ValueOf(items).Elem().Len() > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Server) deleteNamespace(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if name == api.NamespaceDefault {
+		render.Render(w, r, ErrForbidden(fmt.Errorf("namespace %s may not be deleted", name)))
+		return
+	}
+	key := objectKey(r, "namespaces", name)
+
+	var ns api.Namespace
+	if err := s.Store.Get(r.Context(), key, &ns); err != nil {
+		if err == storage.ErrNotFound {
+			render.Render(w, r, ErrNotFound(groupResource{Resource: "namespaces"}, name))
+		} else {
+			render.Render(w, r, ErrInternal(err))
+		}
+		return
+	}
+
+	if len(ns.Spec.Finalizers) == 0 {
+		if err := s.Store.Delete(r.Context(), key); err != nil && err != storage.ErrNotFound {
+			render.Render(w, r, ErrInternal(err))
+			return
+		}
+		render.JSON(w, r, map[string]string{"status": "deleted"})
+		return
+	}
+
+	if ns.DeletionTimestamp == nil {
+		now := time.Now()
+		ns.DeletionTimestamp = &now
+		ns.Status.Phase = api.NamespaceTerminating
+		// ns still carries the version we read, so a concurrent change makes this fail.
+		if err := s.Store.Update(r.Context(), key, &ns); err != nil {
+			if err == storage.ErrConflict {
+				render.Render(w, r, ErrConflict(groupResource{Resource: "namespaces"}, name, errModified))
+			} else {
+				render.Render(w, r, ErrInternal(err))
+			}
+			return
+		}
+	}
+
+	// Still in the store until it's finalized, so return the object like Kubernetes does.
+	render.JSON(w, r, &ns)
+}
+
+// handleNamespaceFinalize replaces the finalizers of a namespace, deleting it
+// if it is terminating and none are left. The finalizer of the namespace
+// controller can't be removed from a terminating namespace while objects are
+// left in it.
+func (s *Server) handleNamespaceFinalize(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	key := objectKey(r, "namespaces", name)
+
+	var update api.Namespace
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+
+	var ns api.Namespace
+	if err := s.Store.Get(r.Context(), key, &ns); err != nil {
+		if err == storage.ErrNotFound {
+			render.Render(w, r, ErrNotFound(groupResource{Resource: "namespaces"}, name))
+		} else {
+			render.Render(w, r, ErrInternal(err))
+		}
+		return
+	}
+
+	if ns.DeletionTimestamp != nil && hasFinalizer(ns.Spec.Finalizers, api.FinalizerKubernetes) &&
+		!hasFinalizer(update.Spec.Finalizers, api.FinalizerKubernetes) {
+		found, err := s.namespaceHasContent(r.Context(), name)
+		if err != nil {
+			render.Render(w, r, ErrInternal(err))
+			return
+		}
+		if found {
+			render.Render(w, r, ErrConflict(groupResource{Resource: "namespaces"}, name, errNamespaceNotEmpty))
+			return
+		}
+	}
+
+	// Only the finalizers can change here; everything else is kept as stored.
+	if update.ResourceVersion != "" {
+		ns.ResourceVersion = update.ResourceVersion
+	}
+	ns.Spec.Finalizers = update.Spec.Finalizers
+
+	if err := s.Store.Update(r.Context(), key, &ns); err != nil {
+		if err == storage.ErrNotFound {
+			render.Render(w, r, ErrNotFound(groupResource{Resource: "namespaces"}, name))
+		} else if err == storage.ErrConflict {
+			render.Render(w, r, ErrConflict(groupResource{Resource: "namespaces"}, name, errModified))
+		} else {
+			render.Render(w, r, ErrInternal(err))
+		}
+		return
+	}
+
+	if ns.DeletionTimestamp != nil && len(ns.Spec.Finalizers) == 0 {
+		if err := s.Store.Delete(r.Context(), key); err != nil && err != storage.ErrNotFound {
+			render.Render(w, r, ErrInternal(err))
+			return
+		}
+	}
+
+	render.JSON(w, r, &ns)
+}
+
+// prepareNamespace starts the lifecycle of a new namespace: it is active, and
+// can't go away before the namespace controller has emptied it.
+func prepareNamespace(ns *api.Namespace) {
+	ns.Spec.Finalizers = addFinalizer(ns.Spec.Finalizers, api.FinalizerKubernetes)
+	ns.Status.Phase = api.NamespaceActive
+	ns.DeletionTimestamp = nil
+}
+
+// keepNamespaceLifecycle copies the lifecycle fields of the stored namespace
+// onto ns, so a plain update can neither revive a terminating namespace nor
+// drop its finalizers.
+func keepNamespaceLifecycle(ns, stored *api.Namespace) {
+	ns.DeletionTimestamp = stored.DeletionTimestamp
+	ns.Spec.Finalizers = stored.Spec.Finalizers
+	ns.Status.Phase = stored.Status.Phase
+}
+
+func addFinalizer(finalizers []string, finalizer string) []string {
+	if hasFinalizer(finalizers, finalizer) {
+		return finalizers
+	}
+	return append(finalizers, finalizer)
+}
+
+func hasFinalizer(finalizers []string, finalizer string) bool {
+	for _, f := range finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
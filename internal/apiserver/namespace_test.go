@@ -0,0 +1,130 @@
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/controller/namespace"
+	"github.com/abhigod/k8s-lite/internal/informer"
+	"github.com/abhigod/k8s-lite/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// A terminating namespace is only released once it is empty, and takes no
+// new objects in the meantime.
+func TestNamespaceFinalize(t *testing.T) {
+	const ns = "/api/v1/namespaces/team"
+	s := newTestServer(t)
+	expectCode(t, do(t, s, "POST", "/api/v1/namespaces", api.Namespace{ObjectMeta: api.ObjectMeta{Name: "team"}}), http.StatusCreated)
+	pod := api.Pod{
+		ObjectMeta: api.ObjectMeta{Name: "web"},
+		Spec:       api.PodSpec{Containers: []api.Container{{Name: "web", Image: "nginx"}}},
+	}
+	expectCode(t, do(t, s, "POST", ns+"/pods", pod), http.StatusCreated)
+
+	expectCode(t, do(t, s, "DELETE", ns, nil), http.StatusOK)
+	pod.Name = "late"
+	expectCode(t, do(t, s, "POST", ns+"/pods", pod), http.StatusForbidden)
+
+	// The pod is still there, so the finalizer stays.
+	empty := api.Namespace{ObjectMeta: api.ObjectMeta{Name: "team"}}
+	expectCode(t, do(t, s, "PUT", ns+"/finalize", empty), http.StatusConflict)
+	var got api.Namespace
+	decode(t, do(t, s, "GET", ns, nil), &got)
+	if got.Status.Phase != api.NamespaceTerminating || len(got.Spec.Finalizers) != 1 {
+		t.Fatalf("got phase %s finalizers %v, want Terminating with the kubernetes finalizer", got.Status.Phase, got.Spec.Finalizers)
+	}
+
+	expectCode(t, do(t, s, "DELETE", ns+"/pods/web", nil), http.StatusOK)
+	expectCode(t, do(t, s, "PUT", ns+"/finalize", empty), http.StatusOK)
+	expectCode(t, do(t, s, "GET", ns, nil), http.StatusNotFound)
+}
+
+// An object that was admitted while its namespace was active, but stored
+// after the namespace started terminating, is deleted again.
+func TestConfirmNamespace(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	expectCode(t, do(t, s, "POST", "/api/v1/namespaces", api.Namespace{ObjectMeta: api.ObjectMeta{Name: "team"}}), http.StatusCreated)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("namespace", "team")
+	r := httptest.NewRequest("POST", "/api/v1/namespaces/team/pods", nil)
+	r = r.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+	var pods *resourceInfo
+	for _, res := range s.resources {
+		if res.Resource == "pods" {
+			pods = res
+		}
+	}
+	const key = "/registry/pods/team/web"
+	if err := s.Store.Create(ctx, key, &api.Pod{ObjectMeta: api.ObjectMeta{Name: "web", Namespace: "team"}}); err != nil {
+		t.Fatal(err)
+	}
+	if errResp := s.confirmNamespace(r, pods, key); errResp != nil {
+		t.Fatalf("active namespace: got %+v", errResp)
+	}
+
+	expectCode(t, do(t, s, "DELETE", "/api/v1/namespaces/team", nil), http.StatusOK)
+	errResp := s.confirmNamespace(r, pods, key)
+	if errResp == nil || errResp.(*ErrResponse).Code != http.StatusForbidden {
+		t.Fatalf("terminating namespace: got %+v, want forbidden", errResp)
+	}
+	if err := s.Store.Get(ctx, key, &api.Pod{}); err != storage.ErrNotFound {
+		t.Errorf("pod in terminating namespace: got %v, want it deleted", err)
+	}
+}
+
+// The namespace controller deletes the objects of every namespaced resource
+// the apiserver serves, including resources it wasn't written for.
+func TestNamespaceControllerDeletesRegisteredResources(t *testing.T) {
+	store, err := storage.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Widgets are stored as leases; only the registry entry is new.
+	widgets := &resourceInfo{
+		Group: "shop", Version: "v1", Resource: "widgets", Kind: "Widget", Namespaced: true,
+		New:     func() interface{} { return &api.Lease{} },
+		NewList: func() interface{} { return &api.LeaseList{} },
+	}
+	s := &Server{Store: store, Router: chi.NewRouter(), resources: append(builtinResources(), widgets)}
+	s.routes()
+	s.ensureDefaultNamespace()
+
+	srv := httptest.NewServer(s.Router)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &client.Client{BaseURL: srv.URL, HTTP: srv.Client()}
+
+	informers := informer.NewFactory(c, 0)
+	ctrl := namespace.New(c, informers)
+	informers.Start(ctx)
+	if !informers.WaitForCacheSync(ctx) {
+		t.Fatal("caches did not sync")
+	}
+	go ctrl.Run(ctx, 1)
+
+	const ns = "/api/v1/namespaces/team"
+	expectCode(t, do(t, s, "POST", "/api/v1/namespaces", api.Namespace{ObjectMeta: api.ObjectMeta{Name: "team"}}), http.StatusCreated)
+	expectCode(t, do(t, s, "POST", "/apis/shop/v1/namespaces/team/widgets", api.Lease{ObjectMeta: api.ObjectMeta{Name: "w1"}}), http.StatusCreated)
+	expectCode(t, do(t, s, "POST", "/apis/shop/v1/namespaces/default/widgets", api.Lease{ObjectMeta: api.ObjectMeta{Name: "w2"}}), http.StatusCreated)
+	expectCode(t, do(t, s, "DELETE", ns, nil), http.StatusOK)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for do(t, s, "GET", ns, nil).Code != http.StatusNotFound {
+		if time.Now().After(deadline) {
+			t.Fatal("namespace was not deleted")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	expectCode(t, do(t, s, "GET", "/apis/shop/v1/namespaces/team/widgets/w1", nil), http.StatusNotFound)
+	expectCode(t, do(t, s, "GET", "/apis/shop/v1/namespaces/default/widgets/w2", nil), http.StatusOK)
+}
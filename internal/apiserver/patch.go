@@ -0,0 +1,102 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/abhigod/k8s-lite/internal/storage"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Patch types accepted by PATCH .../{name}, by Content-Type.
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// handlePatch applies a JSON merge patch (RFC 7386) or a JSON patch
+// (RFC 6902) to the stored object, or hands an apply configuration to
+// handleApply. The patch is applied to whatever version is stored when the
+// write happens, so it never needs a read-modify-write cycle on the client;
+// a patch that sets metadata.resourceVersion makes the write conditional on
+// that version instead. The patched object goes through the same checks as a
+// PUT.
+func (s *Server) handlePatch(res *resourceInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		key := objectKey(r, res.Resource, name)
+
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if contentType == applyPatchType {
+			s.handleApply(res, w, r)
+			return
+		}
+		if contentType != mergePatchType && contentType != jsonPatchType {
+			render.Render(w, r, ErrUnsupportedMediaType(fmt.Errorf("unsupported patch type %q, use %s, %s or %s", contentType, mergePatchType, jsonPatchType, applyPatchType)))
+			return
+		}
+		patch, err := io.ReadAll(r.Body)
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+
+		var applyPatch func(doc []byte) ([]byte, error)
+		if contentType == mergePatchType {
+			if !json.Valid(patch) {
+				render.Render(w, r, ErrInvalidRequest(fmt.Errorf("invalid merge patch")))
+				return
+			}
+			applyPatch = func(doc []byte) ([]byte, error) {
+				return jsonpatch.MergePatch(doc, patch)
+			}
+		} else {
+			ops, err := jsonpatch.DecodePatch(patch)
+			if err != nil {
+				render.Render(w, r, ErrInvalidRequest(fmt.Errorf("invalid JSON patch: %v", err)))
+				return
+			}
+			applyPatch = ops.Apply
+		}
+
+		updated, err := s.guaranteedUpdate(r.Context(), key, res, "", func(existing interface{}) (interface{}, error) {
+			doc, err := json.Marshal(existing)
+			if err != nil {
+				return nil, err
+			}
+			patched, err := applyPatch(doc)
+			if err != nil {
+				return nil, errInvalidUpdate{fmt.Errorf("unable to apply patch: %v", err)}
+			}
+			obj := res.New()
+			if err := json.Unmarshal(patched, obj); err != nil {
+				return nil, errInvalidUpdate{fmt.Errorf("patched object is invalid: %v", err)}
+			}
+
+			meta, _ := getObjectMeta(obj)
+			existingMeta, _ := getObjectMeta(existing)
+			if meta.Name != existingMeta.Name || meta.Namespace != existingMeta.Namespace {
+				return nil, errInvalidUpdate{fmt.Errorf("metadata.name and metadata.namespace cannot be changed by a patch")}
+			}
+			if meta.ResourceVersion != existingMeta.ResourceVersion {
+				return nil, storage.ErrConflict
+			}
+			if err := admitUpdate(res, obj, existing); err != nil {
+				return nil, err
+			}
+			recordUpdate(obj, existing, fieldManager(r))
+			return obj, nil
+		})
+		if err != nil {
+			renderUpdateError(w, r, res.groupResource(), err)
+			return
+		}
+
+		render.JSON(w, r, updated)
+	}
+}
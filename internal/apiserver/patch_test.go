@@ -0,0 +1,184 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// doPatch sends a PATCH with body of the given Content-Type to s.
+func doPatch(t *testing.T, s *Server, path, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("PATCH", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "test")
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	return w
+}
+
+func testDeployment(name string, replicas int32) api.Deployment {
+	labels := map[string]string{"app": name}
+	return api.Deployment{
+		ObjectMeta: api.ObjectMeta{Name: name},
+		Spec: api.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: api.LabelSelector{MatchLabels: labels},
+			Template: api.PodTemplateSpec{
+				ObjectMeta: api.ObjectMeta{Labels: labels},
+				Spec:       api.PodSpec{Containers: []api.Container{{Name: name, Image: "nginx"}}},
+			},
+		},
+	}
+}
+
+func TestHandlePatch(t *testing.T) {
+	const deployments = "/apis/apps/v1/namespaces/default/deployments"
+	tests := []struct {
+		name        string
+		target      string // the deployment patched, if not web
+		contentType string
+		patch       string // $rv stands for the stored resourceVersion
+		want        int
+		replicas    int32 // stored after the patch
+	}{
+		{
+			name:        "merge patch scales",
+			contentType: mergePatchType,
+			patch:       `{"spec": {"replicas": 3}}`,
+			want:        http.StatusOK,
+			replicas:    3,
+		},
+		{
+			name:        "merge patch with the current resourceVersion",
+			contentType: mergePatchType,
+			patch:       `{"metadata": {"resourceVersion": "$rv"}, "spec": {"replicas": 3}}`,
+			want:        http.StatusOK,
+			replicas:    3,
+		},
+		{
+			name:        "merge patch with a stale resourceVersion",
+			contentType: mergePatchType,
+			patch:       `{"metadata": {"resourceVersion": "1"}, "spec": {"replicas": 3}}`,
+			want:        http.StatusConflict,
+			replicas:    1,
+		},
+		{
+			name:        "merge patch changes the name",
+			contentType: mergePatchType,
+			patch:       `{"metadata": {"name": "api"}}`,
+			want:        http.StatusBadRequest,
+			replicas:    1,
+		},
+		{
+			name:        "merge patch changes the namespace",
+			contentType: mergePatchType,
+			patch:       `{"metadata": {"namespace": "kube-system"}}`,
+			want:        http.StatusBadRequest,
+			replicas:    1,
+		},
+		{
+			name:        "status is ignored",
+			contentType: mergePatchType,
+			patch:       `{"spec": {"replicas": 2}, "status": {"replicas": 9, "observedGeneration": 9}}`,
+			want:        http.StatusOK,
+			replicas:    2,
+		},
+		{
+			name:        "malformed merge patch",
+			contentType: mergePatchType,
+			patch:       `{"spec": `,
+			want:        http.StatusBadRequest,
+			replicas:    1,
+		},
+		{
+			name:        "patched object is invalid",
+			contentType: mergePatchType,
+			patch:       `{"spec": {"replicas": -1}}`,
+			want:        http.StatusUnprocessableEntity,
+			replicas:    1,
+		},
+		{
+			name:        "json patch replace",
+			contentType: jsonPatchType + "; charset=utf-8",
+			patch:       `[{"op": "replace", "path": "/spec/replicas", "value": 4}]`,
+			want:        http.StatusOK,
+			replicas:    4,
+		},
+		{
+			name:        "json patch test passes",
+			contentType: jsonPatchType,
+			patch:       `[{"op": "test", "path": "/spec/replicas", "value": 1}, {"op": "replace", "path": "/spec/replicas", "value": 5}]`,
+			want:        http.StatusOK,
+			replicas:    5,
+		},
+		{
+			name:        "json patch test fails",
+			contentType: jsonPatchType,
+			patch:       `[{"op": "test", "path": "/spec/replicas", "value": 7}, {"op": "replace", "path": "/spec/replicas", "value": 5}]`,
+			want:        http.StatusBadRequest,
+			replicas:    1,
+		},
+		{
+			name:        "json patch of a missing path",
+			contentType: jsonPatchType,
+			patch:       `[{"op": "replace", "path": "/spec/paused/value", "value": true}]`,
+			want:        http.StatusBadRequest,
+			replicas:    1,
+		},
+		{
+			name:        "malformed json patch",
+			contentType: jsonPatchType,
+			patch:       `{"op": "replace"}`,
+			want:        http.StatusBadRequest,
+			replicas:    1,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "application/json",
+			patch:       `{"spec": {"replicas": 3}}`,
+			want:        http.StatusUnsupportedMediaType,
+			replicas:    1,
+		},
+		{
+			name:        "missing object",
+			target:      "api",
+			contentType: mergePatchType,
+			patch:       `{"spec": {"replicas": 3}}`,
+			want:        http.StatusNotFound,
+			replicas:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			var created api.Deployment
+			w := do(t, s, "POST", deployments, testDeployment("web", 1))
+			expectCode(t, w, http.StatusCreated)
+			decode(t, w, &created)
+
+			target := tt.target
+			if target == "" {
+				target = "web"
+			}
+			patch := strings.ReplaceAll(tt.patch, "$rv", created.ResourceVersion)
+			w = doPatch(t, s, deployments+"/"+target, tt.contentType, patch)
+			expectCode(t, w, tt.want)
+
+			var got api.Deployment
+			decode(t, do(t, s, "GET", deployments+"/web", nil), &got)
+			if got.Spec.Replicas == nil || *got.Spec.Replicas != tt.replicas {
+				t.Errorf("stored replicas %v, want %d", got.Spec.Replicas, tt.replicas)
+			}
+			if got.Status.Replicas != 0 || got.Status.ObservedGeneration != 0 {
+				t.Errorf("patch changed the status to %+v", got.Status)
+			}
+			if tt.want != http.StatusOK && got.ResourceVersion != created.ResourceVersion {
+				t.Errorf("failed patch stored resourceVersion %s, want %s unchanged", got.ResourceVersion, created.ResourceVersion)
+			}
+		})
+	}
+}
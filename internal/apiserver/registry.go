@@ -0,0 +1,223 @@
+package apiserver
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/validation"
+)
+
+// resourceInfo describes a resource the apiserver serves: where its API is,
+// the types of its objects, and the steps of a write that differ between
+// resources. The handlers only go through it, so serving a new type takes
+// nothing but an entry in builtinResources.
+type resourceInfo struct {
+	Group      string // "" for the core group, served under /api
+	Version    string
+	Resource   string // plural name used in paths and storage keys, e.g. "pods"
+	Kind       string
+	ShortNames []string // accepted by clients in place of Resource, e.g. "po"
+	Namespaced bool
+
+	// New returns an empty object, NewList an empty list of objects.
+	New     func() interface{}
+	NewList func() interface{}
+
+	strategy
+}
+
+// strategy holds the hooks that make a resource more than stored JSON. Any
+// of them may be nil.
+type strategy struct {
+	// Default fills in the empty fields of an object that have a default.
+	Default func(obj interface{})
+	// Validate checks an object after its defaults are set.
+	Validate func(obj interface{}) validation.ErrorList
+	// PrepareForCreate sets the fields the server owns on a new object.
+	PrepareForCreate func(obj interface{})
+	// PrepareForUpdate carries over what a regular update of existing can't
+	// change to obj, or rejects the update with an error.
+	PrepareForUpdate func(obj, existing interface{}) error
+	// CopyStatus sets the status of dst to that of src. Resources that have
+	// it serve a /status subresource, and keep the stored status on every
+	// other write.
+	CopyStatus func(dst, src interface{})
+	// ValidateStatus checks an object after a write to its status.
+	ValidateStatus func(obj interface{}) validation.ErrorList
+	// Delete replaces the regular DELETE .../{name}.
+	Delete func(s *Server, w http.ResponseWriter, r *http.Request)
+	// Subresources are served besides status.
+	Subresources []subresource
+}
+
+// subresource is an endpoint under .../{name}/Name of a resource.
+type subresource struct {
+	Name string
+	// Kind is what the endpoint takes, if it isn't the kind of the resource.
+	Kind string
+	// Verb is what the endpoint does: "create" for POST, "update" for PUT.
+	Verb    string
+	Handler func(s *Server, res *resourceInfo) http.HandlerFunc
+}
+
+// verbMethods maps verbs to the HTTP methods that perform them on a single
+// object or subresource.
+var verbMethods = map[string]string{
+	"get":    http.MethodGet,
+	"create": http.MethodPost,
+	"update": http.MethodPut,
+	"patch":  http.MethodPatch,
+	"delete": http.MethodDelete,
+}
+
+// builtinResources returns the resources the apiserver serves.
+func builtinResources() []*resourceInfo {
+	return []*resourceInfo{
+		{
+			Version: "v1", Resource: "namespaces", Kind: "Namespace", ShortNames: []string{"ns"},
+			New:     func() interface{} { return &api.Namespace{} },
+			NewList: func() interface{} { return &api.NamespaceList{} },
+			strategy: strategy{
+				Validate:         validateAs(validation.ValidateNamespace),
+				PrepareForCreate: func(obj interface{}) { prepareNamespace(obj.(*api.Namespace)) },
+				PrepareForUpdate: func(obj, existing interface{}) error {
+					keepNamespaceLifecycle(obj.(*api.Namespace), existing.(*api.Namespace))
+					return nil
+				},
+				Delete: (*Server).deleteNamespace,
+				Subresources: []subresource{{
+					Name: "finalize", Verb: "update",
+					Handler: func(s *Server, _ *resourceInfo) http.HandlerFunc { return s.handleNamespaceFinalize },
+				}},
+			},
+		},
+		{
+			Version: "v1", Resource: "pods", Kind: "Pod", ShortNames: []string{"po"}, Namespaced: true,
+			New:     func() interface{} { return &api.Pod{} },
+			NewList: func() interface{} { return &api.PodList{} },
+			strategy: strategy{
+				Default:  defaultAs(validation.SetPodDefaults),
+				Validate: validateAs(validation.ValidatePod),
+				PrepareForUpdate: func(obj, existing interface{}) error {
+					return checkPodUpdate(obj.(*api.Pod), existing.(*api.Pod))
+				},
+				CopyStatus:     func(dst, src interface{}) { dst.(*api.Pod).Status = src.(*api.Pod).Status },
+				ValidateStatus: validateAs(validation.ValidatePodStatus),
+				Subresources: []subresource{{
+					Name: "binding", Kind: "Binding", Verb: "create",
+					Handler: func(s *Server, res *resourceInfo) http.HandlerFunc {
+						return func(w http.ResponseWriter, r *http.Request) { s.handleBinding(res, w, r) }
+					},
+				}},
+			},
+		},
+		{
+			Version: "v1", Resource: "nodes", Kind: "Node", ShortNames: []string{"no"},
+			New:     func() interface{} { return &api.Node{} },
+			NewList: func() interface{} { return &api.NodeList{} },
+			strategy: strategy{
+				Validate:       validateAs(validation.ValidateNode),
+				CopyStatus:     func(dst, src interface{}) { dst.(*api.Node).Status = src.(*api.Node).Status },
+				ValidateStatus: validateAs(validation.ValidateNodeStatus),
+			},
+		},
+		{
+			Group: "apps", Version: "v1", Resource: "replicasets", Kind: "ReplicaSet", ShortNames: []string{"rs"}, Namespaced: true,
+			New:     func() interface{} { return &api.ReplicaSet{} },
+			NewList: func() interface{} { return &api.ReplicaSetList{} },
+			strategy: strategy{
+				Default:        defaultAs(validation.SetReplicaSetDefaults),
+				Validate:       validateAs(validation.ValidateReplicaSet),
+				CopyStatus:     func(dst, src interface{}) { dst.(*api.ReplicaSet).Status = src.(*api.ReplicaSet).Status },
+				ValidateStatus: validateAs(validation.ValidateReplicaSetStatus),
+			},
+		},
+		{
+			Group: "apps", Version: "v1", Resource: "deployments", Kind: "Deployment", ShortNames: []string{"deploy"}, Namespaced: true,
+			New:     func() interface{} { return &api.Deployment{} },
+			NewList: func() interface{} { return &api.DeploymentList{} },
+			strategy: strategy{
+				Default:        defaultAs(validation.SetDeploymentDefaults),
+				Validate:       validateAs(validation.ValidateDeployment),
+				CopyStatus:     func(dst, src interface{}) { dst.(*api.Deployment).Status = src.(*api.Deployment).Status },
+				ValidateStatus: validateAs(validation.ValidateDeploymentStatus),
+			},
+		},
+		{
+			Version: "v1", Resource: "services", Kind: "Service", ShortNames: []string{"svc"}, Namespaced: true,
+			New:     func() interface{} { return &api.Service{} },
+			NewList: func() interface{} { return &api.ServiceList{} },
+			strategy: strategy{
+				Default:        defaultAs(validation.SetServiceDefaults),
+				Validate:       validateAs(validation.ValidateService),
+				CopyStatus:     func(dst, src interface{}) { dst.(*api.Service).Status = src.(*api.Service).Status },
+				ValidateStatus: validateAs(validation.ValidateServiceStatus),
+			},
+		},
+		{
+			Version: "v1", Resource: "endpoints", Kind: "Endpoints", ShortNames: []string{"ep"}, Namespaced: true,
+			New:      func() interface{} { return &api.Endpoints{} },
+			NewList:  func() interface{} { return &api.EndpointsList{} },
+			strategy: strategy{Validate: validateAs(validation.ValidateEndpoints)},
+		},
+		{
+			Version: "v1", Resource: "leases", Kind: "Lease", Namespaced: true,
+			New:      func() interface{} { return &api.Lease{} },
+			NewList:  func() interface{} { return &api.LeaseList{} },
+			strategy: strategy{Validate: validateAs(validation.ValidateLease)},
+		},
+	}
+}
+
+// defaultAs adapts a defaulting function of *T to a Default hook.
+func defaultAs[T any](fn func(*T)) func(interface{}) {
+	return func(obj interface{}) { fn(obj.(*T)) }
+}
+
+// validateAs adapts a validation function of *T to a Validate hook.
+func validateAs[T any](fn func(*T) validation.ErrorList) func(interface{}) validation.ErrorList {
+	return func(obj interface{}) validation.ErrorList { return fn(obj.(*T)) }
+}
+
+// groupVersion returns the API version of the resource, e.g. "apps/v1", or
+// just "v1" in the core group.
+func (res *resourceInfo) groupVersion() string {
+	if res.Group == "" {
+		return res.Version
+	}
+	return res.Group + "/" + res.Version
+}
+
+// prefix returns the path the API of the resource is served under, e.g.
+// /apis/apps/v1.
+func (res *resourceInfo) prefix() string {
+	if res.Group == "" {
+		return path.Join("/api", res.Version)
+	}
+	return path.Join("/apis", res.Group, res.Version)
+}
+
+// subresources returns the subresources of res, including status if it has
+// a status subresource.
+func (res *resourceInfo) subresources() []subresource {
+	if res.CopyStatus == nil {
+		return res.Subresources
+	}
+	status := subresource{Name: "status", Verb: "update", Handler: (*Server).handleUpdateStatus}
+	return append([]subresource{status}, res.Subresources...)
+}
+
+func (res *resourceInfo) groupResource() groupResource {
+	return groupResource{Group: res.Group, Resource: res.Resource}
+}
+
+// newList returns an empty list of the resource, and a pointer to its items
+// for storage.Store.List to fill.
+func (res *resourceInfo) newList() (list api.ListObject, items interface{}) {
+	list = res.NewList().(api.ListObject)
+	v := reflect.ValueOf(list).Elem()
+	v.FieldByName("TypeMeta").Set(reflect.ValueOf(api.TypeMeta{Kind: res.Kind + "List", APIVersion: res.groupVersion()}))
+	return list, v.FieldByName("Items").Addr().Interface()
+}
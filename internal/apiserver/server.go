@@ -1,17 +1,24 @@
 package apiserver
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"path"
+	"strconv"
+	"strings"
 
 	"time"
 
 	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/fields"
+	"github.com/abhigod/k8s-lite/internal/labels"
 	"github.com/abhigod/k8s-lite/internal/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -24,6 +31,9 @@ import (
 type Server struct {
 	Store  storage.Store
 	Router *chi.Mux
+
+	// resources are the resources served, see registry.go.
+	resources []*resourceInfo
 }
 
 var (
@@ -45,10 +55,12 @@ var (
 
 func NewServer(store storage.Store) *Server {
 	s := &Server{
-		Store:  store,
-		Router: chi.NewRouter(),
+		Store:     store,
+		Router:    chi.NewRouter(),
+		resources: builtinResources(),
 	}
 	s.routes()
+	s.ensureDefaultNamespace()
 	return s
 }
 
@@ -66,159 +78,155 @@ func (s *Server) routes() {
 
 	s.Router.Handle("/metrics", promhttp.Handler())
 
-	// /api/v1/pods
-	s.registerResourceRoutes("/api/v1", "pods", &api.Pod{}, &api.PodList{})
-
-	// /api/v1/nodes
-	s.registerResourceRoutes("/api/v1", "nodes", &api.Node{}, &api.NodeList{})
-
-	// /apis/apps/v1/replicasets (simplifying to /api/v1 for MVP simplicity if desired, but sticking to structure)
-	s.registerResourceRoutes("/apis/apps/v1", "replicasets", &api.ReplicaSet{}, &api.ReplicaSetList{})
-
-	// /apis/apps/v1/deployments
-	s.registerResourceRoutes("/apis/apps/v1", "deployments", &api.Deployment{}, &api.DeploymentList{})
-
-	// /api/v1/services
-	s.registerResourceRoutes("/api/v1", "services", &api.Service{}, &api.ServiceList{})
-
-	// /api/v1/endpoints
-	s.registerResourceRoutes("/api/v1", "endpoints", &api.Endpoints{}, &api.EndpointsList{})
+	s.Router.Get("/admin/backup", s.handleBackup)
 
-	// /api/v1/leases
-	s.registerResourceRoutes("/api/v1", "leases", &api.Lease{}, &api.LeaseList{})
+	d := newDiscovery()
+	for _, res := range s.resources {
+		d.add(res, s.registerResourceRoutes(res))
+	}
+	s.registerDiscoveryRoutes(d)
 }
 
-func (s *Server) registerResourceRoutes(prefix, resource string, objKind interface{}, listKind interface{}) {
-	// e.g. /api/v1/pods
-	s.Router.Route(path.Join(prefix, resource), func(r chi.Router) {
-		r.Get("/", s.handleList(resource, listKind))
-		r.Post("/", s.handleCreate(resource, objKind))
+// registerResourceRoutes serves res under its prefix. Namespaced resources
+// live under prefix/namespaces/{namespace}/resource, and can additionally be
+// listed and watched across all namespaces at prefix/resource. It returns
+// what it registered, for discovery: the resource and its subresources.
+func (s *Server) registerResourceRoutes(res *resourceInfo) []api.APIResource {
+	registered := []api.APIResource{{
+		Name:         res.Resource,
+		SingularName: strings.ToLower(res.Kind),
+		Namespaced:   res.Namespaced,
+		Kind:         res.Kind,
+		Verbs:        []string{"create", "delete", "get", "list", "patch", "update", "watch"},
+		ShortNames:   res.ShortNames,
+	}}
+
+	collection := path.Join(res.prefix(), res.Resource)
+	if res.Namespaced {
+		// e.g. GET /api/v1/pods
+		s.Router.Get(collection, s.handleList(res))
+		collection = path.Join(res.prefix(), "namespaces", "{namespace}", res.Resource)
+	}
+
+	s.Router.Route(collection, func(r chi.Router) {
+		r.Get("/", s.handleList(res))
+		r.Post("/", s.handleCreate(res))
 
 		r.Route("/{name}", func(r chi.Router) {
-			r.Get("/", s.handleGet(resource, objKind))
-			r.Delete("/", s.handleDelete(resource))
-			r.Put("/", s.handleUpdate(resource, objKind))
+			r.Get("/", s.handleGet(res))
+			r.Delete("/", s.handleDelete(res))
+			r.Put("/", s.handleUpdate(res))
+			r.Patch("/", s.handlePatch(res))
+
+			for _, sub := range res.subresources() {
+				r.Method(verbMethods[sub.Verb], "/"+sub.Name, sub.Handler(s, res))
+
+				kind := sub.Kind
+				if kind == "" {
+					kind = res.Kind
+				}
+				registered = append(registered, api.APIResource{
+					Name:       res.Resource + "/" + sub.Name,
+					Namespaced: res.Namespaced,
+					Kind:       kind,
+					Verbs:      []string{sub.Verb},
+				})
+			}
 		})
 	})
+	return registered
+}
+
+// ensureDefaultNamespace creates the default namespace if it doesn't exist yet.
+func (s *Server) ensureDefaultNamespace() {
+	ns := &api.Namespace{
+		TypeMeta:   api.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
+		ObjectMeta: api.ObjectMeta{Name: api.NamespaceDefault},
+		Spec:       api.NamespaceSpec{Finalizers: []string{api.FinalizerKubernetes}},
+		Status:     api.NamespaceStatus{Phase: api.NamespaceActive},
+	}
+	key := fmt.Sprintf("/registry/namespaces/%s", ns.Name)
+	if err := s.Store.Create(context.Background(), key, ns); err != nil && err != storage.ErrAlreadyExists {
+		log.Printf("Failed to create namespace %q: %v", ns.Name, err)
+	}
 }
 
-func (s *Server) handleUpdate(resource string, _ interface{}) http.HandlerFunc {
+func (s *Server) handleUpdate(res *resourceInfo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		name := chi.URLParam(r, "name")
-		key := fmt.Sprintf("/registry/%s/%s", resource, name)
-
-		// Decode new obj
-		var obj interface{}
-		switch resource {
-		case "pods":
-			obj = &api.Pod{}
-		case "nodes":
-			obj = &api.Node{}
-		case "replicasets":
-			obj = &api.ReplicaSet{}
-		case "deployments":
-			obj = &api.Deployment{}
-		case "services":
-			obj = &api.Service{}
-		case "endpoints":
-			obj = &api.Endpoints{}
-		case "leases":
-			obj = &api.Lease{}
-		}
+		key := objectKey(r, res.Resource, name)
 
+		obj := res.New()
 		if err := json.NewDecoder(r.Body).Decode(obj); err != nil {
 			render.Render(w, r, ErrInvalidRequest(err))
 			return
 		}
 
-		if err := s.Store.Update(r.Context(), key, obj); err != nil {
-			if err == storage.ErrNotFound {
-				render.Render(w, r, ErrNotFound)
-			} else {
-				render.Render(w, r, ErrInternal(err))
+		meta, _ := getObjectMeta(obj)
+		if meta.Name == "" {
+			meta.Name = name
+		} else if meta.Name != name {
+			render.Render(w, r, ErrInvalidRequest(fmt.Errorf("the name of the object (%s) does not match the name on the URL (%s)", meta.Name, name)))
+			return
+		}
+		if err := setNamespace(r, meta); err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+		updated, err := s.guaranteedUpdate(r.Context(), key, res, meta.ResourceVersion, func(existing interface{}) (interface{}, error) {
+			if err := admitUpdate(res, obj, existing); err != nil {
+				return nil, err
 			}
+			recordUpdate(obj, existing, fieldManager(r))
+			return obj, nil
+		})
+		if err != nil {
+			renderUpdateError(w, r, res.groupResource(), err)
 			return
 		}
 
-		render.JSON(w, r, obj)
+		render.JSON(w, r, updated)
 	}
 }
 
-func (s *Server) handleList(resource string, _ interface{}) http.HandlerFunc {
+func (s *Server) handleList(res *resourceInfo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Query().Get("watch") == "true" {
-			s.handleWatch(resource, w, r)
+			s.handleWatch(res, collectionPrefix(r, res.Resource), "", w, r)
 			return
 		}
 
-		ctx := r.Context()
-		keyPrefix := fmt.Sprintf("/registry/%s", resource)
+		opts, err := listOptions(r)
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
 
-		var list interface{}
-		switch resource {
-		case "pods":
-			var pods []api.Pod
-			if err := s.Store.List(ctx, keyPrefix, &pods); err != nil {
-				render.Render(w, r, ErrInternal(err))
-				return
-			}
-			list = &api.PodList{Items: pods}
-		case "nodes":
-			var nodes []api.Node
-			if err := s.Store.List(ctx, keyPrefix, &nodes); err != nil {
-				render.Render(w, r, ErrInternal(err))
-				return
-			}
-			list = &api.NodeList{Items: nodes}
-		case "replicasets":
-			var rss []api.ReplicaSet
-			if err := s.Store.List(ctx, keyPrefix, &rss); err != nil {
-				render.Render(w, r, ErrInternal(err))
-				return
-			}
-			list = &api.ReplicaSetList{Items: rss}
-		case "deployments":
-			var deps []api.Deployment
-			if err := s.Store.List(ctx, keyPrefix, &deps); err != nil {
-				render.Render(w, r, ErrInternal(err))
-				return
-			}
-			list = &api.DeploymentList{Items: deps}
-		case "services":
-			var svcs []api.Service
-			if err := s.Store.List(ctx, keyPrefix, &svcs); err != nil {
-				render.Render(w, r, ErrInternal(err))
-				return
-			}
-			list = &api.ServiceList{Items: svcs}
-		case "endpoints":
-			var eps []api.Endpoints
-			if err := s.Store.List(ctx, keyPrefix, &eps); err != nil {
-				render.Render(w, r, ErrInternal(err))
-				return
-			}
-			list = &api.EndpointsList{Items: eps}
-		case "leases":
-			var leases []api.Lease
-			if err := s.Store.List(ctx, keyPrefix, &leases); err != nil {
-				render.Render(w, r, ErrInternal(err))
-				return
-			}
-			list = &api.LeaseList{Items: leases}
-		default:
-			render.Render(w, r, ErrNotFound)
+		list, items := res.newList()
+		result, err := s.Store.List(r.Context(), collectionPrefix(r, res.Resource), opts, items)
+		if err != nil {
+			render.Render(w, r, listError(err))
 			return
 		}
+		*list.GetListMeta() = api.ListMeta{ResourceVersion: result.ResourceVersion, Continue: result.Continue}
 
 		render.JSON(w, r, list)
 	}
 }
 
-func (s *Server) handleWatch(resource string, w http.ResponseWriter, r *http.Request) {
-	keyPrefix := fmt.Sprintf("/registry/%s", resource)
-	watcher, err := s.Store.Watch(r.Context(), keyPrefix)
+// handleWatch streams the changes under keyPrefix, which is either a
+// collection of res or, to watch the single object name, its key.
+func (s *Server) handleWatch(res *resourceInfo, keyPrefix, name string, w http.ResponseWriter, r *http.Request) {
+	// Resume from the resourceVersion of a previous list or event, if given.
+	opts, err := listOptions(r)
+	if err != nil {
+		render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+
+	watcher, err := s.Store.Watch(r.Context(), keyPrefix, opts)
 	if err != nil {
-		render.Render(w, r, ErrInternal(err))
+		render.Render(w, r, listError(err))
 		return
 	}
 	defer watcher.Stop()
@@ -247,6 +255,10 @@ func (s *Server) handleWatch(resource string, w http.ResponseWriter, r *http.Req
 			if !ok {
 				return
 			}
+			if status, ok := event.Object.(*api.Status); ok {
+				// Say what the watch that ended was of, as for any other error.
+				status.Details = &api.StatusDetails{Name: name, Group: res.Group, Kind: res.Resource}
+			}
 			if err := encoder.Encode(event); err != nil {
 				return
 			}
@@ -255,80 +267,79 @@ func (s *Server) handleWatch(resource string, w http.ResponseWriter, r *http.Req
 	}
 }
 
-func (s *Server) handleCreate(resource string, _ interface{}) http.HandlerFunc {
+func (s *Server) handleCreate(res *resourceInfo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Decode
-		var obj interface{}
-		switch resource {
-		case "pods":
-			obj = &api.Pod{}
-		case "nodes":
-			obj = &api.Node{}
-		case "replicasets":
-			obj = &api.ReplicaSet{}
-		case "deployments":
-			obj = &api.Deployment{}
-		case "services":
-			obj = &api.Service{}
-		case "endpoints":
-			obj = &api.Endpoints{}
-		case "leases":
-			obj = &api.Lease{}
-		}
-
+		obj := res.New()
 		if err := json.NewDecoder(r.Body).Decode(obj); err != nil {
 			render.Render(w, r, ErrInvalidRequest(err))
 			return
 		}
 
-		// Extract Name (simple reflection or type assertion)
-		meta, ok := getObjectMeta(obj)
-		if !ok || meta.Name == "" {
-			render.Render(w, r, ErrInvalidRequest(fmt.Errorf("metadata.name is required")))
+		if errResp := s.admitCreate(r, res, obj); errResp != nil {
+			render.Render(w, r, errResp)
 			return
 		}
+		recordCreate(obj, fieldManager(r))
 
-		key := fmt.Sprintf("/registry/%s/%s", resource, meta.Name)
+		meta, _ := getObjectMeta(obj)
+		key := objectKey(r, res.Resource, meta.Name)
 		if err := s.Store.Create(r.Context(), key, obj); err != nil {
-			if err == storage.ErrConflict {
-				render.Render(w, r, ErrConflict(err))
+			if err == storage.ErrAlreadyExists {
+				render.Render(w, r, ErrAlreadyExists(res.groupResource(), meta.Name))
 			} else {
 				render.Render(w, r, ErrInternal(err))
 			}
 			return
 		}
+		if errResp := s.confirmNamespace(r, res, key); errResp != nil {
+			render.Render(w, r, errResp)
+			return
+		}
 
 		render.Status(r, http.StatusCreated)
 		render.JSON(w, r, obj)
 	}
 }
 
-func (s *Server) handleGet(resource string, _ interface{}) http.HandlerFunc {
+// admitCreate sets the defaults of obj, an object about to be created, checks
+// it and sets the fields the server owns. It returns nil if obj may be
+// created.
+func (s *Server) admitCreate(r *http.Request, res *resourceInfo, obj interface{}) render.Renderer {
+	meta, _ := getObjectMeta(obj)
+	if err := setNamespace(r, meta); err != nil {
+		return ErrInvalidRequest(err)
+	}
+	if errs := defaultAndValidate(res, obj); len(errs) > 0 {
+		return ErrInvalid(res.Group, obj, errs)
+	}
+	if res.PrepareForCreate != nil {
+		res.PrepareForCreate(obj)
+	}
+	if res.Namespaced {
+		if errResp := s.admitNamespace(r); errResp != nil {
+			return errResp
+		}
+	}
+
+	meta.Generation = 1
+	return nil
+}
+
+func (s *Server) handleGet(res *resourceInfo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		name := chi.URLParam(r, "name")
-		key := fmt.Sprintf("/registry/%s/%s", resource, name)
-
-		var obj interface{}
-		switch resource {
-		case "pods":
-			obj = &api.Pod{}
-		case "nodes":
-			obj = &api.Node{}
-		case "replicasets":
-			obj = &api.ReplicaSet{}
-		case "deployments":
-			obj = &api.Deployment{}
-		case "services":
-			obj = &api.Service{}
-		case "endpoints":
-			obj = &api.Endpoints{}
-		case "leases":
-			obj = &api.Lease{}
+		key := objectKey(r, res.Resource, name)
+
+		if r.URL.Query().Get("watch") == "true" {
+			s.handleWatch(res, key, name, w, r)
+			return
 		}
 
+		obj := res.New()
 		if err := s.Store.Get(r.Context(), key, obj); err != nil {
 			if err == storage.ErrNotFound {
-				render.Render(w, r, ErrNotFound)
+				render.Render(w, r, ErrNotFound(res.groupResource(), name))
 			} else {
 				render.Render(w, r, ErrInternal(err))
 			}
@@ -339,14 +350,19 @@ func (s *Server) handleGet(resource string, _ interface{}) http.HandlerFunc {
 	}
 }
 
-func (s *Server) handleDelete(resource string) http.HandlerFunc {
+func (s *Server) handleDelete(res *resourceInfo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if res.Delete != nil {
+			res.Delete(s, w, r)
+			return
+		}
+
 		name := chi.URLParam(r, "name")
-		key := fmt.Sprintf("/registry/%s/%s", resource, name)
+		key := objectKey(r, res.Resource, name)
 
 		if err := s.Store.Delete(r.Context(), key); err != nil {
 			if err == storage.ErrNotFound {
-				render.Render(w, r, ErrNotFound)
+				render.Render(w, r, ErrNotFound(res.groupResource(), name))
 			} else {
 				render.Render(w, r, ErrInternal(err))
 			}
@@ -360,70 +376,78 @@ func (s *Server) handleDelete(resource string) http.HandlerFunc {
 
 // Helpers
 
-func getObjectMeta(obj interface{}) (*api.ObjectMeta, bool) {
-	// Quick hack without full reflection library
-	switch v := obj.(type) {
-	case *api.Pod:
-		return &v.ObjectMeta, true
-	case *api.Node:
-		return &v.ObjectMeta, true
-	case *api.ReplicaSet:
-		return &v.ObjectMeta, true
-	case *api.Deployment:
-		return &v.ObjectMeta, true
-	case *api.Service:
-		return &v.ObjectMeta, true
-	case *api.Endpoints:
-		return &v.ObjectMeta, true
-	case *api.Lease:
-		return &v.ObjectMeta, true
+// objectKey returns the storage key of a named object. Namespaced objects are
+// stored under /registry/<resource>/<namespace>/<name>.
+func objectKey(r *http.Request, resource, name string) string {
+	if ns := chi.URLParam(r, "namespace"); ns != "" {
+		return fmt.Sprintf("/registry/%s/%s/%s", resource, ns, name)
 	}
-	return nil, false
+	return fmt.Sprintf("/registry/%s/%s", resource, name)
 }
 
-// Errors
-
-type ErrResponse struct {
-	Err            error `json:"-"` // low-level runtime error
-	HTTPStatusCode int   `json:"-"` // http response status code
-
-	StatusText string `json:"status"`          // user-level status message
-	AppCode    int64  `json:"code,omitempty"`  // application-specific error code
-	ErrorText  string `json:"error,omitempty"` // application-level error message, for debugging
+// collectionPrefix returns the key prefix covered by a list or watch: a single
+// namespace if the path has one, otherwise every object of the resource.
+func collectionPrefix(r *http.Request, resource string) string {
+	if ns := chi.URLParam(r, "namespace"); ns != "" {
+		return fmt.Sprintf("/registry/%s/%s/", resource, ns)
+	}
+	return fmt.Sprintf("/registry/%s/", resource)
 }
 
-func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
-	render.Status(r, e.HTTPStatusCode)
-	return nil
-}
+// listOptions reads the resourceVersion, labelSelector, fieldSelector, limit
+// and continue query parameters of a list or watch request.
+func listOptions(r *http.Request) (storage.ListOptions, error) {
+	q := r.URL.Query()
+	opts := storage.ListOptions{ResourceVersion: q.Get("resourceVersion")}
+	if _, err := storage.ParseResourceVersion(opts.ResourceVersion); err != nil {
+		return opts, err
+	}
 
-func ErrInvalidRequest(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 400,
-		StatusText:     "Invalid request",
-		ErrorText:      err.Error(),
+	var err error
+	if opts.LabelSelector, err = labels.Parse(q.Get("labelSelector")); err != nil {
+		return opts, fmt.Errorf("invalid labelSelector: %v", err)
+	}
+	if opts.FieldSelector, err = fields.Parse(q.Get("fieldSelector")); err != nil {
+		return opts, fmt.Errorf("invalid fieldSelector: %v", err)
 	}
+	if v := q.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.ParseInt(v, 10, 64); err != nil || opts.Limit < 0 {
+			return opts, fmt.Errorf("invalid limit: %q", v)
+		}
+	}
+	opts.Continue = q.Get("continue")
+	return opts, nil
 }
 
-func ErrConflict(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 409,
-		StatusText:     "Resource already exists",
-		ErrorText:      err.Error(),
+// listError maps a storage list or watch error to a response. A bad continue
+// token, or a resourceVersion the store hasn't reached, is the client's fault.
+func listError(err error) render.Renderer {
+	if errors.Is(err, storage.ErrInvalidContinue) || errors.Is(err, storage.ErrTooLargeResourceVersion) {
+		return ErrInvalidRequest(err)
 	}
+	return ErrInternal(err)
 }
 
-var ErrNotFound = &ErrResponse{HTTPStatusCode: 404, StatusText: "Resource not found"}
+// setNamespace defaults the namespace of an object to the one in the request
+// path and rejects a conflicting one. Cluster-scoped objects have no namespace.
+func setNamespace(r *http.Request, meta *api.ObjectMeta) error {
+	ns := chi.URLParam(r, "namespace")
+	if ns == "" || meta.Namespace == "" {
+		meta.Namespace = ns
+		return nil
+	}
+	if meta.Namespace != ns {
+		return fmt.Errorf("the namespace of the object (%s) does not match the namespace on the request (%s)", meta.Namespace, ns)
+	}
+	return nil
+}
 
-func ErrInternal(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 500,
-		StatusText:     "Internal server error",
-		ErrorText:      err.Error(),
+func getObjectMeta(obj interface{}) (*api.ObjectMeta, bool) {
+	o, ok := obj.(api.Object)
+	if !ok {
+		return nil, false
 	}
+	return o.GetObjectMeta(), true
 }
 
 func (s *Server) prometheusMiddleware(next http.Handler) http.Handler {
@@ -442,12 +466,13 @@ func (s *Server) prometheusMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// ServeTLS starts the server with mTLS enabled
-func (s *Server) ServeTLS(addr, certFile, keyFile, caFile string) error {
+// TLSServer returns an http.Server for s on addr with mTLS enabled: clients
+// must present a certificate signed by the CA in caFile.
+func (s *Server) TLSServer(addr, caFile string) (*http.Server, error) {
 	// Load CA
 	caCert, err := ioutil.ReadFile(caFile)
 	if err != nil {
-		return fmt.Errorf("read ca cert: %v", err)
+		return nil, fmt.Errorf("read ca cert: %v", err)
 	}
 	caCertPool := x509.NewCertPool()
 	caCertPool.AppendCertsFromPEM(caCert)
@@ -458,13 +483,11 @@ func (s *Server) ServeTLS(addr, certFile, keyFile, caFile string) error {
 		ClientAuth: tls.RequireAndVerifyClientCert,
 	}
 
-	server := &http.Server{
+	return &http.Server{
 		Addr:      addr,
 		Handler:   s.Router,
 		TLSConfig: tlsConfig,
-	}
-
-	return server.ListenAndServeTLS(certFile, keyFile)
+	}, nil
 }
 
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
@@ -481,8 +504,3 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
-
-
-
-
-
@@ -0,0 +1,73 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/storage"
+)
+
+// newTestServer returns a server on an in-memory store, with the default
+// namespace created.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	store, err := storage.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(store)
+}
+
+// do sends a request with body, encoded as JSON unless it is a string, to s
+// and returns the response.
+func do(t *testing.T, s *Server, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var data string
+	if b, ok := body.(string); ok {
+		data = b
+	} else if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = string(encoded)
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(data))
+	req.Header.Set("User-Agent", "test")
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	return w
+}
+
+// decode decodes the body of w into out.
+func decode(t *testing.T, w *httptest.ResponseRecorder, out interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+}
+
+// expectCode fails the test unless w has the status code want.
+func expectCode(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("got %d %s, want %d", w.Code, w.Body, want)
+	}
+}
+
+// A watch from a resourceVersion the store hasn't reached would miss every
+// change up to it, so it is refused rather than started from now.
+func TestWatchTooLargeResourceVersion(t *testing.T) {
+	s := newTestServer(t)
+	w := do(t, s, "GET", "/api/v1/namespaces/default/pods?watch=true&resourceVersion=1000", nil)
+	expectCode(t, w, http.StatusBadRequest)
+	var status api.Status
+	decode(t, w, &status)
+	if status.Reason != api.StatusReasonBadRequest || !strings.Contains(status.Message, "too large resource version") {
+		t.Errorf("got %+v, want a bad request for a too large resource version", status)
+	}
+}
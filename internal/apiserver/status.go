@@ -0,0 +1,57 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Status subresource: components that report on an object, like the kubelet
+// for pods or the controllers for their workloads, write its status through
+// PUT .../{name}/status, which takes nothing but .status from the request.
+// The main PUT keeps the stored status in turn, so a status write and a
+// concurrent spec change can't undo each other. Status writes are validated
+// and recorded in the managed fields like any other write, under the status
+// subresource.
+
+func (s *Server) handleUpdateStatus(res *resourceInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		key := objectKey(r, res.Resource, name)
+
+		obj := res.New()
+		if err := json.NewDecoder(r.Body).Decode(obj); err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+		meta, _ := getObjectMeta(obj)
+		if meta.Name != "" && meta.Name != name {
+			render.Render(w, r, ErrInvalidRequest(fmt.Errorf("the name of the object (%s) does not match the name on the URL (%s)", meta.Name, name)))
+			return
+		}
+
+		updated, err := s.guaranteedUpdate(r.Context(), key, res, meta.ResourceVersion, func(existing interface{}) (interface{}, error) {
+			// obj only contributes its status, so the owners of the status
+			// fields are worked out on it and moved to existing with it.
+			recordStatusUpdate(obj, existing, fieldManager(r))
+			res.CopyStatus(existing, obj)
+			existingMeta, _ := getObjectMeta(existing)
+			existingMeta.ManagedFields = meta.ManagedFields
+			if res.ValidateStatus != nil {
+				if errs := res.ValidateStatus(existing); len(errs) > 0 {
+					return nil, errInvalidObject{existing, errs}
+				}
+			}
+			return existing, nil
+		})
+		if err != nil {
+			renderUpdateError(w, r, res.groupResource(), err)
+			return
+		}
+
+		render.JSON(w, r, updated)
+	}
+}
@@ -0,0 +1,94 @@
+package apiserver
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+func TestHandleUpdateStatus(t *testing.T) {
+	const pods = "/api/v1/namespaces/default/pods"
+	tests := []struct {
+		name   string
+		update func(pod *api.Pod)
+		want   int
+		causes []string // the invalid fields, for 422
+		phase  string   // stored after the write
+		owners map[string][]string
+	}{
+		{
+			name: "valid",
+			update: func(pod *api.Pod) {
+				pod.Status.Phase = "Running"
+				pod.Status.PodIP = "10.244.1.5"
+			},
+			want:  http.StatusOK,
+			phase: "Running",
+			owners: map[string][]string{
+				"test/Update":           {"/metadata/labels/app", "/spec/containers", "/spec/restartPolicy"},
+				"kubelet/Update/status": {"/status/phase", "/status/podIP"},
+			},
+		},
+		{
+			name:   "invalid",
+			update: func(pod *api.Pod) { pod.Status.Phase = "Done"; pod.Status.PodIP = "10.244.1" },
+			want:   http.StatusUnprocessableEntity,
+			causes: []string{"status.phase", "status.podIP"},
+			owners: map[string][]string{
+				"test/Update": {"/metadata/labels/app", "/spec/containers", "/spec/restartPolicy"},
+			},
+		},
+		{
+			name: "spec is ignored",
+			update: func(pod *api.Pod) {
+				pod.Spec.Containers[0].Image = "httpd"
+				pod.Status.Phase = "Running"
+			},
+			want:  http.StatusOK,
+			phase: "Running",
+			owners: map[string][]string{
+				"test/Update":           {"/metadata/labels/app", "/spec/containers", "/spec/restartPolicy"},
+				"kubelet/Update/status": {"/status/phase"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			pod := api.Pod{
+				ObjectMeta: api.ObjectMeta{Name: "web", Labels: map[string]string{"app": "web"}},
+				Spec:       api.PodSpec{Containers: []api.Container{{Name: "web", Image: "nginx"}}},
+			}
+			expectCode(t, do(t, s, "POST", pods, pod), http.StatusCreated)
+
+			tt.update(&pod)
+			w := do(t, s, "PUT", pods+"/web/status?fieldManager=kubelet", pod)
+			expectCode(t, w, tt.want)
+			if tt.causes != nil {
+				var status api.Status
+				decode(t, w, &status)
+				var fields []string
+				for _, c := range status.Details.Causes {
+					fields = append(fields, c.Field)
+				}
+				if status.Reason != api.StatusReasonInvalid || !reflect.DeepEqual(fields, tt.causes) {
+					t.Errorf("got %s with causes %v, want Invalid with %v", status.Reason, fields, tt.causes)
+				}
+			}
+
+			var got api.Pod
+			decode(t, do(t, s, "GET", pods+"/web", nil), &got)
+			if got.Status.Phase != tt.phase {
+				t.Errorf("stored phase %q, want %q", got.Status.Phase, tt.phase)
+			}
+			if img := got.Spec.Containers[0].Image; img != "nginx" {
+				t.Errorf("status write changed the image to %q", img)
+			}
+			if o := owners(got.ManagedFields); !reflect.DeepEqual(o, tt.owners) {
+				t.Errorf("got owners %v, want %v", o, tt.owners)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/abhigod/k8s-lite/internal/storage"
+	"github.com/abhigod/k8s-lite/internal/validation"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// guaranteedUpdate reads the object at key, lets tryUpdate derive the object
+// to store from it, and writes that back on the condition that the stored
+// object hasn't changed in between. With a resourceVersion, the write is
+// conditional on that version instead and fails with storage.ErrConflict if
+// it is stale; without one, a concurrent change just makes it start over.
+func (s *Server) guaranteedUpdate(ctx context.Context, key string, res *resourceInfo, resourceVersion string, tryUpdate func(existing interface{}) (interface{}, error)) (interface{}, error) {
+	for {
+		existing := res.New()
+		if err := s.Store.Get(ctx, key, existing); err != nil {
+			return nil, err
+		}
+		existingMeta, _ := getObjectMeta(existing)
+
+		obj, err := tryUpdate(existing)
+		if err != nil {
+			return nil, err
+		}
+		meta, _ := getObjectMeta(obj)
+		if resourceVersion != "" {
+			meta.ResourceVersion = resourceVersion
+		} else {
+			meta.ResourceVersion = existingMeta.ResourceVersion
+		}
+
+		err = s.Store.Update(ctx, key, obj)
+		if err == storage.ErrConflict && resourceVersion == "" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+}
+
+// errInvalidUpdate is returned by the tryUpdate function of guaranteedUpdate
+// to reject the update as a bad request.
+type errInvalidUpdate struct{ error }
+
+// errInvalidObject rejects an object that failed validation.
+type errInvalidObject struct {
+	obj  interface{}
+	errs validation.ErrorList
+}
+
+func (e errInvalidObject) Error() string { return e.errs.Error() }
+
+// defaultAndValidate sets the defaults of obj, an object of res, and
+// returns its invalid fields.
+func defaultAndValidate(res *resourceInfo, obj interface{}) validation.ErrorList {
+	if res.Default != nil {
+		res.Default(obj)
+	}
+	if res.Validate == nil {
+		return nil
+	}
+	return res.Validate(obj)
+}
+
+// admitUpdate checks obj, the replacement for the stored object existing of
+// res, and carries over what a regular update can't change.
+func admitUpdate(res *resourceInfo, obj, existing interface{}) error {
+	if errs := defaultAndValidate(res, obj); len(errs) > 0 {
+		return errInvalidObject{obj, errs}
+	}
+	if res.PrepareForUpdate != nil {
+		if err := res.PrepareForUpdate(obj, existing); err != nil {
+			return errInvalidUpdate{err}
+		}
+	}
+	// Status is written through the status subresource.
+	if res.CopyStatus != nil {
+		res.CopyStatus(obj, existing)
+	}
+	meta, _ := getObjectMeta(obj)
+	meta.Generation = nextGeneration(existing, obj)
+	return nil
+}
+
+// renderUpdateError responds with the error guaranteedUpdate returned for
+// the object of gr named in the request.
+func renderUpdateError(w http.ResponseWriter, r *http.Request, gr groupResource, err error) {
+	name := chi.URLParam(r, "name")
+	if invalid, ok := err.(errInvalidUpdate); ok {
+		render.Render(w, r, ErrInvalidRequest(invalid.error))
+	} else if invalid, ok := err.(errInvalidObject); ok {
+		render.Render(w, r, ErrInvalid(gr.Group, invalid.obj, invalid.errs))
+	} else if err == storage.ErrNotFound {
+		render.Render(w, r, ErrNotFound(gr, name))
+	} else if err == storage.ErrConflict {
+		render.Render(w, r, ErrConflict(gr, name, errModified))
+	} else {
+		render.Render(w, r, ErrInternal(err))
+	}
+}
+
+// nextGeneration returns the generation obj gets when it replaces existing:
+// one more than before if the spec changed, the same otherwise.
+func nextGeneration(existing, obj interface{}) int64 {
+	existingMeta, _ := getObjectMeta(existing)
+	if bytes.Equal(specOf(existing), specOf(obj)) {
+		return existingMeta.Generation
+	}
+	return existingMeta.Generation + 1
+}
+
+// specOf returns the encoded spec of obj, or nil if it has none.
+func specOf(obj interface{}) json.RawMessage {
+	data, _ := json.Marshal(obj)
+	var parts struct {
+		Spec json.RawMessage `json:"spec"`
+	}
+	json.Unmarshal(data, &parts)
+	return parts.Spec
+}
@@ -0,0 +1,155 @@
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/controller/deployment"
+	"github.com/abhigod/k8s-lite/internal/controller/replicaset"
+	"github.com/abhigod/k8s-lite/internal/informer"
+)
+
+// metadata.generation counts the changes to the spec: it starts at 1 and
+// moves with every write that changes the spec, whichever way it is made.
+func TestGeneration(t *testing.T) {
+	const deployments = "/apis/apps/v1/namespaces/default/deployments"
+	tests := []struct {
+		name       string
+		update     func(d *api.Deployment)
+		patch      string // a merge patch, or an apply configuration if apply
+		apply      bool
+		status     bool // PUT update to the status subresource
+		generation int64
+	}{
+		{name: "create", generation: 1},
+		{
+			name:       "update the spec",
+			update:     func(d *api.Deployment) { *d.Spec.Replicas = 3 },
+			generation: 2,
+		},
+		{
+			name:       "update the labels",
+			update:     func(d *api.Deployment) { d.Labels = map[string]string{"tier": "web"} },
+			generation: 1,
+		},
+		{
+			name:       "update the status through the object",
+			update:     func(d *api.Deployment) { d.Status.Replicas = 3 },
+			generation: 1,
+		},
+		{
+			name:       "update the status",
+			update:     func(d *api.Deployment) { d.Status.Replicas = 3; d.Status.ObservedGeneration = 1 },
+			status:     true,
+			generation: 1,
+		},
+		{
+			name:       "patch the spec",
+			patch:      `{"spec": {"template": {"spec": {"containers": [{"name": "web", "image": "httpd"}]}}}}`,
+			generation: 2,
+		},
+		{
+			name:       "patch the annotations",
+			patch:      `{"metadata": {"annotations": {"owner": "team"}}}`,
+			generation: 1,
+		},
+		{
+			name:       "apply the spec",
+			patch:      `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web"}, "spec": {"replicas": 5}}`,
+			apply:      true,
+			generation: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			var d api.Deployment
+			w := do(t, s, "POST", deployments, testDeployment("web", 1))
+			expectCode(t, w, http.StatusCreated)
+			decode(t, w, &d)
+
+			switch {
+			case tt.update != nil && tt.status:
+				tt.update(&d)
+				expectCode(t, do(t, s, "PUT", deployments+"/web/status", d), http.StatusOK)
+			case tt.update != nil:
+				tt.update(&d)
+				expectCode(t, do(t, s, "PUT", deployments+"/web", d), http.StatusOK)
+			case tt.apply:
+				expectCode(t, doPatch(t, s, deployments+"/web?fieldManager=test&force=true", applyPatchType, tt.patch), http.StatusOK)
+			case tt.patch != "":
+				expectCode(t, doPatch(t, s, deployments+"/web", mergePatchType, tt.patch), http.StatusOK)
+			}
+
+			var got api.Deployment
+			decode(t, do(t, s, "GET", deployments+"/web", nil), &got)
+			if got.Generation != tt.generation {
+				t.Errorf("got generation %d, want %d", got.Generation, tt.generation)
+			}
+		})
+	}
+}
+
+// The deployment and replicaset controllers report in status.observedGeneration
+// the generation of the spec they last acted on.
+func TestControllersObserveGeneration(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewServer(s.Router)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &client.Client{BaseURL: srv.URL, HTTP: srv.Client()}
+
+	informers := informer.NewFactory(c, 0)
+	deployments := deployment.New(c, informers)
+	replicasets := replicaset.New(c, informers)
+	informers.Start(ctx)
+	if !informers.WaitForCacheSync(ctx) {
+		t.Fatal("caches did not sync")
+	}
+	go deployments.Run(ctx, 1)
+	go replicasets.Run(ctx, 1)
+
+	const path = "/apis/apps/v1/namespaces/default"
+	// observed waits until the deployment has observed the given generation
+	// of its spec, and its replicaset, scaled to replicas, the latest
+	// generation of its own. It returns the replicaset's generation.
+	observed := func(generation int64, replicas int32) int64 {
+		t.Helper()
+		var d api.Deployment
+		var rss api.ReplicaSetList
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			decode(t, do(t, s, "GET", path+"/deployments/web", nil), &d)
+			decode(t, do(t, s, "GET", path+"/replicasets", nil), &rss)
+			if d.Generation == generation && d.Status.ObservedGeneration == generation && len(rss.Items) == 1 {
+				rs := rss.Items[0]
+				if *rs.Spec.Replicas == replicas && rs.Status.ObservedGeneration == rs.Generation {
+					return rs.Generation
+				}
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("got deployment generation %d observed %d, replicasets %+v; want generation %d observed, and one replicaset of %d replicas",
+					d.Generation, d.Status.ObservedGeneration, rss.Items, generation, replicas)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	expectCode(t, do(t, s, "POST", path+"/deployments", testDeployment("web", 1)), http.StatusCreated)
+	before := observed(1, 1)
+
+	expectCode(t, doPatch(t, s, path+"/deployments/web", mergePatchType, `{"spec": {"replicas": 3}}`), http.StatusOK)
+	if after := observed(2, 3); after != before+1 {
+		t.Errorf("scaling the replicaset moved its generation from %d to %d, want %d", before, after, before+1)
+	}
+
+	// Labels aren't part of the spec, so there is nothing new to observe.
+	expectCode(t, doPatch(t, s, path+"/deployments/web", mergePatchType, `{"metadata": {"labels": {"tier": "web"}}}`), http.StatusOK)
+	observed(2, 3)
+}
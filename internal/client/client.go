@@ -7,9 +7,14 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/abhigod/k8s-lite/internal/api"
 )
@@ -45,41 +50,92 @@ func New(baseURL string, tlsCert, tlsKey, tlsCA string) *Client {
 		}
 	}
 
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient.Transport = &userAgentTransport{base: base, userAgent: userAgent}
+
 	return &Client{
 		BaseURL: baseURL,
 		HTTP:    httpClient,
 	}
 }
 
-// Pods
+// userAgent names the running component, e.g. "controller-manager". The
+// apiserver records it as the manager of the fields the component writes.
+var userAgent = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
 
-func (c *Client) ListPods(ctx context.Context, nodeName string) ([]api.Pod, error) {
-	url := fmt.Sprintf("%s/api/v1/pods", c.BaseURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+// userAgentTransport sets the User-Agent of every request.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
 	}
+	return t.base.RoundTrip(req)
+}
 
-	resp, err := c.HTTP.Do(req)
-	if err != nil {
-		return nil, err
+// resourceURL returns the URL of a namespaced resource collection under group
+// (e.g. /api/v1), or of the named object in it. Objects without a namespace
+// belong to the default namespace.
+func (c *Client) resourceURL(group, resource, namespace, name string) string {
+	if namespace == "" {
+		namespace = api.NamespaceDefault
 	}
-	defer resp.Body.Close()
+	url := fmt.Sprintf("%s%s/namespaces/%s/%s", c.BaseURL, group, namespace, resource)
+	if name != "" {
+		url += "/" + name
+	}
+	return url
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api request failed: %s", resp.Status)
+// listURL returns the URL for listing a namespaced resource in namespace, or in
+// every namespace for api.NamespaceAll.
+func (c *Client) listURL(group, resource, namespace string) string {
+	if namespace == api.NamespaceAll {
+		return fmt.Sprintf("%s%s/%s", c.BaseURL, group, resource)
 	}
+	return c.resourceURL(group, resource, namespace, "")
+}
 
-	var list api.PodList
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
-		return nil, err
+// ListOptions narrows down lists and watches to matching objects.
+type ListOptions struct {
+	// LabelSelector uses the label selector syntax, e.g. "app=web,tier in (frontend,backend)".
+	LabelSelector string
+	// FieldSelector selects on dotted field paths, e.g. "spec.nodeName=node1".
+	FieldSelector string
+	// ResourceVersion is where a watch starts; lists ignore it.
+	ResourceVersion string
+	// Limit is the page size of lists. Lists still return every object, but
+	// fetch them Limit at a time; zero fetches everything in one request.
+	Limit int64
+}
+
+func (o ListOptions) query() neturl.Values {
+	q := neturl.Values{}
+	if o.LabelSelector != "" {
+		q.Set("labelSelector", o.LabelSelector)
 	}
+	if o.FieldSelector != "" {
+		q.Set("fieldSelector", o.FieldSelector)
+	}
+	return q
+}
+
+// Pods
 
-	return list.Items, nil
+func (c *Client) ListPods(ctx context.Context, namespace string, opts ListOptions) ([]api.Pod, error) {
+	items, _, err := c.PodListWatch(namespace, opts).List(ctx)
+	return items, err
 }
 
 func (c *Client) UpdatePod(ctx context.Context, pod *api.Pod) error {
-	url := fmt.Sprintf("%s/api/v1/pods/%s", c.BaseURL, pod.Name)
+	url := c.resourceURL("/api/v1", "pods", pod.Namespace, pod.Name)
 	data, err := json.Marshal(pod)
 	if err != nil {
 		return err
@@ -97,17 +153,51 @@ func (c *Client) UpdatePod(ctx context.Context, pod *api.Pod) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update pod: %s", resp.Status)
+		return fmt.Errorf("failed to update pod: %w", statusError(resp))
 	}
-	return nil
+	// Pick up the new resourceVersion so the caller can update again.
+	return json.NewDecoder(resp.Body).Decode(pod)
 }
 
+// UpdatePodStatus writes the status of pod, leaving its spec and metadata as
+// stored.
 func (c *Client) UpdatePodStatus(ctx context.Context, pod *api.Pod) error {
-	return c.UpdatePod(ctx, pod)
+	return c.updateStatus(ctx, c.resourceURL("/api/v1", "pods", pod.Namespace, pod.Name), "pod", pod)
+}
+
+// BindPod assigns the pod to nodeName. It fails with a conflict if the pod
+// is already bound.
+func (c *Client) BindPod(ctx context.Context, namespace, name, nodeName string) error {
+	binding := api.Binding{
+		TypeMeta:   api.TypeMeta{Kind: "Binding", APIVersion: "v1"},
+		ObjectMeta: api.ObjectMeta{Name: name, Namespace: namespace},
+		Target:     api.ObjectReference{Kind: "Node", Name: nodeName},
+	}
+	data, err := json.Marshal(binding)
+	if err != nil {
+		return err
+	}
+
+	url := c.resourceURL("/api/v1", "pods", namespace, name) + "/binding"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to bind pod: %w", statusError(resp))
+	}
+	return nil
 }
 
 func (c *Client) CreatePod(ctx context.Context, pod *api.Pod) error {
-	url := fmt.Sprintf("%s/api/v1/pods", c.BaseURL)
+	url := c.resourceURL("/api/v1", "pods", pod.Namespace, "")
 	data, err := json.Marshal(pod)
 	if err != nil {
 		return err
@@ -125,13 +215,25 @@ func (c *Client) CreatePod(ctx context.Context, pod *api.Pod) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to create pod: %s", resp.Status)
+		return fmt.Errorf("failed to create pod: %w", statusError(resp))
 	}
 	return nil
 }
 
-func (c *Client) DeletePod(ctx context.Context, name string) error {
-	url := fmt.Sprintf("%s/api/v1/pods/%s", c.BaseURL, name)
+func (c *Client) DeletePod(ctx context.Context, namespace, name string) error {
+	return c.deleteObject(ctx, c.resourceURL("/api/v1", "pods", namespace, name), "pod")
+}
+
+// deleteObject deletes the object at url. Objects that are already gone are
+// not an error.
+func (c *Client) deleteObject(ctx context.Context, url, kind string) error {
+	if err := c.delete(ctx, url, kind); err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) delete(ctx context.Context, url, kind string) error {
 	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
 		return err
@@ -144,25 +246,20 @@ func (c *Client) DeletePod(ctx context.Context, name string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		if resp.StatusCode == http.StatusNotFound {
-			return nil
-		}
-		return fmt.Errorf("failed to delete pod: %s", resp.Status)
+		return fmt.Errorf("failed to delete %s: %w", kind, statusError(resp))
 	}
 	return nil
 }
 
-// Nodes
-
-func (c *Client) RegisterNode(ctx context.Context, node *api.Node) error {
-	url := fmt.Sprintf("%s/api/v1/nodes", c.BaseURL)
-
-	data, err := json.Marshal(node)
+// updateStatus PUTs obj to the status subresource of the object at url and
+// decodes the stored object back into it.
+func (c *Client) updateStatus(ctx context.Context, url, kind string, obj interface{}) error {
+	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "PUT", url+"/status", bytes.NewBuffer(data))
 	if err != nil {
 		return err
 	}
@@ -173,71 +270,112 @@ func (c *Client) RegisterNode(ctx context.Context, node *api.Node) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusConflict {
-		return nil
-	}
-
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to register node: %s", resp.Status)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to update %s status: %w", kind, statusError(resp))
 	}
-	return nil
+	// Pick up the new resourceVersion so the caller can update again.
+	return json.NewDecoder(resp.Body).Decode(obj)
 }
 
-func (c *Client) ListNodes(ctx context.Context) ([]api.Node, error) {
-	url := fmt.Sprintf("%s/api/v1/nodes", c.BaseURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// PatchType is the Content-Type of a patch, which says how the apiserver
+// applies it.
+type PatchType string
+
+const (
+	// MergePatchType is a JSON merge patch (RFC 7386): an object whose fields
+	// replace those of the stored object, with null removing a field.
+	MergePatchType PatchType = "application/merge-patch+json"
+	// JSONPatchType is a JSON patch (RFC 6902): a list of operations.
+	JSONPatchType PatchType = "application/json-patch+json"
+)
+
+// patch sends a patch to url and decodes the patched object into out.
+func (c *Client) patch(ctx context.Context, url, kind string, pt PatchType, data []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewBuffer(data))
 	if err != nil {
-		return nil, err
+		return err
 	}
+	req.Header.Set("Content-Type", string(pt))
 
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api request failed: %s", resp.Status)
+		return fmt.Errorf("failed to patch %s: %w", kind, statusError(resp))
 	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
 
-	var list api.NodeList
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
-		return nil, err
-	}
+// Patch applies a patch to the named object of a namespaced resource under
+// group (e.g. /apis/apps/v1) and decodes the result into out. The apiserver
+// applies it to the object as stored, so it doesn't need to be read first.
+func (c *Client) Patch(ctx context.Context, group, resource, namespace, name string, pt PatchType, data []byte, out interface{}) error {
+	return c.patch(ctx, c.resourceURL(group, resource, namespace, name), resource, pt, data, out)
+}
 
-	return list.Items, nil
+// ApplyOptions name the manager of an apply and whether it may take over
+// fields other managers own.
+type ApplyOptions struct {
+	FieldManager string
+	Force        bool
 }
 
-// ReplicaSets
+// Apply sends config, a partial object with just the fields the caller
+// manages, to be merged into the named object of a namespaced resource under
+// group, which is created if it doesn't exist. The result is decoded into
+// out. If another manager owns a field config would change, the apiserver
+// responds with a conflict unless opts.Force is set.
+func (c *Client) Apply(ctx context.Context, group, resource, namespace, name string, config []byte, opts ApplyOptions, out interface{}) error {
+	return c.apply(ctx, c.resourceURL(group, resource, namespace, name), resource, config, opts, out)
+}
 
-func (c *Client) ListReplicaSets(ctx context.Context) ([]api.ReplicaSet, error) {
-	url := fmt.Sprintf("%s/apis/apps/v1/replicasets", c.BaseURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// ApplyClusterObject is Apply for a resource that isn't namespaced, e.g.
+// nodes.
+func (c *Client) ApplyClusterObject(ctx context.Context, group, resource, name string, config []byte, opts ApplyOptions, out interface{}) error {
+	return c.apply(ctx, fmt.Sprintf("%s%s/%s/%s", c.BaseURL, group, resource, name), resource, config, opts, out)
+}
+
+func (c *Client) apply(ctx context.Context, url, kind string, config []byte, opts ApplyOptions, out interface{}) error {
+	query := neturl.Values{"fieldManager": {opts.FieldManager}}
+	if opts.Force {
+		query.Set("force", "true")
+	}
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url+"?"+query.Encode(), bytes.NewBuffer(config))
 	if err != nil {
-		return nil, err
+		return err
 	}
+	req.Header.Set("Content-Type", "application/apply-patch+json")
 
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api request failed: %s", resp.Status)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to apply %s: %w", kind, statusError(resp))
 	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
 
-	var list api.ReplicaSetList
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+// PatchPod applies a patch to a pod and returns the result.
+func (c *Client) PatchPod(ctx context.Context, namespace, name string, pt PatchType, data []byte) (*api.Pod, error) {
+	var pod api.Pod
+	if err := c.Patch(ctx, "/api/v1", "pods", namespace, name, pt, data, &pod); err != nil {
 		return nil, err
 	}
-
-	return list.Items, nil
+	return &pod, nil
 }
 
-func (c *Client) CreateReplicaSet(ctx context.Context, rs *api.ReplicaSet) error {
-	url := fmt.Sprintf("%s/apis/apps/v1/replicasets", c.BaseURL)
-	data, err := json.Marshal(rs)
+// Nodes
+
+func (c *Client) RegisterNode(ctx context.Context, node *api.Node) error {
+	url := fmt.Sprintf("%s/api/v1/nodes", c.BaseURL)
+
+	data, err := json.Marshal(node)
 	if err != nil {
 		return err
 	}
@@ -253,20 +391,51 @@ func (c *Client) CreateReplicaSet(ctx context.Context, rs *api.ReplicaSet) error
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusConflict {
+		return nil
+	}
+
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to create replicaset: %s", resp.Status)
+		return fmt.Errorf("failed to register node: %w", statusError(resp))
 	}
 	return nil
 }
 
-func (c *Client) UpdateReplicaSet(ctx context.Context, rs *api.ReplicaSet) error {
-	url := fmt.Sprintf("%s/apis/apps/v1/replicasets/%s", c.BaseURL, rs.Name)
+// UpdateNodeStatus writes the status of node, leaving its spec and metadata
+// as stored.
+func (c *Client) UpdateNodeStatus(ctx context.Context, node *api.Node) error {
+	return c.updateStatus(ctx, fmt.Sprintf("%s/api/v1/nodes/%s", c.BaseURL, node.Name), "node", node)
+}
+
+// PatchNode applies a patch to a node and returns the result.
+func (c *Client) PatchNode(ctx context.Context, name string, pt PatchType, data []byte) (*api.Node, error) {
+	var node api.Node
+	if err := c.patch(ctx, fmt.Sprintf("%s/api/v1/nodes/%s", c.BaseURL, name), "node", pt, data, &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+func (c *Client) ListNodes(ctx context.Context, opts ListOptions) ([]api.Node, error) {
+	items, _, err := c.NodeListWatch(opts).List(ctx)
+	return items, err
+}
+
+// ReplicaSets
+
+func (c *Client) ListReplicaSets(ctx context.Context, namespace string, opts ListOptions) ([]api.ReplicaSet, error) {
+	items, _, err := c.ReplicaSetListWatch(namespace, opts).List(ctx)
+	return items, err
+}
+
+func (c *Client) CreateReplicaSet(ctx context.Context, rs *api.ReplicaSet) error {
+	url := c.resourceURL("/apis/apps/v1", "replicasets", rs.Namespace, "")
 	data, err := json.Marshal(rs)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
 	if err != nil {
 		return err
 	}
@@ -277,41 +446,65 @@ func (c *Client) UpdateReplicaSet(ctx context.Context, rs *api.ReplicaSet) error
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update replicaset: %s", resp.Status)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to create replicaset: %w", statusError(resp))
 	}
 	return nil
 }
 
-// Deployments
+func (c *Client) UpdateReplicaSet(ctx context.Context, rs *api.ReplicaSet) error {
+	url := c.resourceURL("/apis/apps/v1", "replicasets", rs.Namespace, rs.Name)
+	data, err := json.Marshal(rs)
+	if err != nil {
+		return err
+	}
 
-func (c *Client) ListDeployments(ctx context.Context) ([]api.Deployment, error) {
-	url := fmt.Sprintf("%s/apis/apps/v1/deployments", c.BaseURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(data))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api request failed: %s", resp.Status)
+		return fmt.Errorf("failed to update replicaset: %w", statusError(resp))
 	}
+	// Pick up the new resourceVersion so the caller can update again.
+	return json.NewDecoder(resp.Body).Decode(rs)
+}
+
+// UpdateReplicaSetStatus writes the status of rs, leaving its spec and
+// metadata as stored.
+func (c *Client) UpdateReplicaSetStatus(ctx context.Context, rs *api.ReplicaSet) error {
+	return c.updateStatus(ctx, c.resourceURL("/apis/apps/v1", "replicasets", rs.Namespace, rs.Name), "replicaset", rs)
+}
 
-	var list api.DeploymentList
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+// PatchReplicaSet applies a patch to a replicaset and returns the result.
+func (c *Client) PatchReplicaSet(ctx context.Context, namespace, name string, pt PatchType, data []byte) (*api.ReplicaSet, error) {
+	var rs api.ReplicaSet
+	if err := c.Patch(ctx, "/apis/apps/v1", "replicasets", namespace, name, pt, data, &rs); err != nil {
 		return nil, err
 	}
+	return &rs, nil
+}
+
+func (c *Client) DeleteReplicaSet(ctx context.Context, namespace, name string) error {
+	return c.deleteObject(ctx, c.resourceURL("/apis/apps/v1", "replicasets", namespace, name), "replicaset")
+}
+
+// Deployments
 
-	return list.Items, nil
+func (c *Client) ListDeployments(ctx context.Context, namespace string, opts ListOptions) ([]api.Deployment, error) {
+	items, _, err := c.DeploymentListWatch(namespace, opts).List(ctx)
+	return items, err
 }
 
 func (c *Client) UpdateDeployment(ctx context.Context, deploy *api.Deployment) error {
-	url := fmt.Sprintf("%s/apis/apps/v1/deployments/%s", c.BaseURL, deploy.Name)
+	url := c.resourceURL("/apis/apps/v1", "deployments", deploy.Namespace, deploy.Name)
 	data, err := json.Marshal(deploy)
 	if err != nil {
 		return err
@@ -329,13 +522,38 @@ func (c *Client) UpdateDeployment(ctx context.Context, deploy *api.Deployment) e
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update deployment: %s", resp.Status)
+		return fmt.Errorf("failed to update deployment: %w", statusError(resp))
 	}
-	return nil
+	// Pick up the new resourceVersion so the caller can update again.
+	return json.NewDecoder(resp.Body).Decode(deploy)
+}
+
+// UpdateDeploymentStatus writes the status of deploy, leaving its spec and
+// metadata as stored.
+func (c *Client) UpdateDeploymentStatus(ctx context.Context, deploy *api.Deployment) error {
+	return c.updateStatus(ctx, c.resourceURL("/apis/apps/v1", "deployments", deploy.Namespace, deploy.Name), "deployment", deploy)
+}
+
+// PatchDeployment applies a patch to a deployment and returns the result.
+func (c *Client) PatchDeployment(ctx context.Context, namespace, name string, pt PatchType, data []byte) (*api.Deployment, error) {
+	var deploy api.Deployment
+	if err := c.Patch(ctx, "/apis/apps/v1", "deployments", namespace, name, pt, data, &deploy); err != nil {
+		return nil, err
+	}
+	return &deploy, nil
+}
+
+// ScaleDeployment sets the number of replicas of a deployment.
+func (c *Client) ScaleDeployment(ctx context.Context, namespace, name string, replicas int32) (*api.Deployment, error) {
+	data, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"replicas": replicas}})
+	if err != nil {
+		return nil, err
+	}
+	return c.PatchDeployment(ctx, namespace, name, MergePatchType, data)
 }
 
 func (c *Client) CreateDeployment(ctx context.Context, deploy *api.Deployment) error {
-	url := fmt.Sprintf("%s/apis/apps/v1/deployments", c.BaseURL)
+	url := c.resourceURL("/apis/apps/v1", "deployments", deploy.Namespace, "")
 	data, err := json.Marshal(deploy)
 	if err != nil {
 		return err
@@ -353,40 +571,24 @@ func (c *Client) CreateDeployment(ctx context.Context, deploy *api.Deployment) e
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to create deployment: %s", resp.Status)
+		return fmt.Errorf("failed to create deployment: %w", statusError(resp))
 	}
 	return nil
 }
 
-// Services
-
-func (c *Client) ListServices(ctx context.Context) ([]api.Service, error) {
-	url := fmt.Sprintf("%s/api/v1/services", c.BaseURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.HTTP.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api request failed: %s", resp.Status)
-	}
+func (c *Client) DeleteDeployment(ctx context.Context, namespace, name string) error {
+	return c.deleteObject(ctx, c.resourceURL("/apis/apps/v1", "deployments", namespace, name), "deployment")
+}
 
-	var list api.ServiceList
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
-		return nil, err
-	}
+// Services
 
-	return list.Items, nil
+func (c *Client) ListServices(ctx context.Context, namespace string, opts ListOptions) ([]api.Service, error) {
+	items, _, err := c.ServiceListWatch(namespace, opts).List(ctx)
+	return items, err
 }
 
 func (c *Client) CreateService(ctx context.Context, svc *api.Service) error {
-	url := fmt.Sprintf("%s/api/v1/services", c.BaseURL)
+	url := c.resourceURL("/api/v1", "services", svc.Namespace, "")
 	data, err := json.Marshal(svc)
 	if err != nil {
 		return err
@@ -404,15 +606,39 @@ func (c *Client) CreateService(ctx context.Context, svc *api.Service) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to create service: %s", resp.Status)
+		return fmt.Errorf("failed to create service: %w", statusError(resp))
 	}
 	return nil
 }
 
+// UpdateServiceStatus writes the status of svc, leaving its spec and
+// metadata as stored.
+func (c *Client) UpdateServiceStatus(ctx context.Context, svc *api.Service) error {
+	return c.updateStatus(ctx, c.resourceURL("/api/v1", "services", svc.Namespace, svc.Name), "service", svc)
+}
+
+// PatchService applies a patch to a service and returns the result.
+func (c *Client) PatchService(ctx context.Context, namespace, name string, pt PatchType, data []byte) (*api.Service, error) {
+	var svc api.Service
+	if err := c.Patch(ctx, "/api/v1", "services", namespace, name, pt, data, &svc); err != nil {
+		return nil, err
+	}
+	return &svc, nil
+}
+
+func (c *Client) DeleteService(ctx context.Context, namespace, name string) error {
+	return c.deleteObject(ctx, c.resourceURL("/api/v1", "services", namespace, name), "service")
+}
+
 // Endpoints
 
-func (c *Client) GetEndpoints(ctx context.Context, name string) (*api.Endpoints, error) {
-	url := fmt.Sprintf("%s/api/v1/endpoints/%s", c.BaseURL, name)
+func (c *Client) ListEndpoints(ctx context.Context, namespace string, opts ListOptions) ([]api.Endpoints, error) {
+	items, _, err := c.EndpointsListWatch(namespace, opts).List(ctx)
+	return items, err
+}
+
+func (c *Client) GetEndpoints(ctx context.Context, namespace, name string) (*api.Endpoints, error) {
+	url := c.resourceURL("/api/v1", "endpoints", namespace, name)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -428,7 +654,7 @@ func (c *Client) GetEndpoints(ctx context.Context, name string) (*api.Endpoints,
 		if resp.StatusCode == http.StatusNotFound {
 			return nil, nil // Not found
 		}
-		return nil, fmt.Errorf("api request failed: %s", resp.Status)
+		return nil, fmt.Errorf("api request failed: %w", statusError(resp))
 	}
 
 	var ep api.Endpoints
@@ -440,7 +666,7 @@ func (c *Client) GetEndpoints(ctx context.Context, name string) (*api.Endpoints,
 }
 
 func (c *Client) CreateEndpoints(ctx context.Context, ep *api.Endpoints) error {
-	url := fmt.Sprintf("%s/api/v1/endpoints", c.BaseURL)
+	url := c.resourceURL("/api/v1", "endpoints", ep.Namespace, "")
 	data, err := json.Marshal(ep)
 	if err != nil {
 		return err
@@ -458,13 +684,13 @@ func (c *Client) CreateEndpoints(ctx context.Context, ep *api.Endpoints) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to create endpoints: %s", resp.Status)
+		return fmt.Errorf("failed to create endpoints: %w", statusError(resp))
 	}
 	return nil
 }
 
 func (c *Client) UpdateEndpoints(ctx context.Context, ep *api.Endpoints) error {
-	url := fmt.Sprintf("%s/api/v1/endpoints/%s", c.BaseURL, ep.Name)
+	url := c.resourceURL("/api/v1", "endpoints", ep.Namespace, ep.Name)
 	data, err := json.Marshal(ep)
 	if err != nil {
 		return err
@@ -482,15 +708,25 @@ func (c *Client) UpdateEndpoints(ctx context.Context, ep *api.Endpoints) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update endpoints: %s", resp.Status)
+		return fmt.Errorf("failed to update endpoints: %w", statusError(resp))
 	}
-	return nil
+	// Pick up the new resourceVersion so the caller can update again.
+	return json.NewDecoder(resp.Body).Decode(ep)
+}
+
+func (c *Client) DeleteEndpoints(ctx context.Context, namespace, name string) error {
+	return c.deleteObject(ctx, c.resourceURL("/api/v1", "endpoints", namespace, name), "endpoints")
 }
 
 // Leases
 
-func (c *Client) GetLease(ctx context.Context, name string) (*api.Lease, error) {
-	url := fmt.Sprintf("%s/api/v1/leases/%s", c.BaseURL, name)
+func (c *Client) ListLeases(ctx context.Context, namespace string, opts ListOptions) ([]api.Lease, error) {
+	items, _, err := c.LeaseListWatch(namespace, opts).List(ctx)
+	return items, err
+}
+
+func (c *Client) GetLease(ctx context.Context, namespace, name string) (*api.Lease, error) {
+	url := c.resourceURL("/api/v1", "leases", namespace, name)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -506,7 +742,7 @@ func (c *Client) GetLease(ctx context.Context, name string) (*api.Lease, error)
 		if resp.StatusCode == http.StatusNotFound {
 			return nil, nil // Not found
 		}
-		return nil, fmt.Errorf("api request failed: %s", resp.Status)
+		return nil, fmt.Errorf("api request failed: %w", statusError(resp))
 	}
 
 	var lease api.Lease
@@ -518,7 +754,7 @@ func (c *Client) GetLease(ctx context.Context, name string) (*api.Lease, error)
 }
 
 func (c *Client) CreateLease(ctx context.Context, lease *api.Lease) error {
-	url := fmt.Sprintf("%s/api/v1/leases", c.BaseURL)
+	url := c.resourceURL("/api/v1", "leases", lease.Namespace, "")
 	data, err := json.Marshal(lease)
 	if err != nil {
 		return err
@@ -536,13 +772,13 @@ func (c *Client) CreateLease(ctx context.Context, lease *api.Lease) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to create lease: %s", resp.Status)
+		return fmt.Errorf("failed to create lease: %w", statusError(resp))
 	}
 	return nil
 }
 
 func (c *Client) UpdateLease(ctx context.Context, lease *api.Lease) error {
-	url := fmt.Sprintf("%s/api/v1/leases/%s", c.BaseURL, lease.Name)
+	url := c.resourceURL("/api/v1", "leases", lease.Namespace, lease.Name)
 	data, err := json.Marshal(lease)
 	if err != nil {
 		return err
@@ -560,15 +796,106 @@ func (c *Client) UpdateLease(ctx context.Context, lease *api.Lease) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update lease: %s", resp.Status)
+		return fmt.Errorf("failed to update lease: %w", statusError(resp))
+	}
+	// Pick up the new resourceVersion so the caller can update again.
+	return json.NewDecoder(resp.Body).Decode(lease)
+}
+
+func (c *Client) DeleteLease(ctx context.Context, namespace, name string) error {
+	return c.deleteObject(ctx, c.resourceURL("/api/v1", "leases", namespace, name), "lease")
+}
+
+// Namespaces
+
+func (c *Client) ListNamespaces(ctx context.Context, opts ListOptions) ([]api.Namespace, error) {
+	items, _, err := c.NamespaceListWatch(opts).List(ctx)
+	return items, err
+}
+
+func (c *Client) CreateNamespace(ctx context.Context, ns *api.Namespace) error {
+	url := fmt.Sprintf("%s/api/v1/namespaces", c.BaseURL)
+	data, err := json.Marshal(ns)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to create namespace: %w", statusError(resp))
 	}
 	return nil
 }
 
+// PatchNamespace applies a patch to a namespace and returns the result.
+func (c *Client) PatchNamespace(ctx context.Context, name string, pt PatchType, data []byte) (*api.Namespace, error) {
+	var ns api.Namespace
+	if err := c.patch(ctx, fmt.Sprintf("%s/api/v1/namespaces/%s", c.BaseURL, name), "namespace", pt, data, &ns); err != nil {
+		return nil, err
+	}
+	return &ns, nil
+}
+
+// DeleteNamespace starts deleting a namespace. It is removed once everything
+// in it has been deleted.
+func (c *Client) DeleteNamespace(ctx context.Context, name string) error {
+	return c.deleteObject(ctx, fmt.Sprintf("%s/api/v1/namespaces/%s", c.BaseURL, name), "namespace")
+}
 
+// FinalizeNamespace replaces the finalizers of a namespace with those of ns.
+func (c *Client) FinalizeNamespace(ctx context.Context, ns *api.Namespace) error {
+	url := fmt.Sprintf("%s/api/v1/namespaces/%s/finalize", c.BaseURL, ns.Name)
+	data, err := json.Marshal(ns)
+	if err != nil {
+		return err
+	}
 
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to finalize namespace: %w", statusError(resp))
+	}
+	return json.NewDecoder(resp.Body).Decode(ns)
+}
 
+// Admin
 
+// Backup writes a point-in-time copy of the cluster state to w, in the
+// format the apiserver's --restore-from flag reads.
+func (c *Client) Backup(ctx context.Context, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/admin/backup", nil)
+	if err != nil {
+		return err
+	}
 
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to back up cluster state: %w", statusError(resp))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
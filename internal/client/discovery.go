@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// Resource is a resource served by the apiserver, as discovered, along with
+// the group version it is served in.
+type Resource struct {
+	GroupVersion string // e.g. "apps/v1", or just "v1" in the core group
+	api.APIResource
+}
+
+// Path returns the path the API of the resource is served under, e.g.
+// /apis/apps/v1, as taken by Patch and Apply.
+func (r Resource) Path() string {
+	if strings.Contains(r.GroupVersion, "/") {
+		return "/apis/" + r.GroupVersion
+	}
+	return "/api/" + r.GroupVersion
+}
+
+// IsSubresource reports whether r is a subresource, e.g. pods/status.
+func (r Resource) IsSubresource() bool {
+	return strings.Contains(r.Name, "/")
+}
+
+// ServerResources returns every resource the server serves, subresources
+// included, in the preferred version of each API group.
+func (c *Client) ServerResources(ctx context.Context) ([]Resource, error) {
+	var versions api.APIVersions
+	if err := c.getJSON(ctx, c.BaseURL+"/api", "API versions", &versions); err != nil {
+		return nil, err
+	}
+	var groups api.APIGroupList
+	if err := c.getJSON(ctx, c.BaseURL+"/apis", "API groups", &groups); err != nil {
+		return nil, err
+	}
+
+	var groupVersions []string
+	groupVersions = append(groupVersions, versions.Versions...)
+	for _, g := range groups.Groups {
+		groupVersions = append(groupVersions, g.PreferredVersion.GroupVersion)
+	}
+
+	var resources []Resource
+	for _, gv := range groupVersions {
+		var list api.APIResourceList
+		path := Resource{GroupVersion: gv}.Path()
+		if err := c.getJSON(ctx, c.BaseURL+path, "API resources", &list); err != nil {
+			return nil, err
+		}
+		for _, r := range list.Resources {
+			resources = append(resources, Resource{GroupVersion: list.GroupVersion, APIResource: r})
+		}
+	}
+	return resources, nil
+}
+
+// FindResource looks up the resource called name on the server, the way
+// kubectl does: name may be its plural or singular name, one of its short
+// names or its kind, in any case, e.g. "deployments", "deploy" or
+// "Deployment".
+func (c *Client) FindResource(ctx context.Context, name string) (Resource, error) {
+	resources, err := c.ServerResources(ctx)
+	if err != nil {
+		return Resource{}, err
+	}
+	for _, r := range resources {
+		if !r.IsSubresource() && r.matches(name) {
+			return r, nil
+		}
+	}
+	return Resource{}, fmt.Errorf("the server doesn't have a resource type %q", name)
+}
+
+func (r Resource) matches(name string) bool {
+	name = strings.ToLower(name)
+	if name == r.Name || name == r.SingularName || name == strings.ToLower(r.Kind) {
+		return true
+	}
+	for _, s := range r.ShortNames {
+		if name == s {
+			return true
+		}
+	}
+	return false
+}
+
+// collectionURL returns the URL of the objects of res in namespace, or in
+// every namespace for api.NamespaceAll. Namespaces are ignored for resources
+// that aren't namespaced.
+func (c *Client) collectionURL(res Resource, namespace string) string {
+	if !res.Namespaced {
+		return c.BaseURL + res.Path() + "/" + res.Name
+	}
+	return c.listURL(res.Path(), res.Name, namespace)
+}
+
+// objectURL returns the URL of the named object of res in namespace.
+func (c *Client) objectURL(res Resource, namespace, name string) string {
+	if !res.Namespaced {
+		return c.BaseURL + res.Path() + "/" + res.Name + "/" + name
+	}
+	return c.resourceURL(res.Path(), res.Name, namespace, name)
+}
+
+// Get reads the named object of res, a discovered resource, into out.
+func (c *Client) Get(ctx context.Context, res Resource, namespace, name string, out interface{}) error {
+	return c.getJSON(ctx, c.objectURL(res, namespace, name), res.SingularName, out)
+}
+
+// ForEach calls fn for every object of res, a discovered resource, in
+// namespace, or in every namespace for api.NamespaceAll. Set opts.Limit to
+// hold a single page of objects in memory at a time.
+func (c *Client) ForEach(ctx context.Context, res Resource, namespace string, opts ListOptions, fn func(json.RawMessage) error) error {
+	lw := &ListWatch[json.RawMessage]{client: c, url: c.collectionURL(res, namespace), opts: opts}
+	return lw.ForEach(ctx, func(obj *json.RawMessage) error { return fn(*obj) })
+}
+
+// Delete deletes the named object of res, a discovered resource. Unlike the
+// typed deletes, it fails if the object doesn't exist.
+func (c *Client) Delete(ctx context.Context, res Resource, namespace, name string) error {
+	return c.delete(ctx, c.objectURL(res, namespace, name), res.SingularName)
+}
+
+// getJSON GETs url and decodes the response into out.
+func (c *Client) getJSON(ctx context.Context, url, kind string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: %w", kind, statusError(resp))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
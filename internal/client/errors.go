@@ -0,0 +1,109 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// StatusError is a request the apiserver refused, as described by the Status
+// it responded with.
+type StatusError struct {
+	ErrStatus api.Status
+}
+
+func (e *StatusError) Error() string {
+	return e.ErrStatus.Message
+}
+
+// statusError reads the Status from resp, a failed response. A response
+// without one, e.g. from a proxy, is described by its HTTP status.
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var status api.Status
+	if err := json.Unmarshal(body, &status); err != nil || status.Kind != "Status" {
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			message = resp.Status
+		}
+		status = api.Status{
+			Status:  api.StatusFailure,
+			Message: message,
+			Reason:  reasonForCode(resp.StatusCode),
+		}
+	}
+	status.Code = int32(resp.StatusCode)
+	return &StatusError{ErrStatus: status}
+}
+
+// reasonForCode guesses the reason for a failure from its HTTP status.
+func reasonForCode(code int) api.StatusReason {
+	switch code {
+	case http.StatusBadRequest:
+		return api.StatusReasonBadRequest
+	case http.StatusForbidden:
+		return api.StatusReasonForbidden
+	case http.StatusNotFound:
+		return api.StatusReasonNotFound
+	case http.StatusConflict:
+		return api.StatusReasonConflict
+	case http.StatusUnsupportedMediaType:
+		return api.StatusReasonUnsupportedMediaType
+	case http.StatusGone:
+		return api.StatusReasonGone
+	case http.StatusUnprocessableEntity:
+		return api.StatusReasonInvalid
+	case http.StatusInternalServerError:
+		return api.StatusReasonInternalError
+	}
+	return api.StatusReasonUnknown
+}
+
+// ReasonForError returns the reason the apiserver gave for err, or
+// api.StatusReasonUnknown if err didn't come from the apiserver.
+func ReasonForError(err error) api.StatusReason {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.ErrStatus.Reason
+	}
+	return api.StatusReasonUnknown
+}
+
+// IsNotFound reports whether err says the object doesn't exist.
+func IsNotFound(err error) bool {
+	return ReasonForError(err) == api.StatusReasonNotFound
+}
+
+// IsAlreadyExists reports whether err says an object of that name exists.
+func IsAlreadyExists(err error) bool {
+	return ReasonForError(err) == api.StatusReasonAlreadyExists
+}
+
+// IsConflict reports whether err says the write conflicted with another,
+// e.g. because it was based on an outdated object.
+func IsConflict(err error) bool {
+	return ReasonForError(err) == api.StatusReasonConflict
+}
+
+// IsInvalid reports whether err says the object failed validation. The
+// invalid fields are in the causes of its details.
+func IsInvalid(err error) bool {
+	return ReasonForError(err) == api.StatusReasonInvalid
+}
+
+// IsResourceExpired reports whether err says the resourceVersion a watch
+// resumed from is older than the history the server keeps, so that the
+// objects have to be listed again.
+func IsResourceExpired(err error) bool {
+	return ReasonForError(err) == api.StatusReasonExpired
+}
+
+// IsGone reports whether err says what was requested is no longer there.
+// Expired resourceVersions are reported by IsResourceExpired instead.
+func IsGone(err error) bool {
+	return ReasonForError(err) == api.StatusReasonGone
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+func TestStatusError(t *testing.T) {
+	checks := map[string]func(error) bool{
+		"IsNotFound":        IsNotFound,
+		"IsAlreadyExists":   IsAlreadyExists,
+		"IsConflict":        IsConflict,
+		"IsInvalid":         IsInvalid,
+		"IsResourceExpired": IsResourceExpired,
+		"IsGone":            IsGone,
+	}
+	tests := []struct {
+		name    string
+		code    int
+		body    string
+		reason  api.StatusReason
+		message string
+		is      string // the check that holds, if any
+	}{
+		{
+			name:    "not found",
+			code:    http.StatusNotFound,
+			body:    `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"pods \"web\" not found","reason":"NotFound","details":{"name":"web","kind":"pods"},"code":404}`,
+			reason:  api.StatusReasonNotFound,
+			message: `pods "web" not found`,
+			is:      "IsNotFound",
+		},
+		{
+			// Both are 409s, told apart by their reason alone.
+			name:    "already exists",
+			code:    http.StatusConflict,
+			body:    `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"pods \"web\" already exists","reason":"AlreadyExists","code":409}`,
+			reason:  api.StatusReasonAlreadyExists,
+			message: `pods "web" already exists`,
+			is:      "IsAlreadyExists",
+		},
+		{
+			name:    "conflict",
+			code:    http.StatusConflict,
+			body:    `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"Operation cannot be fulfilled","reason":"Conflict","code":409}`,
+			reason:  api.StatusReasonConflict,
+			message: "Operation cannot be fulfilled",
+			is:      "IsConflict",
+		},
+		{
+			name:    "invalid",
+			code:    http.StatusUnprocessableEntity,
+			body:    `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"Pod \"web\" is invalid","reason":"Invalid","code":422}`,
+			reason:  api.StatusReasonInvalid,
+			message: `Pod "web" is invalid`,
+			is:      "IsInvalid",
+		},
+		{
+			name:    "expired",
+			code:    http.StatusGone,
+			body:    `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"too old resource version: 1 (5)","reason":"Expired","code":410}`,
+			reason:  api.StatusReasonExpired,
+			message: "too old resource version: 1 (5)",
+			is:      "IsResourceExpired",
+		},
+		{
+			name:    "not a Status",
+			code:    http.StatusNotFound,
+			body:    "404 page not found\n",
+			reason:  api.StatusReasonNotFound,
+			message: "404 page not found",
+			is:      "IsNotFound",
+		},
+		{
+			name:    "empty gone",
+			code:    http.StatusGone,
+			reason:  api.StatusReasonGone,
+			message: "410 Gone",
+			is:      "IsGone",
+		},
+		{
+			name:    "unknown code",
+			code:    http.StatusTeapot,
+			body:    "short and stout",
+			reason:  api.StatusReasonUnknown,
+			message: "short and stout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := statusError(&http.Response{
+				StatusCode: tt.code,
+				Status:     fmt.Sprintf("%d %s", tt.code, http.StatusText(tt.code)),
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			})
+			if got := ReasonForError(err); got != tt.reason {
+				t.Errorf("got reason %q, want %q", got, tt.reason)
+			}
+			if err.Error() != tt.message {
+				t.Errorf("got message %q, want %q", err.Error(), tt.message)
+			}
+			if code := err.(*StatusError).ErrStatus.Code; code != int32(tt.code) {
+				t.Errorf("got code %d, want %d", code, tt.code)
+			}
+			for name, check := range checks {
+				if got := check(err); got != (name == tt.is) {
+					t.Errorf("%s = %t", name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWatchExpired(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"Type":"ERROR","Object":{"kind":"Status","apiVersion":"v1","status":"Failure","message":"too old resource version: 1 (5)","reason":"Expired","details":{"kind":"pods"},"code":410}}`+"\n")
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, HTTP: srv.Client()}
+	w, err := c.PodListWatch("default", ListOptions{}).Watch(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	event, ok := <-w.ResultChan()
+	if !ok {
+		t.Fatal("watch closed without an event")
+	}
+	if event.Type != Error || event.Object != nil {
+		t.Fatalf("got %s event with object %v, want an Error event", event.Type, event.Object)
+	}
+	if err := event.Err(); !IsResourceExpired(err) {
+		t.Errorf("got %v, want an expired error", err)
+	}
+	if d := event.Status.Details; d == nil || d.Kind != "pods" {
+		t.Errorf("got details %+v, want those of pods", d)
+	}
+}
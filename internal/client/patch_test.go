@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	var method, path, contentType, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, contentType, body = r.Method, r.URL.Path, r.Header.Get("Content-Type"), string(data)
+		if r.URL.Path == "/api/v1/namespaces/default/pods/stale" {
+			w.WriteHeader(http.StatusConflict)
+			io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"the object has been modified","reason":"Conflict","code":409}`)
+			return
+		}
+		io.WriteString(w, `{"metadata":{"name":"web","resourceVersion":"7"}}`)
+	}))
+	defer srv.Close()
+	c := &Client{BaseURL: srv.URL, HTTP: srv.Client()}
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		patch       func() error
+		path        string
+		contentType PatchType
+		body        string
+	}{
+		{
+			name: "pod",
+			patch: func() error {
+				_, err := c.PatchPod(ctx, "prod", "web", MergePatchType, []byte(`{"metadata":{"labels":{"app":"web"}}}`))
+				return err
+			},
+			path:        "/api/v1/namespaces/prod/pods/web",
+			contentType: MergePatchType,
+			body:        `{"metadata":{"labels":{"app":"web"}}}`,
+		},
+		{
+			name: "deployment in the default namespace",
+			patch: func() error {
+				_, err := c.PatchDeployment(ctx, "", "web", JSONPatchType, []byte(`[{"op":"remove","path":"/spec/paused"}]`))
+				return err
+			},
+			path:        "/apis/apps/v1/namespaces/default/deployments/web",
+			contentType: JSONPatchType,
+			body:        `[{"op":"remove","path":"/spec/paused"}]`,
+		},
+		{
+			name: "scale",
+			patch: func() error {
+				_, err := c.ScaleDeployment(ctx, "default", "web", 3)
+				return err
+			},
+			path:        "/apis/apps/v1/namespaces/default/deployments/web",
+			contentType: MergePatchType,
+			body:        `{"spec":{"replicas":3}}`,
+		},
+		{
+			name: "cluster-scoped node",
+			patch: func() error {
+				_, err := c.PatchNode(ctx, "node1", MergePatchType, []byte(`{"spec":{"unschedulable":true}}`))
+				return err
+			},
+			path:        "/api/v1/nodes/node1",
+			contentType: MergePatchType,
+			body:        `{"spec":{"unschedulable":true}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.patch(); err != nil {
+				t.Fatal(err)
+			}
+			if method != "PATCH" || path != tt.path {
+				t.Errorf("got %s %s, want PATCH %s", method, path, tt.path)
+			}
+			if contentType != string(tt.contentType) {
+				t.Errorf("got Content-Type %q, want %q", contentType, tt.contentType)
+			}
+			if body != tt.body {
+				t.Errorf("got body %s, want %s", body, tt.body)
+			}
+		})
+	}
+
+	pod, err := c.PatchPod(ctx, "default", "web", MergePatchType, []byte(`{}`))
+	if err != nil || pod.ResourceVersion != "7" {
+		t.Errorf("got %+v, %v, want the patched pod at resourceVersion 7", pod, err)
+	}
+	if _, err := c.PatchPod(ctx, "default", "stale", MergePatchType, []byte(`{}`)); !IsConflict(err) {
+		t.Errorf("got %v, want a conflict", err)
+	}
+}
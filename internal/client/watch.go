@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// EventType is the type of a watch event, as sent by the API server.
+type EventType string
+
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+	Error    EventType = "ERROR"
+)
+
+// WatchEvent is a single decoded event from a watch stream.
+type WatchEvent[T any] struct {
+	Type   EventType
+	Object *T
+	// Status is set instead of Object on Error events. Err returns it as an
+	// error, e.g. for IsResourceExpired.
+	Status *api.Status
+}
+
+// Err returns the Status of an Error event as a *StatusError, or nil for
+// any other event.
+func (e WatchEvent[T]) Err() error {
+	if e.Status == nil {
+		return nil
+	}
+	return &StatusError{ErrStatus: *e.Status}
+}
+
+// Watcher streams typed events until it is stopped or the server closes the stream.
+type Watcher[T any] struct {
+	result chan WatchEvent[T]
+	cancel context.CancelFunc
+}
+
+// ResultChan returns the event channel. It is closed when the watch ends.
+func (w *Watcher[T]) ResultChan() <-chan WatchEvent[T] {
+	return w.result
+}
+
+// Stop ends the watch and closes the connection.
+func (w *Watcher[T]) Stop() {
+	w.cancel()
+}
+
+// ListWatch lists and watches a single resource, optionally narrowed down by
+// selectors. It is the building block for informers.
+type ListWatch[T any] struct {
+	client *Client
+	url    string // of the collection, without query
+	opts   ListOptions
+}
+
+// List returns every object along with the resourceVersion of the list.
+// With opts.Limit set it fetches the objects one page at a time, but still
+// holds all of them in memory at once; use ForEach to walk a list that may
+// be large.
+func (lw *ListWatch[T]) List(ctx context.Context) ([]T, string, error) {
+	var items []T
+	rv, err := lw.pages(ctx, func(page []T) error {
+		items = append(items, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return items, rv, nil
+}
+
+// ForEach calls fn for every object, holding a single page in memory at a
+// time. It stops at the first error returned by fn.
+func (lw *ListWatch[T]) ForEach(ctx context.Context, fn func(*T) error) error {
+	_, err := lw.pages(ctx, func(page []T) error {
+		for i := range page {
+			if err := fn(&page[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// pages walks the list page by page, following continue tokens. It returns
+// the resourceVersion of the first page, which all later pages share.
+func (lw *ListWatch[T]) pages(ctx context.Context, fn func([]T) error) (string, error) {
+	var rv, cont string
+	for {
+		items, meta, err := lw.page(ctx, cont)
+		if err != nil {
+			return "", err
+		}
+		if rv == "" {
+			rv = meta.ResourceVersion
+		}
+		if err := fn(items); err != nil {
+			return "", err
+		}
+		if meta.Continue == "" {
+			return rv, nil
+		}
+		cont = meta.Continue
+	}
+}
+
+func (lw *ListWatch[T]) page(ctx context.Context, cont string) ([]T, api.ListMeta, error) {
+	q := lw.opts.query()
+	if lw.opts.Limit > 0 {
+		q.Set("limit", strconv.FormatInt(lw.opts.Limit, 10))
+	}
+	if cont != "" {
+		q.Set("continue", cont)
+	}
+	url := lw.url
+	if enc := q.Encode(); enc != "" {
+		url += "?" + enc
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, api.ListMeta{}, err
+	}
+
+	resp, err := lw.client.HTTP.Do(req)
+	if err != nil {
+		return nil, api.ListMeta{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, api.ListMeta{}, fmt.Errorf("api request failed: %w", statusError(resp))
+	}
+
+	var list struct {
+		Metadata api.ListMeta `json:"metadata"`
+		Items    []T          `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, api.ListMeta{}, err
+	}
+	return list.Items, list.Metadata, nil
+}
+
+// Watch streams every change made after resourceVersion.
+// An empty resourceVersion starts from the current state.
+func (lw *ListWatch[T]) Watch(ctx context.Context, resourceVersion string) (*Watcher[T], error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	q := lw.opts.query()
+	q.Set("watch", "true")
+	if resourceVersion != "" {
+		q.Set("resourceVersion", resourceVersion)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", lw.url+"?"+q.Encode(), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp, err := lw.client.HTTP.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("watch request failed: %w", statusError(resp))
+	}
+
+	w := &Watcher[T]{
+		result: make(chan WatchEvent[T]),
+		cancel: cancel,
+	}
+	go func() {
+		defer close(w.result)
+		defer resp.Body.Close()
+
+		// The server sends one JSON-encoded event per line.
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var raw struct {
+				Type   EventType
+				Object json.RawMessage
+			}
+			if err := decoder.Decode(&raw); err != nil {
+				return
+			}
+
+			event := WatchEvent[T]{Type: raw.Type}
+			if raw.Type == Error {
+				event.Status = &api.Status{}
+				json.Unmarshal(raw.Object, event.Status)
+			} else {
+				event.Object = new(T)
+				if err := json.Unmarshal(raw.Object, event.Object); err != nil {
+					return
+				}
+			}
+
+			select {
+			case w.result <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return w, nil
+}
+
+func (c *Client) NamespaceListWatch(opts ListOptions) *ListWatch[api.Namespace] {
+	return &ListWatch[api.Namespace]{client: c, url: c.BaseURL + "/api/v1/namespaces", opts: opts}
+}
+
+func (c *Client) PodListWatch(namespace string, opts ListOptions) *ListWatch[api.Pod] {
+	return &ListWatch[api.Pod]{client: c, url: c.listURL("/api/v1", "pods", namespace), opts: opts}
+}
+
+func (c *Client) NodeListWatch(opts ListOptions) *ListWatch[api.Node] {
+	return &ListWatch[api.Node]{client: c, url: c.BaseURL + "/api/v1/nodes", opts: opts}
+}
+
+func (c *Client) ReplicaSetListWatch(namespace string, opts ListOptions) *ListWatch[api.ReplicaSet] {
+	return &ListWatch[api.ReplicaSet]{client: c, url: c.listURL("/apis/apps/v1", "replicasets", namespace), opts: opts}
+}
+
+func (c *Client) DeploymentListWatch(namespace string, opts ListOptions) *ListWatch[api.Deployment] {
+	return &ListWatch[api.Deployment]{client: c, url: c.listURL("/apis/apps/v1", "deployments", namespace), opts: opts}
+}
+
+func (c *Client) ServiceListWatch(namespace string, opts ListOptions) *ListWatch[api.Service] {
+	return &ListWatch[api.Service]{client: c, url: c.listURL("/api/v1", "services", namespace), opts: opts}
+}
+
+func (c *Client) EndpointsListWatch(namespace string, opts ListOptions) *ListWatch[api.Endpoints] {
+	return &ListWatch[api.Endpoints]{client: c, url: c.listURL("/api/v1", "endpoints", namespace), opts: opts}
+}
+
+func (c *Client) LeaseListWatch(namespace string, opts ListOptions) *ListWatch[api.Lease] {
+	return &ListWatch[api.Lease]{client: c, url: c.listURL("/api/v1", "leases", namespace), opts: opts}
+}
+
+// Typed watches, starting after opts.ResourceVersion
+
+func (c *Client) WatchNamespaces(ctx context.Context, opts ListOptions) (*Watcher[api.Namespace], error) {
+	return c.NamespaceListWatch(opts).Watch(ctx, opts.ResourceVersion)
+}
+
+func (c *Client) WatchPods(ctx context.Context, namespace string, opts ListOptions) (*Watcher[api.Pod], error) {
+	return c.PodListWatch(namespace, opts).Watch(ctx, opts.ResourceVersion)
+}
+
+func (c *Client) WatchNodes(ctx context.Context, opts ListOptions) (*Watcher[api.Node], error) {
+	return c.NodeListWatch(opts).Watch(ctx, opts.ResourceVersion)
+}
+
+func (c *Client) WatchReplicaSets(ctx context.Context, namespace string, opts ListOptions) (*Watcher[api.ReplicaSet], error) {
+	return c.ReplicaSetListWatch(namespace, opts).Watch(ctx, opts.ResourceVersion)
+}
+
+func (c *Client) WatchDeployments(ctx context.Context, namespace string, opts ListOptions) (*Watcher[api.Deployment], error) {
+	return c.DeploymentListWatch(namespace, opts).Watch(ctx, opts.ResourceVersion)
+}
+
+func (c *Client) WatchServices(ctx context.Context, namespace string, opts ListOptions) (*Watcher[api.Service], error) {
+	return c.ServiceListWatch(namespace, opts).Watch(ctx, opts.ResourceVersion)
+}
+
+func (c *Client) WatchEndpoints(ctx context.Context, namespace string, opts ListOptions) (*Watcher[api.Endpoints], error) {
+	return c.EndpointsListWatch(namespace, opts).Watch(ctx, opts.ResourceVersion)
+}
+
+func (c *Client) WatchLeases(ctx context.Context, namespace string, opts ListOptions) (*Watcher[api.Lease], error) {
+	return c.LeaseListWatch(namespace, opts).Watch(ctx, opts.ResourceVersion)
+}
+
+// WatchLease watches the single Lease namespace/name, e.g. to notice as soon
+// as a lock changes hands.
+func (c *Client) WatchLease(ctx context.Context, namespace, name string, opts ListOptions) (*Watcher[api.Lease], error) {
+	lw := &ListWatch[api.Lease]{client: c, url: c.resourceURL("/api/v1", "leases", namespace, name), opts: opts}
+	return lw.Watch(ctx, opts.ResourceVersion)
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/abhigod/k8s-lite/internal/workqueue"
+)
+
+// Controller is a reconcile loop the controller-manager can run.
+type Controller interface {
+	// Name identifies the controller in logs.
+	Name() string
+	// Run processes work with the given number of workers until ctx is cancelled.
+	Run(ctx context.Context, workers int)
+}
+
+// SyncFunc reconciles the object identified by key. Returning an error
+// requeues the key with backoff.
+type SyncFunc func(ctx context.Context, key string) error
+
+// maxRetries is how many times a failing key is retried before it is dropped.
+// It is picked up again on the next event or informer resync.
+const maxRetries = 15
+
+// RunWorkers starts workers that pull keys off queue and pass them to syncHandler.
+// It blocks until ctx is cancelled, then shuts the queue down and waits for
+// in-flight work to finish.
+func RunWorkers(ctx context.Context, name string, queue *workqueue.Queue, workers int, syncHandler SyncFunc) {
+	log.Printf("Starting %s controller with %d workers", name, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for processNextItem(ctx, name, queue, syncHandler) {
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	queue.ShutDown()
+	wg.Wait()
+}
+
+func processNextItem(ctx context.Context, name string, queue *workqueue.Queue, syncHandler SyncFunc) bool {
+	key, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(key)
+
+	err := syncHandler(ctx, key)
+	if err == nil {
+		queue.Forget(key)
+		return true
+	}
+
+	if queue.NumRequeues(key) < maxRetries {
+		log.Printf("Error syncing %s %q, retrying: %v", name, key, err)
+		queue.AddRateLimited(key)
+		return true
+	}
+
+	log.Printf("Dropping %s %q out of the queue: %v", name, key, err)
+	queue.Forget(key)
+	return true
+}
@@ -7,65 +7,85 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/abhigod/k8s-lite/internal/api"
 	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/controller"
+	"github.com/abhigod/k8s-lite/internal/informer"
+	"github.com/abhigod/k8s-lite/internal/labels"
+	"github.com/abhigod/k8s-lite/internal/workqueue"
 )
 
 type Controller struct {
 	Client *client.Client
-}
 
-func New(client *client.Client) *Controller {
-	return &Controller{Client: client}
+	dInformer  *informer.Informer[api.Deployment]
+	rsInformer *informer.Informer[api.ReplicaSet]
+	queue      *workqueue.Queue
 }
 
-func (c *Controller) Start(ctx context.Context) {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			if err := c.reconcile(ctx); err != nil {
-				log.Printf("Error reconciling deployments: %v", err)
+func New(client *client.Client, informers *informer.Factory) *Controller {
+	c := &Controller{
+		Client:     client,
+		dInformer:  informers.Deployments(),
+		rsInformer: informers.ReplicaSets(),
+		queue:      workqueue.New("deployment"),
+	}
+
+	c.dInformer.AddEventHandler(informer.ResourceEventHandler[api.Deployment]{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, d *api.Deployment) { c.enqueue(d) },
+	})
+
+	// ReplicaSet changes wake up the Deployments that select them.
+	enqueueOwners := func(rs *api.ReplicaSet) {
+		for _, d := range c.dInformer.List() {
+			if sel, ok := selectorFor(d); ok && d.Namespace == rs.Namespace && sel.Matches(rs.Labels) {
+				c.enqueue(d)
 			}
 		}
 	}
+	c.rsInformer.AddEventHandler(informer.ResourceEventHandler[api.ReplicaSet]{
+		AddFunc:    enqueueOwners,
+		UpdateFunc: func(_, rs *api.ReplicaSet) { enqueueOwners(rs) },
+		DeleteFunc: enqueueOwners,
+	})
+	return c
 }
 
-func (c *Controller) reconcile(ctx context.Context) error {
-	deployments, err := c.Client.ListDeployments(ctx)
-	if err != nil {
-		return err
-	}
+func (c *Controller) Name() string {
+	return "deployment"
+}
 
-	// For each deployment, ensure we have the correct RS
-	for _, d := range deployments {
-		if err := c.syncDeployment(ctx, &d); err != nil {
-			log.Printf("Error syncing deployment %s: %v", d.Name, err)
-		}
-	}
-	return nil
+func (c *Controller) Run(ctx context.Context, workers int) {
+	controller.RunWorkers(ctx, c.Name(), c.queue, workers, c.syncDeployment)
+}
+
+func (c *Controller) enqueue(d *api.Deployment) {
+	c.queue.Add(informer.MetaKey(d))
 }
 
-func (c *Controller) syncDeployment(ctx context.Context, d *api.Deployment) error {
-	// 1. List all ReplicaSets
-	allRS, err := c.Client.ListReplicaSets(ctx)
-	if err != nil {
-		return err
+func (c *Controller) syncDeployment(ctx context.Context, key string) error {
+	// 1. Get the Deployment from the cache
+	cached, ok := c.dInformer.Get(key)
+	if !ok {
+		return nil // Deleted
 	}
+	d := *cached
 
-	// 2. Filter RS owned by this Deployment (by Label Selector for now, or Name convention)
-	// For MVP, we use labels match.
+	selector, ok := selectorFor(&d)
+	if !ok {
+		log.Printf("Deployment %s has an invalid or empty selector, ignoring", d.Name)
+		return nil
+	}
+
+	// 2. Filter RS owned by this Deployment by label selector.
+	// Cached objects are shared, so take copies we are free to modify.
 	var ownedRS []*api.ReplicaSet
-	for i := range allRS {
-		rs := &allRS[i]
-		if labelsMatch(d.Spec.Selector.MatchLabels, rs.ObjectMeta.Labels) {
-			ownedRS = append(ownedRS, rs)
+	for _, cachedRS := range c.rsInformer.List() {
+		if cachedRS.Namespace == d.Namespace && selector.Matches(cachedRS.ObjectMeta.Labels) {
+			rs := *cachedRS
+			ownedRS = append(ownedRS, &rs)
 		}
 	}
 
@@ -85,7 +105,8 @@ func (c *Controller) syncDeployment(ctx context.Context, d *api.Deployment) erro
 	// 5. If New RS doesn't exist, Create it
 	if newRS == nil {
 		log.Printf("Creating new ReplicaSet for Deployment %s (hash: %s)", d.Name, podTemplateHash)
-		newRS, err = c.createNewReplicaSet(ctx, d, podTemplateHash)
+		var err error
+		newRS, err = c.createNewReplicaSet(ctx, &d, podTemplateHash)
 		if err != nil {
 			return fmt.Errorf("failed to create new RS: %v", err)
 		}
@@ -149,7 +170,7 @@ func (c *Controller) syncDeployment(ctx context.Context, d *api.Deployment) erro
 		// Let's assume I will add        log.Printf("Scaling New RS %s to %d", newRS.Name, replicas)
 		log.Printf("Scaling New RS %s to %d", newRS.Name, replicas)
 		if err := c.Client.UpdateReplicaSet(ctx, newRS); err != nil {
-			log.Printf("Failed to update RS %s: %v", newRS.Name, err)
+			return fmt.Errorf("failed to update RS %s: %v", newRS.Name, err)
 		}
 	}
 
@@ -160,11 +181,37 @@ func (c *Controller) syncDeployment(ctx context.Context, d *api.Deployment) erro
 			old.Spec.Replicas = &zero
 			log.Printf("Scaling Down Old RS %s to 0", old.Name)
 			if err := c.Client.UpdateReplicaSet(ctx, old); err != nil {
-				log.Printf("Failed to update RS %s: %v", old.Name, err)
+				return fmt.Errorf("failed to update RS %s: %v", old.Name, err)
 			}
 		}
 	}
 
+	return c.updateStatus(ctx, &d, newRS, ownedRS, desiredReplicas)
+}
+
+// updateStatus sums up the ReplicaSets of d, and records that its current
+// spec has been acted on. d is a copy of the cached Deployment.
+func (c *Controller) updateStatus(ctx context.Context, d *api.Deployment, newRS *api.ReplicaSet, ownedRS []*api.ReplicaSet, desired int32) error {
+	status := api.DeploymentStatus{
+		ObservedGeneration: d.Generation,
+		UpdatedReplicas:    newRS.Status.Replicas,
+	}
+	for _, rs := range ownedRS {
+		status.Replicas += rs.Status.Replicas
+		status.ReadyReplicas += rs.Status.ReadyReplicas
+		status.AvailableReplicas += rs.Status.AvailableReplicas
+	}
+	if status.AvailableReplicas < desired {
+		status.UnavailableReplicas = desired - status.AvailableReplicas
+	}
+	if status == d.Status {
+		return nil
+	}
+
+	d.Status = status
+	if err := c.Client.UpdateDeploymentStatus(ctx, d); err != nil {
+		return fmt.Errorf("failed to update status of Deployment %s: %v", d.Name, err)
+	}
 	return nil
 }
 
@@ -215,16 +262,12 @@ func computeHash(template api.PodTemplateSpec) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func labelsMatch(selector, labels map[string]string) bool {
-	for k, v := range selector {
-		if labels[k] != v {
-			return false
-		}
+// selectorFor returns the ReplicaSet selector of d. Invalid and empty selectors
+// are reported as unusable, so such a Deployment never adopts anything.
+func selectorFor(d *api.Deployment) (labels.Selector, bool) {
+	sel, err := labels.SelectorFromLabelSelector(d.Spec.Selector)
+	if err != nil || sel.Empty() {
+		return nil, false
 	}
-	return true
+	return sel, true
 }
-
-
-
-
-
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// expectationsTimeout bounds how long a controller waits for creations or
+// deletions it made to show up in its cache before acting again anyway.
+const expectationsTimeout = 5 * time.Minute
+
+// Expectations tracks creations and deletions a controller has issued but not
+// yet observed through its informers. While they are outstanding, the cache
+// is known to be stale for that key and the controller must not act on it,
+// or it would create or delete the same objects twice.
+type Expectations struct {
+	lock    sync.Mutex
+	pending map[string]*expectation
+}
+
+type expectation struct {
+	add       int
+	del       int
+	timestamp time.Time
+}
+
+func NewExpectations() *Expectations {
+	return &Expectations{pending: make(map[string]*expectation)}
+}
+
+// Expect records that adds creations and dels deletions are in flight for key.
+func (e *Expectations) Expect(key string, adds, dels int) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.pending[key] = &expectation{add: adds, del: dels, timestamp: time.Now()}
+}
+
+// CreationObserved lowers the pending creations for key by one. Controllers
+// also call it for creations that failed, since those will never be observed.
+func (e *Expectations) CreationObserved(key string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if exp, ok := e.pending[key]; ok {
+		exp.add--
+	}
+}
+
+// DeletionObserved lowers the pending deletions for key by one.
+func (e *Expectations) DeletionObserved(key string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if exp, ok := e.pending[key]; ok {
+		exp.del--
+	}
+}
+
+// Satisfied reports whether the controller may act on key: nothing is
+// pending, everything pending was observed, or the expectations expired.
+func (e *Expectations) Satisfied(key string) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	exp, ok := e.pending[key]
+	if !ok {
+		return true
+	}
+	return (exp.add <= 0 && exp.del <= 0) || time.Since(exp.timestamp) > expectationsTimeout
+}
+
+// Delete forgets key, e.g. once its object has been deleted.
+func (e *Expectations) Delete(key string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	delete(e.pending, key)
+}
@@ -0,0 +1,156 @@
+package namespace
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/controller"
+	"github.com/abhigod/k8s-lite/internal/informer"
+	"github.com/abhigod/k8s-lite/internal/workqueue"
+)
+
+// recheckDelay is how long to wait before checking that a namespace whose
+// content was just deleted is really empty.
+const recheckDelay = time.Second
+
+// listPageSize is how many objects of a resource are listed per request.
+const listPageSize = 500
+
+// Controller deletes everything in a terminating namespace, then removes the
+// namespace's finalizer so the API server can delete it.
+type Controller struct {
+	Client *client.Client
+
+	nsInformer *informer.Informer[api.Namespace]
+	queue      *workqueue.Queue
+}
+
+func New(client *client.Client, informers *informer.Factory) *Controller {
+	c := &Controller{
+		Client:     client,
+		nsInformer: informers.Namespaces(),
+		queue:      workqueue.New("namespace"),
+	}
+
+	c.nsInformer.AddEventHandler(informer.ResourceEventHandler[api.Namespace]{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, ns *api.Namespace) { c.enqueue(ns) },
+	})
+	return c
+}
+
+func (c *Controller) Name() string {
+	return "namespace"
+}
+
+func (c *Controller) Run(ctx context.Context, workers int) {
+	controller.RunWorkers(ctx, c.Name(), c.queue, workers, c.syncNamespace)
+}
+
+func (c *Controller) enqueue(ns *api.Namespace) {
+	if ns.DeletionTimestamp != nil {
+		c.queue.Add(informer.MetaKey(ns))
+	}
+}
+
+func (c *Controller) syncNamespace(ctx context.Context, key string) error {
+	cached, ok := c.nsInformer.Get(key)
+	if !ok || cached.DeletionTimestamp == nil {
+		return nil
+	}
+
+	var finalizers []string
+	for _, f := range cached.Spec.Finalizers {
+		if f != api.FinalizerKubernetes {
+			finalizers = append(finalizers, f)
+		}
+	}
+	if len(finalizers) == len(cached.Spec.Finalizers) {
+		return nil // Already finalized by us
+	}
+
+	found, err := c.deleteContent(ctx, cached.Name)
+	if err != nil {
+		return err
+	}
+	if found > 0 {
+		// Check again once the deletions have settled; controllers may still
+		// have been working on objects in the namespace.
+		log.Printf("Deleted %d objects in namespace %s", found, cached.Name)
+		c.queue.AddAfter(key, recheckDelay)
+		return nil
+	}
+
+	// Work on a copy; cached objects are shared.
+	ns := *cached
+	ns.Spec.Finalizers = finalizers
+	if err := c.Client.FinalizeNamespace(ctx, &ns); err != nil {
+		if client.IsConflict(err) {
+			// An object landed after our list, or the namespace changed;
+			// the apiserver won't release a namespace that isn't empty.
+			c.queue.AddAfter(key, recheckDelay)
+			return nil
+		}
+		return err
+	}
+	log.Printf("Namespace %s is empty, finalized", ns.Name)
+	return nil
+}
+
+// deleteContent deletes every object in namespace, of every namespaced
+// resource the apiserver serves, and returns how many it found. Owners go
+// first so their controllers stop recreating what is deleted after them.
+func (c *Controller) deleteContent(ctx context.Context, namespace string) (int, error) {
+	resources, err := c.Client.ServerResources(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var namespaced []client.Resource
+	for _, res := range resources {
+		if res.Namespaced && !res.IsSubresource() {
+			namespaced = append(namespaced, res)
+		}
+	}
+	sort.SliceStable(namespaced, func(i, j int) bool {
+		return deleteRank(namespaced[i].Name) < deleteRank(namespaced[j].Name)
+	})
+
+	found := 0
+	for _, res := range namespaced {
+		err := c.Client.ForEach(ctx, res, namespace, client.ListOptions{Limit: listPageSize}, func(item json.RawMessage) error {
+			var obj struct {
+				Metadata api.ObjectMeta `json:"metadata"`
+			}
+			if err := json.Unmarshal(item, &obj); err != nil {
+				return err
+			}
+			found++
+			if err := c.Client.Delete(ctx, res, namespace, obj.Metadata.Name); err != nil && !client.IsNotFound(err) {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return found, err
+		}
+	}
+	return found, nil
+}
+
+// deleteOrder lists the resources whose controllers create objects of other
+// resources, in the order to delete them. Everything else follows.
+var deleteOrder = []string{"deployments", "replicasets"}
+
+func deleteRank(resource string) int {
+	for i, r := range deleteOrder {
+		if r == resource {
+			return i
+		}
+	}
+	return len(deleteOrder)
+}
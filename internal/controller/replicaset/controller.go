@@ -4,71 +4,116 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/abhigod/k8s-lite/internal/api"
 	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/controller"
+	"github.com/abhigod/k8s-lite/internal/informer"
+	"github.com/abhigod/k8s-lite/internal/labels"
+	"github.com/abhigod/k8s-lite/internal/workqueue"
 	"github.com/google/uuid"
 )
 
 type Controller struct {
 	Client *client.Client
+
+	rsInformer   *informer.Informer[api.ReplicaSet]
+	podInformer  *informer.Informer[api.Pod]
+	queue        *workqueue.Queue
+	expectations *controller.Expectations
 }
 
-func New(client *client.Client) *Controller {
-	return &Controller{
-		Client: client,
+func New(client *client.Client, informers *informer.Factory) *Controller {
+	c := &Controller{
+		Client:       client,
+		rsInformer:   informers.ReplicaSets(),
+		podInformer:  informers.Pods(),
+		queue:        workqueue.New("replicaset"),
+		expectations: controller.NewExpectations(),
 	}
+
+	c.rsInformer.AddEventHandler(informer.ResourceEventHandler[api.ReplicaSet]{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, rs *api.ReplicaSet) { c.enqueue(rs) },
+		DeleteFunc: func(rs *api.ReplicaSet) { c.expectations.Delete(informer.MetaKey(rs)) },
+	})
+
+	// Pod changes wake up the ReplicaSets that select them.
+	c.podInformer.AddEventHandler(informer.ResourceEventHandler[api.Pod]{
+		AddFunc: func(pod *api.Pod) {
+			for _, key := range c.replicaSetsFor(pod) {
+				c.expectations.CreationObserved(key)
+				c.queue.Add(key)
+			}
+		},
+		UpdateFunc: func(old, pod *api.Pod) {
+			for _, key := range c.replicaSetsFor(old) {
+				c.queue.Add(key)
+			}
+			for _, key := range c.replicaSetsFor(pod) {
+				c.queue.Add(key)
+			}
+		},
+		DeleteFunc: func(pod *api.Pod) {
+			for _, key := range c.replicaSetsFor(pod) {
+				c.expectations.DeletionObserved(key)
+				c.queue.Add(key)
+			}
+		},
+	})
+	return c
 }
 
-func (c *Controller) Start(ctx context.Context) {
-	log.Println("Starting ReplicaSet Controller...")
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			c.reconcile(ctx)
-		}
-	}
+func (c *Controller) Name() string {
+	return "replicaset"
+}
+
+func (c *Controller) Run(ctx context.Context, workers int) {
+	controller.RunWorkers(ctx, c.Name(), c.queue, workers, c.syncReplicaSet)
+}
+
+func (c *Controller) enqueue(rs *api.ReplicaSet) {
+	c.queue.Add(informer.MetaKey(rs))
 }
 
-func (c *Controller) reconcile(ctx context.Context) {
-	// 1. List All ReplicaSets
-	rss, err := c.Client.ListReplicaSets(ctx)
-	if err != nil {
-		log.Printf("Error listing replicasets: %v", err)
-		return
+// replicaSetsFor returns the keys of the ReplicaSets whose selector matches pod.
+func (c *Controller) replicaSetsFor(pod *api.Pod) []string {
+	var keys []string
+	for _, rs := range c.rsInformer.List() {
+		if sel, ok := selectorFor(rs); ok && rs.Namespace == pod.Namespace && sel.Matches(pod.Labels) {
+			keys = append(keys, informer.MetaKey(rs))
+		}
 	}
+	return keys
+}
 
-	// 2. List All Pods (Optimization: List once and filter in memory)
-	// 2. List All Pods (Optimization: List once and filter in memory)
-	pods, err := c.Client.ListPods(ctx, "")
-	if err != nil {
-		log.Printf("Error listing pods: %v", err)
-		return
+func (c *Controller) syncReplicaSet(ctx context.Context, key string) error {
+	rs, ok := c.rsInformer.Get(key)
+	if !ok {
+		return nil // Deleted
 	}
 
-	// 3. Reconcile each RS
-	for _, rs := range rss {
-		c.reconcileRS(ctx, &rs, pods)
+	// Wait until our own creations/deletions show up in the cache,
+	// otherwise we would count them as missing and act twice.
+	if !c.expectations.Satisfied(key) {
+		return nil
 	}
-}
 
-func (c *Controller) reconcileRS(ctx context.Context, rs *api.ReplicaSet, allPods []api.Pod) {
 	if rs.Spec.Replicas == nil {
-		return
+		return nil
 	}
 	desired := *rs.Spec.Replicas
 
+	selector, ok := selectorFor(rs)
+	if !ok {
+		log.Printf("RS %s has an invalid or empty selector, ignoring", rs.Name)
+		return nil
+	}
+
 	// Find owned pods
-	// Simple label match
-	var ownedPods []api.Pod
-	for _, pod := range allPods {
-		if labelsMatch(rs.Spec.Selector.MatchLabels, pod.Labels) {
+	var ownedPods []*api.Pod
+	for _, pod := range c.podInformer.List() {
+		if pod.Namespace == rs.Namespace && selector.Matches(pod.Labels) {
 			ownedPods = append(ownedPods, pod)
 		}
 	}
@@ -76,28 +121,73 @@ func (c *Controller) reconcileRS(ctx context.Context, rs *api.ReplicaSet, allPod
 	current := int32(len(ownedPods))
 	log.Printf("RS %s: Desired=%d, Current=%d", rs.Name, desired, current)
 
+	var firstErr error
 	if current < desired {
 		// Scale Up
 		diff := desired - current
 		log.Printf("Scaling up RS %s by %d", rs.Name, diff)
+		c.expectations.Expect(key, int(diff), 0)
 		for i := int32(0); i < diff; i++ {
 			if err := c.createPod(ctx, rs); err != nil {
 				log.Printf("Failed to create pod for RS %s: %v", rs.Name, err)
+				// This creation will never be observed.
+				c.expectations.CreationObserved(key)
+				if firstErr == nil {
+					firstErr = err
+				}
 			}
 		}
 	} else if current > desired {
 		// Scale Down
 		diff := current - desired
 		log.Printf("Scaling down RS %s by %d", rs.Name, diff)
+		c.expectations.Expect(key, 0, int(diff))
 		// Delete simplest ones (e.g. Pending, or just random first ones)
-		// We'll delete from the end of our list
 		for i := int32(0); i < diff; i++ {
 			pod := ownedPods[i] // Simple selection
-			if err := c.Client.DeletePod(ctx, pod.Name); err != nil {
+			if err := c.Client.DeletePod(ctx, pod.Namespace, pod.Name); err != nil {
 				log.Printf("Failed to delete pod %s: %v", pod.Name, err)
+				c.expectations.DeletionObserved(key)
+				if firstErr == nil {
+					firstErr = err
+				}
 			}
 		}
 	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return c.updateStatus(ctx, rs, ownedPods)
+}
+
+// updateStatus records the pods rs had when it was synced, and that its
+// current spec has been acted on.
+func (c *Controller) updateStatus(ctx context.Context, cached *api.ReplicaSet, pods []*api.Pod) error {
+	status := api.ReplicaSetStatus{
+		ObservedGeneration: cached.Generation,
+		Replicas:           int32(len(pods)),
+	}
+	templateLabels := labels.SelectorFromSet(cached.Spec.Template.Labels)
+	for _, pod := range pods {
+		if templateLabels.Matches(pod.Labels) {
+			status.FullyLabeledReplicas++
+		}
+		if pod.Status.Phase == "Running" {
+			status.ReadyReplicas++
+			status.AvailableReplicas++
+		}
+	}
+	if status == cached.Status {
+		return nil
+	}
+
+	// Cached objects are shared, so update a copy.
+	rs := *cached
+	rs.Status = status
+	if err := c.Client.UpdateReplicaSetStatus(ctx, &rs); err != nil {
+		return fmt.Errorf("failed to update status of RS %s: %v", rs.Name, err)
+	}
+	return nil
 }
 
 func (c *Controller) createPod(ctx context.Context, rs *api.ReplicaSet) error {
@@ -113,6 +203,7 @@ func (c *Controller) createPod(ctx context.Context, rs *api.ReplicaSet) error {
 
 	// Generate Name: rs-name-random
 	pod.Name = fmt.Sprintf("%s-%s", rs.Name, uuid.New().String()[:5])
+	pod.Namespace = rs.Namespace
 
 	// Ensure labels are set from template (they should match selector)
 	if pod.Labels == nil {
@@ -125,20 +216,12 @@ func (c *Controller) createPod(ctx context.Context, rs *api.ReplicaSet) error {
 	return c.Client.CreatePod(ctx, pod)
 }
 
-func labelsMatch(selector map[string]string, labels map[string]string) bool {
-	if len(selector) == 0 {
-		return false // Empty selector matches nothing? Or everything? Kubernetes says empty selector matches everything usually, but for RS it should be strict.
-		// Let's assume matches nothing to be safe against accidental adoption.
+// selectorFor returns the pod selector of rs. Invalid and empty selectors are
+// reported as unusable, so such a ReplicaSet never adopts any pod.
+func selectorFor(rs *api.ReplicaSet) (labels.Selector, bool) {
+	sel, err := labels.SelectorFromLabelSelector(rs.Spec.Selector)
+	if err != nil || sel.Empty() {
+		return nil, false
 	}
-	for k, v := range selector {
-		if val, ok := labels[k]; !ok || val != v {
-			return false
-		}
-	}
-	return true
+	return sel, true
 }
-
-
-
-
-
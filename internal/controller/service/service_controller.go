@@ -5,76 +5,97 @@ import (
 	"log"
 	"reflect"
 	"sort"
-	"time"
 
 	"github.com/abhigod/k8s-lite/internal/api"
 	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/controller"
+	"github.com/abhigod/k8s-lite/internal/informer"
+	"github.com/abhigod/k8s-lite/internal/labels"
+	"github.com/abhigod/k8s-lite/internal/workqueue"
 )
 
 type ServiceController struct {
 	Client *client.Client
+
+	svcInformer *informer.Informer[api.Service]
+	podInformer *informer.Informer[api.Pod]
+	epInformer  *informer.Informer[api.Endpoints]
+	queue       *workqueue.Queue
 }
 
-func NewController(client *client.Client) *ServiceController {
-	return &ServiceController{
-		Client: client,
+func NewController(client *client.Client, informers *informer.Factory) *ServiceController {
+	c := &ServiceController{
+		Client:      client,
+		svcInformer: informers.Services(),
+		podInformer: informers.Pods(),
+		epInformer:  informers.Endpoints(),
+		queue:       workqueue.New("service"),
 	}
-}
 
-func (c *ServiceController) Run(ctx context.Context) {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+	c.svcInformer.AddEventHandler(informer.ResourceEventHandler[api.Service]{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, svc *api.Service) { c.enqueue(svc) },
+	})
 
-	log.Println("Service Controller started")
+	// Pod changes wake up the Services that select them, before and after the change.
+	c.podInformer.AddEventHandler(informer.ResourceEventHandler[api.Pod]{
+		AddFunc: c.enqueueServicesFor,
+		UpdateFunc: func(old, pod *api.Pod) {
+			c.enqueueServicesFor(old)
+			c.enqueueServicesFor(pod)
+		},
+		DeleteFunc: c.enqueueServicesFor,
+	})
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			if err := c.syncServices(ctx); err != nil {
-				log.Printf("Error syncing services: %v", err)
-			}
-		}
-	}
+	// Endpoints are named after their Service; resync them if someone else edits them.
+	c.epInformer.AddEventHandler(informer.ResourceEventHandler[api.Endpoints]{
+		UpdateFunc: func(_, ep *api.Endpoints) { c.queue.Add(informer.MetaKey(ep)) },
+		DeleteFunc: func(ep *api.Endpoints) { c.queue.Add(informer.MetaKey(ep)) },
+	})
+	return c
 }
 
-func (c *ServiceController) syncServices(ctx context.Context) error {
-	services, err := c.Client.ListServices(ctx)
-	if err != nil {
-		return err
-	}
+func (c *ServiceController) Name() string {
+	return "service"
+}
+
+func (c *ServiceController) Run(ctx context.Context, workers int) {
+	controller.RunWorkers(ctx, c.Name(), c.queue, workers, c.syncService)
+}
+
+func (c *ServiceController) enqueue(svc *api.Service) {
+	c.queue.Add(informer.MetaKey(svc))
+}
 
-	for _, svc := range services {
-		if err := c.reconcileService(ctx, &svc); err != nil {
-			log.Printf("Error reconciling service %s: %v", svc.Name, err)
+func (c *ServiceController) enqueueServicesFor(pod *api.Pod) {
+	for _, svc := range c.svcInformer.List() {
+		if svc.Namespace == pod.Namespace && len(svc.Spec.Selector) > 0 && labels.SelectorFromSet(svc.Spec.Selector).Matches(pod.Labels) {
+			c.enqueue(svc)
 		}
 	}
-	return nil
 }
 
-func (c *ServiceController) reconcileService(ctx context.Context, svc *api.Service) error {
+func (c *ServiceController) syncService(ctx context.Context, key string) error {
+	svc, ok := c.svcInformer.Get(key)
+	if !ok {
+		return nil // Deleted
+	}
+
 	// If the service has no selector, we don't manage endpoints (user might).
 	if len(svc.Spec.Selector) == 0 {
 		return nil
 	}
 
-	// 1. Find Pods matching selector
-	// For MVP, we list all pods and filter. Inefficient but fine for now.
-	// Assume single namespace or matching namespace.
-	pods, err := c.Client.ListPods(ctx, "") // All pods
-	if err != nil {
-		return err
-	}
-
+	// 1. Find Pods matching selector, from the cache.
+	selector := labels.SelectorFromSet(svc.Spec.Selector)
 	var matchingPods []api.Pod
-	for _, pod := range pods {
+	for _, pod := range c.podInformer.List() {
 		if pod.Namespace != svc.Namespace {
 			continue // Should match namespace
 		}
-		if isMatch(pod.Labels, svc.Spec.Selector) {
+		if selector.Matches(pod.Labels) {
 			if pod.Status.Phase == "Running" && pod.Status.PodIP != "" {
-				matchingPods = append(matchingPods, pod)
+				matchingPods = append(matchingPods, *pod)
 			}
 		}
 	}
@@ -122,19 +143,16 @@ func (c *ServiceController) reconcileService(ctx context.Context, svc *api.Servi
 			Namespace: svc.Namespace,
 			Labels:    svc.Labels,
 		},
-		Subsets: []api.EndpointSubset{},
+		// Left nil when empty so it compares equal to what we read back from the API.
+		Subsets: nil,
 	}
 	if len(subset.Addresses) > 0 {
 		desiredEp.Subsets = append(desiredEp.Subsets, subset)
 	}
 
 	// 3. Get Existing Endpoints
-	existingEp, err := c.Client.GetEndpoints(ctx, svc.Name)
-	if err != nil {
-		return err
-	}
-
-	if existingEp == nil {
+	cachedEp, exists := c.epInformer.Get(key)
+	if !exists {
 		// Create
 		log.Printf("Creating Endpoints for Service %s with %d addresses", svc.Name, len(subset.Addresses))
 		return c.Client.CreateEndpoints(ctx, desiredEp)
@@ -142,23 +160,12 @@ func (c *ServiceController) reconcileService(ctx context.Context, svc *api.Servi
 
 	// 4. Update if different
 	// DeepEqual check
-	if !reflect.DeepEqual(existingEp.Subsets, desiredEp.Subsets) {
+	if !reflect.DeepEqual(cachedEp.Subsets, desiredEp.Subsets) {
+		existingEp := *cachedEp
 		existingEp.Subsets = desiredEp.Subsets
 		log.Printf("Updating Endpoints for Service %s with %d addresses", svc.Name, len(subset.Addresses))
-		return c.Client.UpdateEndpoints(ctx, existingEp)
+		return c.Client.UpdateEndpoints(ctx, &existingEp)
 	}
 
 	return nil
 }
-
-func isMatch(labels, selector map[string]string) bool {
-	for k, v := range selector {
-		if labels[k] != v {
-			return false
-		}
-	}
-	return true
-}
-
-
-
@@ -0,0 +1,119 @@
+// Package fields implements field selectors, as used by the fieldSelector
+// query parameter, e.g. "spec.nodeName=node1,status.phase!=Succeeded".
+package fields
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Operator is the relation a Requirement checks.
+type Operator string
+
+const (
+	Equals       Operator = "="
+	DoubleEquals Operator = "=="
+	NotEquals    Operator = "!="
+)
+
+// Fields gives access to the fields of an object by dotted path,
+// e.g. "spec.nodeName". Missing fields read as the empty string.
+type Fields interface {
+	Get(field string) string
+}
+
+// Set is a Fields backed by a map.
+type Set map[string]string
+
+func (s Set) Get(field string) string {
+	return s[field]
+}
+
+// Requirement is a single condition on one field.
+type Requirement struct {
+	Field    string
+	Operator Operator
+	Value    string
+}
+
+func (r Requirement) Matches(f Fields) bool {
+	if r.Operator == NotEquals {
+		return f.Get(r.Field) != r.Value
+	}
+	return f.Get(r.Field) == r.Value
+}
+
+func (r Requirement) String() string {
+	return r.Field + string(r.Operator) + r.Value
+}
+
+// Selector is a list of requirements that must all hold.
+// An empty Selector matches everything.
+type Selector []Requirement
+
+// Everything returns a selector that matches all objects.
+func Everything() Selector {
+	return nil
+}
+
+// OneTermEqualSelector returns a selector requiring field to equal value.
+func OneTermEqualSelector(field, value string) Selector {
+	return Selector{{Field: field, Operator: Equals, Value: value}}
+}
+
+func (s Selector) Matches(f Fields) bool {
+	for _, r := range s {
+		if !r.Matches(f) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+func (s Selector) String() string {
+	terms := make([]string, len(s))
+	for i, r := range s {
+		terms[i] = r.String()
+	}
+	return strings.Join(terms, ",")
+}
+
+var fieldPattern = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*$`)
+
+// Parse parses a selector of comma-separated field=value, field==value and
+// field!=value terms. An empty value selects objects where the field is unset,
+// e.g. "spec.nodeName=" matches unscheduled pods.
+func Parse(selector string) (Selector, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, nil
+	}
+
+	var sel Selector
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		var r Requirement
+		for _, op := range []Operator{NotEquals, DoubleEquals, Equals} {
+			if i := strings.Index(term, string(op)); i >= 0 {
+				r = Requirement{
+					Field:    strings.TrimSpace(term[:i]),
+					Operator: op,
+					Value:    strings.TrimSpace(term[i+len(op):]),
+				}
+				break
+			}
+		}
+		if r.Operator == "" {
+			return nil, fmt.Errorf("invalid field selector term %q: expected field=value or field!=value", term)
+		}
+		if !fieldPattern.MatchString(r.Field) {
+			return nil, fmt.Errorf("invalid field path %q", r.Field)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
@@ -0,0 +1,122 @@
+package fields
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     Selector
+	}{
+		{selector: "", want: nil},
+		{selector: "  ", want: nil},
+		{selector: "spec.nodeName=node1", want: Selector{{Field: "spec.nodeName", Operator: Equals, Value: "node1"}}},
+		{selector: "spec.nodeName==node1", want: Selector{{Field: "spec.nodeName", Operator: DoubleEquals, Value: "node1"}}},
+		{selector: "spec.nodeName!=node1", want: Selector{{Field: "spec.nodeName", Operator: NotEquals, Value: "node1"}}},
+		{selector: "spec.nodeName=", want: Selector{{Field: "spec.nodeName", Operator: Equals, Value: ""}}},
+		{selector: "spec.nodeName!=", want: Selector{{Field: "spec.nodeName", Operator: NotEquals, Value: ""}}},
+		{
+			selector: "spec.nodeName = node1, status.phase != Succeeded",
+			want: Selector{
+				{Field: "spec.nodeName", Operator: Equals, Value: "node1"},
+				{Field: "status.phase", Operator: NotEquals, Value: "Succeeded"},
+			},
+		},
+		{selector: "metadata.name=web", want: Selector{{Field: "metadata.name", Operator: Equals, Value: "web"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.selector, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %#v, want %#v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+	}{
+		{name: "no operator", selector: "spec.nodeName"},
+		{name: "set operator", selector: "spec.nodeName in (node1)"},
+		{name: "ordering operator", selector: "spec.replicas>1"},
+		{name: "negation", selector: "!spec.nodeName"},
+		{name: "empty term", selector: "spec.nodeName=node1,"},
+		{name: "empty field", selector: "=node1"},
+		{name: "trailing dot", selector: "spec.=node1"},
+		{name: "empty path segment", selector: "spec..nodeName=node1"},
+		{name: "index", selector: "spec.containers[0].name=web"},
+		{name: "space in field", selector: "spec node=node1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sel, err := Parse(tt.selector); err == nil {
+				t.Errorf("Parse(%q) = %v, want an error", tt.selector, sel)
+			}
+		})
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	scheduled := Set{"spec.nodeName": "node1", "status.phase": "Running"}
+	unscheduled := Set{"spec.nodeName": "", "status.phase": "Pending"}
+	missing := Set{"status.phase": "Pending"}
+
+	tests := []struct {
+		selector string
+		fields   Set
+		want     bool
+	}{
+		{selector: "", fields: scheduled, want: true},
+		{selector: "spec.nodeName=node1", fields: scheduled, want: true},
+		{selector: "spec.nodeName==node1", fields: scheduled, want: true},
+		{selector: "spec.nodeName=node2", fields: scheduled, want: false},
+		{selector: "spec.nodeName!=node2", fields: scheduled, want: true},
+		{selector: "spec.nodeName!=node1", fields: scheduled, want: false},
+		// An empty value stands for an unset field.
+		{selector: "spec.nodeName=", fields: unscheduled, want: true},
+		{selector: "spec.nodeName=", fields: missing, want: true},
+		{selector: "spec.nodeName=", fields: scheduled, want: false},
+		{selector: "spec.nodeName!=", fields: scheduled, want: true},
+		{selector: "spec.nodeName!=", fields: unscheduled, want: false},
+		{selector: "spec.nodeName!=", fields: missing, want: false},
+		{selector: "spec.nodeName!=node1", fields: unscheduled, want: true},
+		{selector: "spec.nodeName!=node1", fields: missing, want: true},
+		{selector: "spec.nodeName=,status.phase!=Succeeded", fields: unscheduled, want: true},
+		{selector: "spec.nodeName=,status.phase=Running", fields: unscheduled, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.selector, err)
+			}
+			if got := sel.Matches(tt.fields); got != tt.want {
+				t.Errorf("%q matches %v = %v, want %v", tt.selector, tt.fields, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOneTermEqualSelector(t *testing.T) {
+	sel := OneTermEqualSelector("spec.nodeName", "node1")
+	if got, want := sel.String(), "spec.nodeName=node1"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if !sel.Matches(Set{"spec.nodeName": "node1"}) || sel.Matches(Set{}) {
+		t.Error("OneTermEqualSelector matches the wrong objects")
+	}
+	if !Everything().Empty() || !Everything().Matches(Set{}) {
+		t.Error("Everything() is not an empty selector")
+	}
+}
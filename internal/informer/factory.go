@@ -0,0 +1,152 @@
+package informer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/client"
+)
+
+// listPageSize is how many objects informers fetch per request on their
+// initial list.
+const listPageSize = 500
+
+// Factory hands out one shared informer per resource, so components running
+// in the same process share a single cache and watch connection.
+type Factory struct {
+	client *client.Client
+	resync time.Duration
+
+	lock      sync.Mutex
+	informers map[string]runnable
+	started   map[string]bool
+}
+
+type runnable interface {
+	Run(ctx context.Context)
+	HasSynced() bool
+}
+
+func NewFactory(client *client.Client, resync time.Duration) *Factory {
+	return &Factory{
+		client:    client,
+		resync:    resync,
+		informers: make(map[string]runnable),
+		started:   make(map[string]bool),
+	}
+}
+
+// Start runs every informer requested so far that isn't running yet.
+func (f *Factory) Start(ctx context.Context) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for name, inf := range f.informers {
+		if !f.started[name] {
+			f.started[name] = true
+			go inf.Run(ctx)
+		}
+	}
+}
+
+// WaitForCacheSync blocks until every started informer has synced, or ctx is
+// done. It reports whether all caches synced.
+func (f *Factory) WaitForCacheSync(ctx context.Context) bool {
+	return WaitForCacheSync(ctx, func() bool {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		for name, inf := range f.informers {
+			if f.started[name] && !inf.HasSynced() {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// WaitForCacheSync blocks until every cacheSync function reports true, or ctx
+// is done. It reports whether all caches synced.
+func WaitForCacheSync(ctx context.Context, cacheSyncs ...func() bool) bool {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		synced := true
+		for _, hasSynced := range cacheSyncs {
+			if !hasSynced() {
+				synced = false
+				break
+			}
+		}
+		if synced {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// informerFor returns the shared informer for name, creating it with newFn on first use.
+func informerFor[T any](f *Factory, name string, newFn func() *Informer[T]) *Informer[T] {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if inf, ok := f.informers[name]; ok {
+		return inf.(*Informer[T])
+	}
+	inf := newFn()
+	f.informers[name] = inf
+	return inf
+}
+
+func (f *Factory) Namespaces() *Informer[api.Namespace] {
+	return informerFor(f, "namespaces", func() *Informer[api.Namespace] {
+		return New[api.Namespace](f.client.NamespaceListWatch(client.ListOptions{Limit: listPageSize}), f.resync)
+	})
+}
+
+func (f *Factory) Pods() *Informer[api.Pod] {
+	return informerFor(f, "pods", func() *Informer[api.Pod] {
+		return New[api.Pod](f.client.PodListWatch(api.NamespaceAll, client.ListOptions{Limit: listPageSize}), f.resync)
+	})
+}
+
+func (f *Factory) Nodes() *Informer[api.Node] {
+	return informerFor(f, "nodes", func() *Informer[api.Node] {
+		return New[api.Node](f.client.NodeListWatch(client.ListOptions{Limit: listPageSize}), f.resync)
+	})
+}
+
+func (f *Factory) ReplicaSets() *Informer[api.ReplicaSet] {
+	return informerFor(f, "replicasets", func() *Informer[api.ReplicaSet] {
+		return New[api.ReplicaSet](f.client.ReplicaSetListWatch(api.NamespaceAll, client.ListOptions{Limit: listPageSize}), f.resync)
+	})
+}
+
+func (f *Factory) Deployments() *Informer[api.Deployment] {
+	return informerFor(f, "deployments", func() *Informer[api.Deployment] {
+		return New[api.Deployment](f.client.DeploymentListWatch(api.NamespaceAll, client.ListOptions{Limit: listPageSize}), f.resync)
+	})
+}
+
+func (f *Factory) Services() *Informer[api.Service] {
+	return informerFor(f, "services", func() *Informer[api.Service] {
+		return New[api.Service](f.client.ServiceListWatch(api.NamespaceAll, client.ListOptions{Limit: listPageSize}), f.resync)
+	})
+}
+
+func (f *Factory) Endpoints() *Informer[api.Endpoints] {
+	return informerFor(f, "endpoints", func() *Informer[api.Endpoints] {
+		return New[api.Endpoints](f.client.EndpointsListWatch(api.NamespaceAll, client.ListOptions{Limit: listPageSize}), f.resync)
+	})
+}
+
+func (f *Factory) Leases() *Informer[api.Lease] {
+	return informerFor(f, "leases", func() *Informer[api.Lease] {
+		return New[api.Lease](f.client.LeaseListWatch(api.NamespaceAll, client.ListOptions{Limit: listPageSize}), f.resync)
+	})
+}
@@ -0,0 +1,45 @@
+package informer
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/client"
+)
+
+func TestFactorySharesInformers(t *testing.T) {
+	fake := newFakeAPI("1", pod("a", "1"))
+	close(fake.listGate)
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &client.Client{BaseURL: srv.URL, HTTP: srv.Client()}
+
+	f := NewFactory(c, 0)
+	pods := f.Pods()
+	if f.Pods() != pods {
+		t.Error("Pods returned a second informer")
+	}
+
+	f.Start(ctx)
+	f.Start(ctx) // starts nothing new
+	syncCtx, syncCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer syncCancel()
+	if !f.WaitForCacheSync(syncCtx) {
+		t.Fatal("factory did not sync")
+	}
+	if _, ok := f.Pods().Get("default/a"); !ok {
+		t.Error("shared informer has not cached a")
+	}
+
+	// The started informer lists once, however often it was requested.
+	fake.mu.Lock()
+	lists := fake.lists
+	fake.mu.Unlock()
+	if lists != 1 {
+		t.Errorf("got %d lists, want 1", lists)
+	}
+}
@@ -0,0 +1,340 @@
+package informer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/client"
+)
+
+// ListerWatcher is what an Informer needs from the API: a full list with its
+// resourceVersion, and a watch that resumes from such a version.
+type ListerWatcher[T any] interface {
+	List(ctx context.Context) ([]T, string, error)
+	Watch(ctx context.Context, resourceVersion string) (*client.Watcher[T], error)
+}
+
+// ResourceEventHandler receives notifications about cache changes.
+// Handlers run on the informer's goroutines and should return quickly,
+// typically by enqueueing a key for a worker.
+type ResourceEventHandler[T any] struct {
+	AddFunc    func(obj *T)
+	UpdateFunc func(oldObj, newObj *T)
+	DeleteFunc func(obj *T)
+}
+
+// IndexFunc computes the index values of an object.
+type IndexFunc[T any] func(obj *T) []string
+
+// Informer keeps a local cache of one resource in sync with the API server
+// using list+watch, and notifies handlers about changes.
+// Objects handed out by the cache are shared and must not be modified.
+type Informer[T any] struct {
+	lw     ListerWatcher[T]
+	resync time.Duration
+
+	lock     sync.RWMutex
+	items    map[string]*T
+	indexers map[string]IndexFunc[T]
+	indices  map[string]map[string]map[string]struct{} // index -> value -> keys
+	handlers []ResourceEventHandler[T]
+	synced   bool
+}
+
+// New creates an informer. If resync is non-zero, UpdateFunc is called for
+// every cached object at that interval so handlers can retry failed work.
+func New[T any](lw ListerWatcher[T], resync time.Duration) *Informer[T] {
+	return &Informer[T]{
+		lw:       lw,
+		resync:   resync,
+		items:    make(map[string]*T),
+		indexers: make(map[string]IndexFunc[T]),
+		indices:  make(map[string]map[string]map[string]struct{}),
+	}
+}
+
+// MetaKey returns the cache key of an API object: "namespace/name", or just
+// "name" for objects without a namespace.
+func MetaKey(obj interface{}) string {
+	o, ok := obj.(api.Object)
+	if !ok {
+		return ""
+	}
+	meta := o.GetObjectMeta()
+	if meta.Namespace == "" {
+		return meta.Name
+	}
+	return meta.Namespace + "/" + meta.Name
+}
+
+// AddEventHandler registers a handler. Handlers added after the informer has
+// synced receive an AddFunc call for every object already in the cache.
+func (i *Informer[T]) AddEventHandler(h ResourceEventHandler[T]) {
+	i.lock.Lock()
+	i.handlers = append(i.handlers, h)
+	var existing []*T
+	if i.synced {
+		for _, obj := range i.items {
+			existing = append(existing, obj)
+		}
+	}
+	i.lock.Unlock()
+
+	if h.AddFunc != nil {
+		for _, obj := range existing {
+			h.AddFunc(obj)
+		}
+	}
+}
+
+// AddIndexer registers an index. It must be called before Run.
+func (i *Informer[T]) AddIndexer(name string, fn IndexFunc[T]) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.indexers[name] = fn
+	i.indices[name] = make(map[string]map[string]struct{})
+}
+
+// HasSynced reports whether the initial list has been loaded into the cache.
+func (i *Informer[T]) HasSynced() bool {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.synced
+}
+
+// List returns every cached object.
+func (i *Informer[T]) List() []*T {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	out := make([]*T, 0, len(i.items))
+	for _, obj := range i.items {
+		out = append(out, obj)
+	}
+	return out
+}
+
+// Get returns the cached object with the given key.
+func (i *Informer[T]) Get(key string) (*T, bool) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	obj, ok := i.items[key]
+	return obj, ok
+}
+
+// ByIndex returns the cached objects whose index values include value.
+func (i *Informer[T]) ByIndex(name, value string) []*T {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	var out []*T
+	for key := range i.indices[name][value] {
+		out = append(out, i.items[key])
+	}
+	return out
+}
+
+// Run lists and watches until ctx is cancelled, relisting whenever the watch
+// can't be resumed.
+func (i *Informer[T]) Run(ctx context.Context) {
+	if i.resync > 0 {
+		go i.resyncLoop(ctx)
+	}
+
+	for {
+		err := i.listAndWatch(ctx)
+		if client.IsResourceExpired(err) {
+			// The watch fell behind the server's history; relist right away.
+			continue
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Informer: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (i *Informer[T]) listAndWatch(ctx context.Context) error {
+	items, resourceVersion, err := i.lw.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list failed: %v", err)
+	}
+	i.replace(items)
+
+	for ctx.Err() == nil {
+		w, err := i.lw.Watch(ctx, resourceVersion)
+		if err != nil {
+			return fmt.Errorf("watch failed: %v", err)
+		}
+		resourceVersion, err = i.consume(w, resourceVersion)
+		w.Stop()
+		if err != nil {
+			return err
+		}
+		// The stream ended cleanly; resume from the last version we saw.
+	}
+	return nil
+}
+
+// consume applies events to the cache until the stream ends. It returns the
+// last resourceVersion seen, or an error if the watch must be restarted from
+// a fresh list.
+func (i *Informer[T]) consume(w *client.Watcher[T], resourceVersion string) (string, error) {
+	for event := range w.ResultChan() {
+		if event.Type == client.Error {
+			return resourceVersion, event.Err()
+		}
+
+		key := MetaKey(any(event.Object))
+		switch event.Type {
+		case client.Added, client.Modified:
+			i.store(key, event.Object)
+		case client.Deleted:
+			i.remove(key)
+		}
+		if o, ok := any(event.Object).(api.Object); ok {
+			resourceVersion = o.GetObjectMeta().ResourceVersion
+		}
+	}
+	return resourceVersion, nil
+}
+
+// replace swaps in the result of a fresh list, notifying handlers about
+// whatever changed since the previous cache contents.
+func (i *Informer[T]) replace(items []T) {
+	fresh := make(map[string]*T, len(items))
+	for idx := range items {
+		fresh[MetaKey(any(&items[idx]))] = &items[idx]
+	}
+
+	i.lock.RLock()
+	var stale []*T
+	for key, obj := range i.items {
+		if _, ok := fresh[key]; !ok {
+			stale = append(stale, obj)
+		}
+	}
+	i.lock.RUnlock()
+
+	for key, obj := range fresh {
+		i.store(key, obj)
+	}
+	for _, obj := range stale {
+		i.remove(MetaKey(any(obj)))
+	}
+
+	i.lock.Lock()
+	i.synced = true
+	i.lock.Unlock()
+}
+
+func (i *Informer[T]) store(key string, obj *T) {
+	i.lock.Lock()
+	old, exists := i.items[key]
+	if exists {
+		i.unindex(key, old)
+	}
+	i.items[key] = obj
+	i.index(key, obj)
+	handlers := i.handlers
+	i.lock.Unlock()
+
+	if exists && resourceVersion(old) == resourceVersion(obj) {
+		return
+	}
+	for _, h := range handlers {
+		if !exists && h.AddFunc != nil {
+			h.AddFunc(obj)
+		} else if exists && h.UpdateFunc != nil {
+			h.UpdateFunc(old, obj)
+		}
+	}
+}
+
+// remove drops the object cached under key, and hands it to the delete
+// handlers. A key that isn't cached, e.g. one whose deletion was already seen
+// through a relist, notifies nobody.
+func (i *Informer[T]) remove(key string) {
+	i.lock.Lock()
+	old, ok := i.items[key]
+	if ok {
+		i.unindex(key, old)
+		delete(i.items, key)
+	}
+	handlers := i.handlers
+	i.lock.Unlock()
+
+	if !ok {
+		return
+	}
+	for _, h := range handlers {
+		if h.DeleteFunc != nil {
+			h.DeleteFunc(old)
+		}
+	}
+}
+
+// index and unindex must be called with the lock held.
+func (i *Informer[T]) index(key string, obj *T) {
+	for name, fn := range i.indexers {
+		for _, value := range fn(obj) {
+			if i.indices[name][value] == nil {
+				i.indices[name][value] = make(map[string]struct{})
+			}
+			i.indices[name][value][key] = struct{}{}
+		}
+	}
+}
+
+func (i *Informer[T]) unindex(key string, obj *T) {
+	for name, fn := range i.indexers {
+		for _, value := range fn(obj) {
+			delete(i.indices[name][value], key)
+			if len(i.indices[name][value]) == 0 {
+				delete(i.indices[name], value)
+			}
+		}
+	}
+}
+
+func (i *Informer[T]) resyncLoop(ctx context.Context) {
+	ticker := time.NewTicker(i.resync)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			i.lock.RLock()
+			items := make([]*T, 0, len(i.items))
+			for _, obj := range i.items {
+				items = append(items, obj)
+			}
+			handlers := i.handlers
+			i.lock.RUnlock()
+
+			for _, obj := range items {
+				for _, h := range handlers {
+					if h.UpdateFunc != nil {
+						h.UpdateFunc(obj, obj)
+					}
+				}
+			}
+		}
+	}
+}
+
+func resourceVersion(obj interface{}) string {
+	if o, ok := obj.(api.Object); ok {
+		return o.GetObjectMeta().ResourceVersion
+	}
+	return ""
+}
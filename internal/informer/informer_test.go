@@ -0,0 +1,188 @@
+package informer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/client"
+)
+
+// fakeAPI serves pods: lists return the current pods, and watches stream the
+// events the test sends, ending after an Error event.
+type fakeAPI struct {
+	mu        sync.Mutex
+	pods      []api.Pod
+	rv        string   // resourceVersion of the list
+	lists     int      // list requests served
+	watchRVs  []string // resourceVersion of each watch request
+	listGate  chan struct{}
+	watchEvts chan event
+}
+
+type event struct {
+	Type   client.EventType
+	Object interface{}
+}
+
+func newFakeAPI(rv string, pods ...api.Pod) *fakeAPI {
+	return &fakeAPI{pods: pods, rv: rv, listGate: make(chan struct{}), watchEvts: make(chan event)}
+}
+
+func (f *fakeAPI) setPods(rv string, pods ...api.Pod) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pods, f.rv = pods, rv
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("watch") != "true" {
+		<-f.listGate
+		f.mu.Lock()
+		f.lists++
+		list := api.PodList{Items: f.pods, ListMeta: api.ListMeta{ResourceVersion: f.rv}}
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	f.mu.Lock()
+	f.watchRVs = append(f.watchRVs, r.URL.Query().Get("resourceVersion"))
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-f.watchEvts:
+			json.NewEncoder(w).Encode(e)
+			w.(http.Flusher).Flush()
+			if e.Type == client.Error {
+				return
+			}
+		}
+	}
+}
+
+func pod(name, rv string) api.Pod {
+	return api.Pod{ObjectMeta: api.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: rv}}
+}
+
+// notification is a handler call, with the resourceVersions of its objects.
+type notification struct {
+	Type  string
+	Name  string
+	OldRV string
+	RV    string
+}
+
+func recordEvents(inf *Informer[api.Pod]) <-chan notification {
+	ch := make(chan notification, 100)
+	inf.AddEventHandler(ResourceEventHandler[api.Pod]{
+		AddFunc: func(p *api.Pod) { ch <- notification{"add", p.Name, "", p.ResourceVersion} },
+		UpdateFunc: func(old, p *api.Pod) {
+			ch <- notification{"update", p.Name, old.ResourceVersion, p.ResourceVersion}
+		},
+		DeleteFunc: func(p *api.Pod) { ch <- notification{"delete", p.Name, "", p.ResourceVersion} },
+	})
+	return ch
+}
+
+// expect fails the test unless the next notifications are want, in any order.
+func expect(t *testing.T, ch <-chan notification, want ...notification) {
+	t.Helper()
+	pending := make(map[notification]int)
+	for _, n := range want {
+		pending[n]++
+	}
+	for range want {
+		select {
+		case n := <-ch:
+			if pending[n] == 0 {
+				t.Fatalf("got %+v, want one of %+v", n, pending)
+			}
+			pending[n]--
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %+v", pending)
+		}
+	}
+}
+
+func TestInformer(t *testing.T) {
+	fake := newFakeAPI("2", pod("a", "1"), pod("b", "2"))
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &client.Client{BaseURL: srv.URL, HTTP: srv.Client()}
+
+	inf := New[api.Pod](c.PodListWatch(api.NamespaceAll, client.ListOptions{}), 0)
+	events := recordEvents(inf)
+	go inf.Run(ctx)
+
+	// Not synced until the initial list is in.
+	time.Sleep(50 * time.Millisecond)
+	if inf.HasSynced() {
+		t.Fatal("synced before the list was served")
+	}
+	close(fake.listGate)
+	syncCtx, syncCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer syncCancel()
+	if !WaitForCacheSync(syncCtx, inf.HasSynced) {
+		t.Fatal("informer did not sync")
+	}
+	expect(t, events, notification{"add", "a", "", "1"}, notification{"add", "b", "", "2"})
+	if got := len(inf.List()); got != 2 {
+		t.Errorf("got %d cached pods, want 2", got)
+	}
+
+	// Handlers added after the sync get the cache replayed as adds.
+	late := recordEvents(inf)
+	expect(t, late, notification{"add", "a", "", "1"}, notification{"add", "b", "", "2"})
+
+	a3 := pod("a", "3")
+	fake.watchEvts <- event{client.Modified, a3}
+	expect(t, events, notification{"update", "a", "1", "3"})
+	if got, ok := inf.Get("default/a"); !ok || got.ResourceVersion != "3" {
+		t.Errorf("cached a: got %+v, want resourceVersion 3", got)
+	}
+
+	// Delete handlers get the object as it was cached.
+	fake.watchEvts <- event{client.Deleted, pod("b", "4")}
+	expect(t, events, notification{"delete", "b", "", "2"})
+	if _, ok := inf.Get("default/b"); ok {
+		t.Error("b is still cached after its deletion")
+	}
+	// Deleting what isn't cached notifies nobody; the check for unexpected
+	// notifications at the end covers it.
+	fake.watchEvts <- event{client.Deleted, pod("x", "5")}
+
+	// The watch expires: relist, and notify only about what changed.
+	fake.setPods("6", a3, pod("c", "6"))
+	fake.watchEvts <- event{client.Error, api.Status{Status: api.StatusFailure, Reason: api.StatusReasonExpired, Code: http.StatusGone}}
+	expect(t, events, notification{"add", "c", "", "6"})
+
+	// Wait for the watch after the relist, then check the requests made.
+	fake.watchEvts <- event{client.Added, pod("d", "7")}
+	expect(t, events, notification{"add", "d", "", "7"})
+	fake.mu.Lock()
+	lists, watchRVs := fake.lists, fake.watchRVs
+	fake.mu.Unlock()
+	if lists != 2 {
+		t.Errorf("got %d lists, want 2", lists)
+	}
+	if len(watchRVs) != 2 || watchRVs[0] != "2" || watchRVs[1] != "6" {
+		t.Errorf("watches started from %v, want [2 6]", watchRVs)
+	}
+	select {
+	case n := <-events:
+		t.Errorf("unexpected %+v", n)
+	default:
+	}
+}
@@ -2,13 +2,14 @@ package kubelet
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"reflect"
 	"strings"
 	"time"
 
 	"github.com/abhigod/k8s-lite/internal/api"
 	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/informer"
 )
 
 type Agent struct {
@@ -17,17 +18,24 @@ type Agent struct {
 	Runtime  Runtime
 	Prober   Prober
 
+	pods *informer.Informer[api.Pod] // only pods bound to this node
+	kick chan struct{}
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
 func NewAgent(nodeName string, apiURL, tlsCert, tlsKey, tlsCA string) *Agent {
 	ctx, cancel := context.WithCancel(context.Background())
+	cli := client.New(apiURL, tlsCert, tlsKey, tlsCA)
+	onNode := client.ListOptions{FieldSelector: "spec.nodeName=" + nodeName}
 	return &Agent{
 		NodeName: nodeName,
-		Client:   client.New(apiURL, tlsCert, tlsKey, tlsCA),
+		Client:   cli,
 		Runtime:  NewDockerRuntime(),
 		Prober:   NewProber(),
+		pods:     informer.New[api.Pod](cli.PodListWatch(api.NamespaceAll, onNode), 0),
+		kick:     make(chan struct{}, 1),
 		ctx:      ctx,
 		cancel:   cancel,
 	}
@@ -41,7 +49,24 @@ func (a *Agent) Start() error {
 		return err
 	}
 
-	// 2. Start Sync Loop
+	// 2. Watch pods bound to this node; the API server filters out the rest.
+	// Spec changes trigger an immediate sync; status changes are usually our
+	// own writes and can wait for the next tick.
+	a.pods.AddEventHandler(informer.ResourceEventHandler[api.Pod]{
+		AddFunc: func(_ *api.Pod) { a.trigger() },
+		UpdateFunc: func(old, pod *api.Pod) {
+			if !reflect.DeepEqual(old.Spec, pod.Spec) {
+				a.trigger()
+			}
+		},
+		DeleteFunc: func(_ *api.Pod) { a.trigger() },
+	})
+	go a.pods.Run(a.ctx)
+	if !informer.WaitForCacheSync(a.ctx, a.pods.HasSynced) {
+		return nil
+	}
+
+	// 3. Start Sync Loop
 	go a.syncLoop()
 
 	<-a.ctx.Done()
@@ -67,7 +92,16 @@ func (a *Agent) registerNode() error {
 	return a.Client.RegisterNode(a.ctx, node)
 }
 
+// trigger requests a sync without blocking.
+func (a *Agent) trigger() {
+	select {
+	case a.kick <- struct{}{}:
+	default:
+	}
+}
+
 func (a *Agent) syncLoop() {
+	// The ticker still drives syncs so we notice containers exiting and failing probes.
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
@@ -77,24 +111,18 @@ func (a *Agent) syncLoop() {
 			return
 		case <-ticker.C:
 			a.runSync()
+		case <-a.kick:
+			a.runSync()
 		}
 	}
 }
 
 func (a *Agent) runSync() {
-	// 1. Get Desired State (Pods from API)
-	pods, err := a.Client.ListPods(a.ctx, a.NodeName)
-	if err != nil {
-		log.Printf("Error listing pods: %v", err)
-		return
-	}
-
-	// Filter for my node
+	// 1. Get Desired State (Pods on this node from the informer cache)
+	// Copying since cached objects are shared.
 	var myPods []api.Pod
-	for _, p := range pods {
-		if p.Spec.NodeName == a.NodeName {
-			myPods = append(myPods, p)
-		}
+	for _, p := range a.pods.List() {
+		myPods = append(myPods, *p)
 	}
 
 	// 2. Get Actual State (Containers from Runtime)
@@ -106,25 +134,27 @@ func (a *Agent) runSync() {
 	// Debug log
 	log.Printf("Found %d containers from runtime", len(containers))
 
-	// Map of running pods (podName -> []ContainerInfo)
+	// Map of running pods (namespace/podName -> []ContainerInfo)
 	runningPods := make(map[string][]ContainerInfo)
 	for _, c := range containers {
-		runningPods[c.PodName] = append(runningPods[c.PodName], c)
+		key := c.PodNamespace + "/" + c.PodName
+		runningPods[key] = append(runningPods[key], c)
 	}
 
 	// 3. Reconcile
 
 	// A. Create/Start missing pods
 	for _, pod := range myPods {
-		a.reconcilePod(&pod, runningPods[pod.Name])
-		delete(runningPods, pod.Name) // Mark as handled
+		key := pod.Namespace + "/" + pod.Name
+		a.reconcilePod(&pod, runningPods[key])
+		delete(runningPods, key) // Mark as handled
 	}
 
 	// B. Delete extra pods (that are no longer assigned to us)
-	for podName, containers := range runningPods {
+	for podKey, containers := range runningPods {
 		// Only if it looks like a k8s pod (has podName)
-		if podName != "" {
-			log.Printf("Pod %s no longer assigned, cleaning up %d containers", podName, len(containers))
+		if containers[0].PodName != "" {
+			log.Printf("Pod %s no longer assigned, cleaning up %d containers", podKey, len(containers))
 			for _, c := range containers {
 				a.Runtime.StopContainer(a.ctx, c.ID, 0) // Force kill/immediate for cleanup
 			}
@@ -139,11 +169,12 @@ func (a *Agent) reconcilePod(pod *api.Pod, runningContainers []ContainerInfo) {
 
 	// We can assume Running if we find the container.
 	// If not found, Pending?
+	oldPhase, oldIP := pod.Status.Phase, pod.Status.PodIP
 
 	for _, specContainer := range pod.Spec.Containers {
 		found := false
 		for _, rc := range runningContainers {
-			expectedName := fmt.Sprintf("k8s-lite-%s-%s", pod.Name, specContainer.Name)
+			expectedName := ContainerName(pod, &specContainer)
 			// log.Printf("Checking %s vs %s", rc.Name, expectedName)
 			if rc.Name == expectedName {
 				found = true
@@ -190,10 +221,8 @@ func (a *Agent) reconcilePod(pod *api.Pod, runningContainers []ContainerInfo) {
 		newPhase = "Running"
 	}
 
-	// Only update if changed (simple cache/check logic?)
-	// For MVP, just update periodically or check if pod.Status.Phase != newPhase
-	// Also check if PodIP changed
-	if pod.Status.Phase != newPhase || pod.Status.PodIP != "" {
+	// Only update if the phase or PodIP changed; every write fans out to all watchers.
+	if oldPhase != newPhase || oldIP != pod.Status.PodIP {
 		pod.Status.Phase = newPhase
 		if err := a.Client.UpdatePodStatus(a.ctx, pod); err != nil {
 			log.Printf("Failed to update pod status: %v", err)
@@ -202,6 +231,3 @@ func (a *Agent) reconcilePod(pod *api.Pod, runningContainers []ContainerInfo) {
 		}
 	}
 }
-
-
-
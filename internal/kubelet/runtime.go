@@ -26,6 +26,12 @@ type ContainerInfo struct {
 	PodNamespace string
 }
 
+// ContainerName returns the runtime name of a pod's container:
+// k8s-lite-<namespace>-<podName>-<containerName>.
+func ContainerName(pod *api.Pod, container *api.Container) string {
+	return fmt.Sprintf("k8s-lite-%s-%s-%s", pod.Namespace, pod.Name, container.Name)
+}
+
 // DockerRuntime implements Runtime using the 'docker' CLI.
 type DockerRuntime struct{}
 
@@ -34,8 +40,7 @@ func NewDockerRuntime() *DockerRuntime {
 }
 
 func (d *DockerRuntime) RunContainer(ctx context.Context, pod *api.Pod, container *api.Container) (string, error) {
-	// k8s-lite-podName-containerName
-	containerName := fmt.Sprintf("k8s-lite-%s-%s", pod.Name, container.Name)
+	containerName := ContainerName(pod, container)
 
 	// Check if running
 	// For simplicity, always remove and recreate if not restart=Never?
@@ -144,8 +149,3 @@ func (d *DockerRuntime) GetContainerIP(ctx context.Context, containerID string)
 	}
 	return strings.TrimSpace(string(out)), nil
 }
-
-
-
-
-
@@ -0,0 +1,60 @@
+package labels
+
+import (
+	"fmt"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// SelectorFromLabelSelector converts the LabelSelector of an API object.
+// MatchLabels and MatchExpressions are combined with AND. As in Kubernetes,
+// a selector with neither selects everything; controllers check Empty before
+// acting on one so they never adopt every pod in a namespace.
+func SelectorFromLabelSelector(ls api.LabelSelector) (Selector, error) {
+	sel := SelectorFromSet(ls.MatchLabels)
+	for _, r := range sel {
+		if err := validateKey(r.Key); err != nil {
+			return nil, fmt.Errorf("matchLabels: %v", err)
+		}
+		if err := validateValue(r.Values[0]); err != nil {
+			return nil, fmt.Errorf("matchLabels: %v", err)
+		}
+	}
+
+	for i, expr := range ls.MatchExpressions {
+		r := Requirement{Key: expr.Key, Values: expr.Values}
+		switch expr.Operator {
+		case api.LabelSelectorOpIn:
+			r.Operator = In
+		case api.LabelSelectorOpNotIn:
+			r.Operator = NotIn
+		case api.LabelSelectorOpExists:
+			r.Operator = Exists
+		case api.LabelSelectorOpDoesNotExist:
+			r.Operator = DoesNotExist
+		default:
+			return nil, fmt.Errorf("matchExpressions[%d]: unknown operator %q", i, expr.Operator)
+		}
+
+		if err := validateKey(r.Key); err != nil {
+			return nil, fmt.Errorf("matchExpressions[%d]: %v", i, err)
+		}
+		switch r.Operator {
+		case In, NotIn:
+			if len(r.Values) == 0 {
+				return nil, fmt.Errorf("matchExpressions[%d]: values must be non-empty for operator %s", i, expr.Operator)
+			}
+		default:
+			if len(r.Values) > 0 {
+				return nil, fmt.Errorf("matchExpressions[%d]: values must be empty for operator %s", i, expr.Operator)
+			}
+		}
+		for _, v := range r.Values {
+			if err := validateValue(v); err != nil {
+				return nil, fmt.Errorf("matchExpressions[%d]: %v", i, err)
+			}
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
@@ -0,0 +1,156 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+func TestSelectorFromLabelSelector(t *testing.T) {
+	tests := []struct {
+		name string
+		ls   api.LabelSelector
+		want string
+	}{
+		{name: "empty", ls: api.LabelSelector{}, want: ""},
+		{
+			name: "matchLabels",
+			ls:   api.LabelSelector{MatchLabels: map[string]string{"tier": "frontend", "app": "web"}},
+			want: "app=web,tier=frontend",
+		},
+		{
+			name: "matchExpressions",
+			ls: api.LabelSelector{MatchExpressions: []api.LabelSelectorRequirement{
+				{Key: "tier", Operator: api.LabelSelectorOpIn, Values: []string{"frontend", "backend"}},
+				{Key: "track", Operator: api.LabelSelectorOpNotIn, Values: []string{"canary"}},
+				{Key: "app", Operator: api.LabelSelectorOpExists},
+				{Key: "legacy", Operator: api.LabelSelectorOpDoesNotExist},
+			}},
+			want: "tier in (frontend,backend),track notin (canary),app,!legacy",
+		},
+		{
+			name: "both",
+			ls: api.LabelSelector{
+				MatchLabels:      map[string]string{"app": "web"},
+				MatchExpressions: []api.LabelSelectorRequirement{{Key: "tier", Operator: api.LabelSelectorOpIn, Values: []string{"frontend"}}},
+			},
+			want: "app=web,tier in (frontend)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := SelectorFromLabelSelector(tt.ls)
+			if err != nil {
+				t.Fatalf("SelectorFromLabelSelector: %v", err)
+			}
+			if got := sel.String(); got != tt.want {
+				t.Errorf("selector = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectorFromLabelSelectorErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		ls   api.LabelSelector
+	}{
+		{name: "invalid matchLabels key", ls: api.LabelSelector{MatchLabels: map[string]string{"-app": "web"}}},
+		{name: "invalid matchLabels value", ls: api.LabelSelector{MatchLabels: map[string]string{"app": "web!"}}},
+		{
+			name: "unknown operator",
+			ls:   api.LabelSelector{MatchExpressions: []api.LabelSelectorRequirement{{Key: "app", Operator: "Equals", Values: []string{"web"}}}},
+		},
+		{
+			name: "lowercase operator",
+			ls:   api.LabelSelector{MatchExpressions: []api.LabelSelectorRequirement{{Key: "app", Operator: "in", Values: []string{"web"}}}},
+		},
+		{
+			name: "In without values",
+			ls:   api.LabelSelector{MatchExpressions: []api.LabelSelectorRequirement{{Key: "app", Operator: api.LabelSelectorOpIn}}},
+		},
+		{
+			name: "NotIn without values",
+			ls:   api.LabelSelector{MatchExpressions: []api.LabelSelectorRequirement{{Key: "app", Operator: api.LabelSelectorOpNotIn}}},
+		},
+		{
+			name: "Exists with values",
+			ls:   api.LabelSelector{MatchExpressions: []api.LabelSelectorRequirement{{Key: "app", Operator: api.LabelSelectorOpExists, Values: []string{"web"}}}},
+		},
+		{
+			name: "DoesNotExist with values",
+			ls:   api.LabelSelector{MatchExpressions: []api.LabelSelectorRequirement{{Key: "app", Operator: api.LabelSelectorOpDoesNotExist, Values: []string{"web"}}}},
+		},
+		{
+			name: "invalid expression key",
+			ls:   api.LabelSelector{MatchExpressions: []api.LabelSelectorRequirement{{Key: "", Operator: api.LabelSelectorOpExists}}},
+		},
+		{
+			name: "invalid expression value",
+			ls:   api.LabelSelector{MatchExpressions: []api.LabelSelectorRequirement{{Key: "app", Operator: api.LabelSelectorOpIn, Values: []string{"web", "-db"}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sel, err := SelectorFromLabelSelector(tt.ls); err == nil {
+				t.Errorf("SelectorFromLabelSelector(%+v) = %q, want an error", tt.ls, sel)
+			}
+		})
+	}
+}
+
+// An empty LabelSelector selects everything, which is why controllers refuse
+// to act on one; Nothing is the selector that selects no objects at all.
+func TestLabelSelectorEmptyVersusNothing(t *testing.T) {
+	pod := map[string]string{"app": "web"}
+
+	for _, ls := range []api.LabelSelector{{}, {MatchLabels: map[string]string{}}, {MatchExpressions: []api.LabelSelectorRequirement{}}} {
+		sel, err := SelectorFromLabelSelector(ls)
+		if err != nil {
+			t.Fatalf("SelectorFromLabelSelector(%+v): %v", ls, err)
+		}
+		if !sel.Empty() {
+			t.Errorf("SelectorFromLabelSelector(%+v) is not Empty", ls)
+		}
+		if !sel.Matches(pod) || !sel.Matches(nil) {
+			t.Errorf("SelectorFromLabelSelector(%+v) does not match everything", ls)
+		}
+	}
+
+	if Nothing().Matches(pod) || Nothing().Matches(nil) {
+		t.Error("Nothing() matches a pod")
+	}
+}
+
+func TestLabelSelectorMatches(t *testing.T) {
+	ls := api.LabelSelector{
+		MatchLabels: map[string]string{"app": "web"},
+		MatchExpressions: []api.LabelSelectorRequirement{
+			{Key: "tier", Operator: api.LabelSelectorOpIn, Values: []string{"frontend", "backend"}},
+			{Key: "track", Operator: api.LabelSelectorOpNotIn, Values: []string{"canary"}},
+		},
+	}
+	sel, err := SelectorFromLabelSelector(ls)
+	if err != nil {
+		t.Fatalf("SelectorFromLabelSelector: %v", err)
+	}
+
+	tests := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{labels: map[string]string{"app": "web", "tier": "frontend"}, want: true},
+		{labels: map[string]string{"app": "web", "tier": "backend", "track": "stable"}, want: true},
+		{labels: map[string]string{"app": "web", "tier": "frontend", "track": "canary"}, want: false},
+		{labels: map[string]string{"app": "web", "tier": "cache"}, want: false},
+		{labels: map[string]string{"app": "web"}, want: false},
+		{labels: map[string]string{"tier": "frontend"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := sel.Matches(tt.labels); got != tt.want {
+			t.Errorf("%q matches %v = %v, want %v", sel, tt.labels, got, tt.want)
+		}
+	}
+}
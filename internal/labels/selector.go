@@ -0,0 +1,237 @@
+// Package labels implements label selectors, as used by the labelSelector
+// query parameter, e.g. "app=web,tier in (frontend,backend),!canary".
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Operator is the relation a Requirement checks.
+type Operator string
+
+const (
+	Equals       Operator = "="
+	DoubleEquals Operator = "=="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+
+	// nothing is never satisfied; it backs the selector returned by Nothing.
+	nothing Operator = ""
+)
+
+// Requirement is a single condition on one label.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Matches reports whether labels satisfy the requirement. Like in Kubernetes,
+// != and notin also match objects that don't have the label at all.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case Equals, DoubleEquals, In:
+		return ok && contains(r.Values, value)
+	case NotEquals, NotIn:
+		return !ok || !contains(r.Values, value)
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	}
+	return r.Key + string(r.Operator) + strings.Join(r.Values, "")
+}
+
+// Selector is a list of requirements that must all hold.
+// An empty Selector matches everything.
+type Selector []Requirement
+
+// Everything returns a selector that matches all objects.
+func Everything() Selector {
+	return nil
+}
+
+// Nothing returns a selector that matches no objects. Unlike an empty
+// selector it is not Empty, and it renders as "".
+func Nothing() Selector {
+	return Selector{{Operator: nothing}}
+}
+
+// SelectorFromSet returns a selector requiring every label in set to have the
+// given value.
+func SelectorFromSet(set map[string]string) Selector {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sel := make(Selector, 0, len(keys))
+	for _, k := range keys {
+		sel = append(sel, Requirement{Key: k, Operator: Equals, Values: []string{set[k]}})
+	}
+	return sel
+}
+
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+func (s Selector) String() string {
+	terms := make([]string, len(s))
+	for i, r := range s {
+		terms[i] = r.String()
+	}
+	return strings.Join(terms, ",")
+}
+
+// Parse parses a selector of comma-separated requirements:
+//
+//	key=value, key==value, key!=value
+//	key in (v1,v2), key notin (v1,v2)
+//	key, !key
+func Parse(selector string) (Selector, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, nil
+	}
+
+	var sel Selector
+	for _, term := range splitTerms(selector) {
+		r, err := parseRequirement(strings.TrimSpace(term))
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// splitTerms splits on the commas that aren't inside a value list.
+func splitTerms(selector string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	if term == "" {
+		return Requirement{}, fmt.Errorf("empty requirement")
+	}
+
+	if strings.HasPrefix(term, "!") {
+		r := Requirement{Key: strings.TrimSpace(term[1:]), Operator: DoesNotExist}
+		return r, validateKey(r.Key)
+	}
+
+	if open := strings.Index(term, "("); open >= 0 {
+		if !strings.HasSuffix(term, ")") {
+			return Requirement{}, fmt.Errorf("missing ')' in %q", term)
+		}
+		head := strings.Fields(term[:open])
+		if len(head) != 2 || (head[1] != string(In) && head[1] != string(NotIn)) {
+			return Requirement{}, fmt.Errorf("expected 'key in (...)' or 'key notin (...)', got %q", term)
+		}
+		r := Requirement{Key: head[0], Operator: Operator(head[1])}
+		if err := validateKey(r.Key); err != nil {
+			return Requirement{}, err
+		}
+		values := term[open+1 : len(term)-1]
+		if strings.TrimSpace(values) == "" {
+			return Requirement{}, fmt.Errorf("empty value set in %q", term)
+		}
+		for _, v := range strings.Split(values, ",") {
+			v = strings.TrimSpace(v)
+			if err := validateValue(v); err != nil {
+				return Requirement{}, err
+			}
+			r.Values = append(r.Values, v)
+		}
+		return r, nil
+	}
+
+	for _, op := range []Operator{NotEquals, DoubleEquals, Equals} {
+		if i := strings.Index(term, string(op)); i >= 0 {
+			r := Requirement{
+				Key:      strings.TrimSpace(term[:i]),
+				Operator: op,
+				Values:   []string{strings.TrimSpace(term[i+len(op):])},
+			}
+			if err := validateKey(r.Key); err != nil {
+				return Requirement{}, err
+			}
+			return r, validateValue(r.Values[0])
+		}
+	}
+
+	r := Requirement{Key: term, Operator: Exists}
+	return r, validateKey(r.Key)
+}
+
+var (
+	keyPattern   = regexp.MustCompile(`^([A-Za-z0-9.-]+/)?[A-Za-z0-9]([A-Za-z0-9_.-]*[A-Za-z0-9])?$`)
+	valuePattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9_.-]*[A-Za-z0-9])?)?$`)
+)
+
+func validateKey(key string) error {
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	return nil
+}
+
+func validateValue(value string) error {
+	if len(value) > 63 || !valuePattern.MatchString(value) {
+		return fmt.Errorf("invalid label value %q", value)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
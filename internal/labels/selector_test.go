@@ -0,0 +1,180 @@
+package labels
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     Selector
+	}{
+		{selector: "", want: nil},
+		{selector: "   ", want: nil},
+		{selector: "app=web", want: Selector{{Key: "app", Operator: Equals, Values: []string{"web"}}}},
+		{selector: "app==web", want: Selector{{Key: "app", Operator: DoubleEquals, Values: []string{"web"}}}},
+		{selector: "app!=web", want: Selector{{Key: "app", Operator: NotEquals, Values: []string{"web"}}}},
+		{selector: "app=", want: Selector{{Key: "app", Operator: Equals, Values: []string{""}}}},
+		{
+			selector: "tier in (frontend, backend)",
+			want:     Selector{{Key: "tier", Operator: In, Values: []string{"frontend", "backend"}}},
+		},
+		{
+			selector: "tier notin (cache)",
+			want:     Selector{{Key: "tier", Operator: NotIn, Values: []string{"cache"}}},
+		},
+		{selector: "canary", want: Selector{{Key: "canary", Operator: Exists}}},
+		{selector: "!canary", want: Selector{{Key: "canary", Operator: DoesNotExist}}},
+		{
+			selector: "app=web, tier in (frontend,backend), !canary, example.com/team",
+			want: Selector{
+				{Key: "app", Operator: Equals, Values: []string{"web"}},
+				{Key: "tier", Operator: In, Values: []string{"frontend", "backend"}},
+				{Key: "canary", Operator: DoesNotExist},
+				{Key: "example.com/team", Operator: Exists},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.selector, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %#v, want %#v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+	}{
+		{name: "empty key", selector: "=web"},
+		{name: "empty key after not", selector: "!"},
+		{name: "empty term", selector: "app=web,"},
+		{name: "unclosed paren", selector: "tier in (frontend,backend"},
+		{name: "empty value set", selector: "tier in ()"},
+		{name: "blank value set", selector: "tier notin ( )"},
+		{name: "unknown set operator", selector: "tier within (frontend)"},
+		{name: "missing set operator", selector: "tier (frontend)"},
+		{name: "invalid key", selector: "-app=web"},
+		{name: "invalid value", selector: "app=-web"},
+		{name: "value too long", selector: "app=" + string(make([]byte, 64))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sel, err := Parse(tt.selector); err == nil {
+				t.Errorf("Parse(%q) = %v, want an error", tt.selector, sel)
+			}
+		})
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	web := map[string]string{"app": "web", "tier": "frontend"}
+	tests := []struct {
+		selector string
+		labels   map[string]string
+		want     bool
+	}{
+		{selector: "", labels: web, want: true},
+		{selector: "", labels: nil, want: true},
+		{selector: "app=web", labels: web, want: true},
+		{selector: "app==web", labels: web, want: true},
+		{selector: "app=db", labels: web, want: false},
+		{selector: "app=web", labels: nil, want: false},
+		{selector: "app!=db", labels: web, want: true},
+		{selector: "app!=web", labels: web, want: false},
+		// != and notin match objects without the label.
+		{selector: "track!=canary", labels: web, want: true},
+		{selector: "track notin (canary)", labels: web, want: true},
+		{selector: "track!=canary", labels: nil, want: true},
+		{selector: "tier in (frontend,backend)", labels: web, want: true},
+		{selector: "tier in (backend)", labels: web, want: false},
+		{selector: "track in (stable)", labels: web, want: false},
+		{selector: "tier notin (frontend)", labels: web, want: false},
+		{selector: "tier", labels: web, want: true},
+		{selector: "track", labels: web, want: false},
+		{selector: "!track", labels: web, want: true},
+		{selector: "!tier", labels: web, want: false},
+		{selector: "app=web,tier in (backend)", labels: web, want: false},
+		{selector: "app=web,!track,tier notin (backend)", labels: web, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := Parse(tt.selector)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.selector, err)
+			}
+			if got := sel.Matches(tt.labels); got != tt.want {
+				t.Errorf("%q matches %v = %v, want %v", tt.selector, tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEverythingAndNothing(t *testing.T) {
+	for _, l := range []map[string]string{nil, {}, {"app": "web"}} {
+		if !Everything().Matches(l) {
+			t.Errorf("Everything() does not match %v", l)
+		}
+		if Nothing().Matches(l) {
+			t.Errorf("Nothing() matches %v", l)
+		}
+	}
+
+	if !Everything().Empty() {
+		t.Error("Everything() is not Empty")
+	}
+	if Nothing().Empty() {
+		t.Error("Nothing() is Empty")
+	}
+	if s := Nothing().String(); s != "" {
+		t.Errorf("Nothing().String() = %q, want \"\"", s)
+	}
+}
+
+func TestSelectorFromSet(t *testing.T) {
+	sel := SelectorFromSet(map[string]string{"tier": "frontend", "app": "web"})
+	if got, want := sel.String(), "app=web,tier=frontend"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if !sel.Matches(map[string]string{"app": "web", "tier": "frontend", "track": "stable"}) {
+		t.Error("selector does not match a superset of its labels")
+	}
+	if sel.Matches(map[string]string{"app": "web"}) {
+		t.Error("selector matches a subset of its labels")
+	}
+	if !SelectorFromSet(nil).Empty() {
+		t.Error("SelectorFromSet(nil) is not Empty")
+	}
+}
+
+func TestSelectorStringRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"app=web",
+		"app==web",
+		"app!=web",
+		"tier in (frontend,backend)",
+		"tier notin (cache)",
+		"canary",
+		"!canary",
+		"app=web,tier in (frontend),!canary",
+	} {
+		sel, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", s, err)
+		}
+		if got := sel.String(); got != s {
+			t.Errorf("Parse(%q).String() = %q", s, got)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 )
 
 type Config struct {
+	LockNamespace string // defaults to the default namespace
 	LockName      string
 	Identity      string
 	LeaseDuration time.Duration
@@ -90,7 +91,7 @@ func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) error {
 	client := le.config.Client
 	now := time.Now()
 
-	lease, err := client.GetLease(ctx, le.config.LockName)
+	lease, err := client.GetLease(ctx, le.config.LockNamespace, le.config.LockName)
 	if err != nil {
 		return err
 	}
@@ -99,7 +100,8 @@ func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) error {
 		// If not found, create it
 		lease = &api.Lease{
 			ObjectMeta: api.ObjectMeta{
-				Name: le.config.LockName,
+				Name:      le.config.LockName,
+				Namespace: le.config.LockNamespace,
 			},
 			Spec: api.LeaseSpec{
 				HolderIdentity:       &le.config.Identity,
@@ -129,11 +131,9 @@ func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) error {
 		}
 	}
 
-	// Lease expired or empty, acquire it
-	// We need to be careful with resource version (optimistic locking)
-	// client.UpdateLease handles checking ResourceVersion if we passed it back from GetLease
-	// (Our simple store/client might not enforce it strictly unless we implemented it fully in storage layer,
-	// but standard mechanism is to send back ResourceVersion)
+	// Lease expired or empty, acquire it.
+	// The lease still carries the ResourceVersion we read in GetLease, so if another
+	// candidate grabbed it in the meantime the API server rejects this update with a Conflict.
 
 	lease.Spec.HolderIdentity = &le.config.Identity
 	lease.Spec.AcquireTime = &now
@@ -146,6 +146,3 @@ func (le *LeaderElector) tryAcquireOrRenew(ctx context.Context) error {
 }
 
 func int32Ptr(i int32) *int32 { return &i }
-
-
-
@@ -12,6 +12,7 @@ import (
 
 	"github.com/abhigod/k8s-lite/internal/api"
 	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/informer"
 )
 
 type Proxier struct {
@@ -22,28 +23,44 @@ type Proxier struct {
 	lock      sync.Mutex
 
 	// Cache
-	endpoints map[string]*api.Endpoints // ServiceName -> Endpoints
+	informers *informer.Factory
+	services  *informer.Informer[api.Service]
+	endpoints *informer.Informer[api.Endpoints] // keyed like the Service they belong to
+	kick      chan struct{}
 }
 
 func NewProxier(client *client.Client) *Proxier {
+	informers := informer.NewFactory(client, 30*time.Second)
 	return &Proxier{
 		Client:    client,
 		listeners: make(map[int32]net.Listener),
-		endpoints: make(map[string]*api.Endpoints),
+		informers: informers,
+		services:  informers.Services(),
+		endpoints: informers.Endpoints(),
+		kick:      make(chan struct{}, 1),
 	}
 }
 
 func (p *Proxier) Run(ctx context.Context) {
 	log.Println("Starting K8s-Lite Proxy...")
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+
+	// Listeners only depend on Services; Endpoints are read from the cache per connection.
+	p.services.AddEventHandler(informer.ResourceEventHandler[api.Service]{
+		AddFunc:    func(*api.Service) { p.trigger() },
+		UpdateFunc: func(_, _ *api.Service) { p.trigger() },
+		DeleteFunc: func(*api.Service) { p.trigger() },
+	})
+	p.informers.Start(ctx)
+	if !p.informers.WaitForCacheSync(ctx) {
+		return
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			p.closeAll()
 			return
-		case <-ticker.C:
+		case <-p.kick:
 			if err := p.sync(ctx); err != nil {
 				log.Printf("Proxy Sync Error: %v", err)
 			}
@@ -51,32 +68,22 @@ func (p *Proxier) Run(ctx context.Context) {
 	}
 }
 
-func (p *Proxier) sync(ctx context.Context) error {
-	// 1. Update Endpoints Cache
-	// In real K8s, we'd watch. Here we list all endpoints.
-	// Actually, we iterate services to know what ports to open,
-	// and we need endpoints for those services.
-
-	services, err := p.Client.ListServices(ctx)
-	if err != nil {
-		return err
+// trigger requests a sync without blocking.
+func (p *Proxier) trigger() {
+	select {
+	case p.kick <- struct{}{}:
+	default:
 	}
+}
 
-	// Build map of desired NodePorts
-	desiredPorts := make(map[int32]string) // port -> serviceName
+func (p *Proxier) sync(ctx context.Context) error {
+	// Build map of desired NodePorts from the Service cache
+	desiredPorts := make(map[int32]string) // port -> service key
 
-	for _, svc := range services {
+	for _, svc := range p.services.List() {
 		for _, port := range svc.Spec.Ports {
 			if port.NodePort != 0 {
-				desiredPorts[port.NodePort] = svc.Name
-
-				// Update cache for this service
-				ep, err := p.Client.GetEndpoints(ctx, svc.Name)
-				if err == nil && ep != nil {
-					p.lock.Lock()
-					p.endpoints[svc.Name] = ep
-					p.lock.Unlock()
-				}
+				desiredPorts[port.NodePort] = informer.MetaKey(svc)
 			}
 		}
 	}
@@ -85,16 +92,16 @@ func (p *Proxier) sync(ctx context.Context) error {
 	defer p.lock.Unlock()
 
 	// 2. Open new listeners
-	for port, svcName := range desiredPorts {
+	for port, svcKey := range desiredPorts {
 		if _, exists := p.listeners[port]; !exists {
-			log.Printf("Opening Proxy Listener for Service %s on :%d", svcName, port)
+			log.Printf("Opening Proxy Listener for Service %s on :%d", svcKey, port)
 			ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 			if err != nil {
 				log.Printf("Failed to listen on :%d: %v", port, err)
 				continue
 			}
 			p.listeners[port] = ln
-			go p.serve(ln, svcName)
+			go p.serve(ln, svcKey)
 		}
 	}
 
@@ -110,26 +117,24 @@ func (p *Proxier) sync(ctx context.Context) error {
 	return nil
 }
 
-func (p *Proxier) serve(ln net.Listener, svcName string) {
+func (p *Proxier) serve(ln net.Listener, svcKey string) {
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
 			return // Listener closed
 		}
-		go p.handleConnection(conn, svcName)
+		go p.handleConnection(conn, svcKey)
 	}
 }
 
-func (p *Proxier) handleConnection(inConn net.Conn, svcName string) {
+func (p *Proxier) handleConnection(inConn net.Conn, svcKey string) {
 	defer inConn.Close()
 
 	// Pick backend
-	p.lock.Lock()
-	ep, ok := p.endpoints[svcName]
-	p.lock.Unlock()
+	ep, ok := p.endpoints.Get(svcKey)
 
 	if !ok || len(ep.Subsets) == 0 || len(ep.Subsets[0].Addresses) == 0 {
-		log.Printf("No endpoints for %s, closing connection", svcName)
+		log.Printf("No endpoints for %s, closing connection", svcKey)
 		return
 	}
 
@@ -155,7 +160,7 @@ func (p *Proxier) handleConnection(inConn net.Conn, svcName string) {
 	}
 
 	backend := backends[rand.Intn(len(backends))]
-	// log.Printf("Forwarding %s -> %s", svcName, backend)
+	// log.Printf("Forwarding %s -> %s", svcKey, backend)
 
 	outConn, err := net.DialTimeout("tcp", backend, 2*time.Second)
 	if err != nil {
@@ -176,9 +181,3 @@ func (p *Proxier) closeAll() {
 		ln.Close()
 	}
 }
-
-
-
-
-
-
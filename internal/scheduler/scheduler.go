@@ -10,57 +10,85 @@ import (
 
 	"github.com/abhigod/k8s-lite/internal/api"
 	"github.com/abhigod/k8s-lite/internal/client"
+	"github.com/abhigod/k8s-lite/internal/informer"
 )
 
 type Scheduler struct {
 	Client *client.Client
+
+	informers *informer.Factory
+	pods      *informer.Informer[api.Pod] // only unscheduled pods
+	nodes     *informer.Informer[api.Node]
+	kick      chan struct{}
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
 func New(apiURL string) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
+	cli := client.New(apiURL, "", "", "")
+	// The resync period doubles as the retry interval for pods that didn't fit anywhere.
+	resync := 30 * time.Second
+	informers := informer.NewFactory(cli, resync)
+	unscheduled := client.ListOptions{FieldSelector: "spec.nodeName="}
 	return &Scheduler{
-		Client: client.New(apiURL, "", "", ""),
-		ctx:    ctx,
-		cancel: cancel,
+		Client:    cli,
+		informers: informers,
+		pods:      informer.New[api.Pod](cli.PodListWatch(api.NamespaceAll, unscheduled), resync),
+		nodes:     informers.Nodes(),
+		kick:      make(chan struct{}, 1),
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 }
 
 func (s *Scheduler) Start() {
 	log.Println("Starting Scheduler...")
 
-	// Polling loop for MVP. Ideally Watch.
-	// Since we haven't implemented a robust Client Watch yet (server has it, but client lib doesn't),
-	// we'll poll for unscheduled pods.
-
-	ticker := time.NewTicker(3 * time.Second)
-	defer ticker.Stop()
+	// Run a scheduling round whenever an unscheduled pod shows up or a node changes.
+	s.pods.AddEventHandler(informer.ResourceEventHandler[api.Pod]{
+		AddFunc:    func(*api.Pod) { s.trigger() },
+		UpdateFunc: func(_, _ *api.Pod) { s.trigger() },
+	})
+	s.nodes.AddEventHandler(informer.ResourceEventHandler[api.Node]{
+		AddFunc:    func(*api.Node) { s.trigger() },
+		UpdateFunc: func(_, _ *api.Node) { s.trigger() },
+	})
+
+	s.informers.Start(s.ctx)
+	go s.pods.Run(s.ctx)
+	if !s.informers.WaitForCacheSync(s.ctx) || !informer.WaitForCacheSync(s.ctx, s.pods.HasSynced) {
+		return
+	}
 
 	for {
 		select {
 		case <-s.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-s.kick:
 			s.scheduleOneRound()
 		}
 	}
 }
 
-func (s *Scheduler) scheduleOneRound() {
-	// 1. Get all pods
-	// Note: Inefficient for large clusters.
-	pods, err := s.Client.ListPods(s.ctx, "")
-	if err != nil {
-		log.Printf("Scheduler Error listing pods: %v", err)
-		return
+// trigger requests a scheduling round without blocking; bursts of events
+// collapse into a single round.
+func (s *Scheduler) trigger() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
 	}
+}
 
-	// 2. Identify unscheduled pods
+func (s *Scheduler) scheduleOneRound() {
+	// 1. Identify unscheduled pods from the cache.
+	// Cached objects are shared, so work on copies.
 	var unscheduled []*api.Pod
-	for i := range pods {
-		if pods[i].Spec.NodeName == "" {
-			unscheduled = append(unscheduled, &pods[i])
+	for _, p := range s.pods.List() {
+		if p.Spec.NodeName == "" {
+			pod := *p
+			unscheduled = append(unscheduled, &pod)
 		}
 	}
 
@@ -68,11 +96,10 @@ func (s *Scheduler) scheduleOneRound() {
 		return
 	}
 
-	// 3. Get Nodes
-	nodes, err := s.Client.ListNodes(s.ctx)
-	if err != nil {
-		log.Printf("Scheduler Error listing nodes: %v", err)
-		return
+	// 2. Get Nodes
+	var nodes []api.Node
+	for _, n := range s.nodes.List() {
+		nodes = append(nodes, *n)
 	}
 
 	if len(nodes) == 0 {
@@ -80,7 +107,7 @@ func (s *Scheduler) scheduleOneRound() {
 		return
 	}
 
-	// 4. Schedule each pod
+	// 3. Schedule each pod
 	for _, pod := range unscheduled {
 		node, err := s.selectNode(pod, nodes)
 		if err != nil {
@@ -158,12 +185,10 @@ func (s *Scheduler) podFitsResources(pod *api.Pod, node *api.Node) bool {
 	return true
 }
 
+// bind assigns pod to nodeName through the binding subresource, which fails
+// if someone else bound the pod in the meantime.
 func (s *Scheduler) bind(pod *api.Pod, nodeName string) error {
-	// Assign nodeName
-	pod.Spec.NodeName = nodeName
-
-	// Update via API
-	return s.Client.UpdatePod(s.ctx, pod)
+	return s.Client.BindPod(s.ctx, pod.Namespace, pod.Name, nodeName)
 }
 
 // Helpers
@@ -193,5 +218,3 @@ func parseCPU(val string) int64 {
 	f, _ := strconv.ParseFloat(val, 64)
 	return int64(f * 1000)
 }
-
-
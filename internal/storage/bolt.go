@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	objectsBucket = []byte("objects")
+	metaBucket    = []byte("meta")
+	revisionKey   = []byte("revision")
+)
+
+// BoltStore implements Store on an embedded bbolt database. Objects live in
+// a single bucket under their storage key, so prefix scans come back in key
+// order. The store revision is kept next to them and bumped in the same
+// transaction as every change, which bbolt fsyncs on commit.
+type BoltStore struct {
+	db *bolt.DB
+
+	// lock serializes writes with each other and with starting watches, so
+	// changes reach the watch cache in revision order.
+	lock     sync.Mutex
+	revision int64 // the committed revision, guarded by lock
+
+	watchCache *watchCache
+}
+
+// NewBoltStore opens, or creates, the database at path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BoltStore{db: db, watchCache: newWatchCache()}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(objectsBucket); err != nil {
+			return err
+		}
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		s.revision = decodeRevision(meta.Get(revisionKey))
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func encodeRevision(rev int64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(rev))
+	return buf
+}
+
+func decodeRevision(data []byte) int64 {
+	if len(data) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(data))
+}
+
+// write runs a change to key in a transaction that commits the next revision.
+// change gets the current object, nil if there is none, and the revision to
+// stamp, and returns the kind of change and the data to record: the new
+// object, or the tombstone for Deleted.
+func (s *BoltStore) write(key string, change func(existing []byte, rev int64) (EventType, []byte, error)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rev := s.revision + 1
+	var eventType EventType
+	var data, prev []byte
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		objects := tx.Bucket(objectsBucket)
+		// Values are only valid during the transaction.
+		if existing := objects.Get([]byte(key)); existing != nil {
+			prev = bytes.Clone(existing)
+		}
+
+		var err error
+		eventType, data, err = change(prev, rev)
+		if err != nil {
+			return err
+		}
+		if eventType == Deleted {
+			err = objects.Delete([]byte(key))
+		} else {
+			err = objects.Put([]byte(key), data)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(metaBucket).Put(revisionKey, encodeRevision(rev))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.revision = rev
+	if eventType != Modified {
+		prev = nil
+	}
+	s.watchCache.notify(rev, eventType, key, data, prev)
+	return nil
+}
+
+func (s *BoltStore) Create(ctx context.Context, key string, obj interface{}) error {
+	var stored int64
+	err := s.write(key, func(existing []byte, rev int64) (EventType, []byte, error) {
+		if existing != nil {
+			return "", nil, ErrAlreadyExists
+		}
+		stored = rev
+		data, err := encodeObject(obj, rev)
+		return Added, data, err
+	})
+	if err != nil {
+		return err
+	}
+	setResourceVersion(obj, stored)
+	return nil
+}
+
+func (s *BoltStore) Update(ctx context.Context, key string, obj interface{}) error {
+	var stored int64
+	err := s.write(key, func(existing []byte, rev int64) (EventType, []byte, error) {
+		if existing == nil {
+			return "", nil, ErrNotFound
+		}
+		// Compare-and-swap when the caller tells us which version it read.
+		if v, ok := obj.(Versioned); ok && v.GetResourceVersion() != "" {
+			if v.GetResourceVersion() != storedVersion(existing) {
+				return "", nil, ErrConflict
+			}
+		}
+		stored = rev
+		data, err := encodeObject(obj, rev)
+		return Modified, data, err
+	})
+	if err != nil {
+		return err
+	}
+	setResourceVersion(obj, stored)
+	return nil
+}
+
+func (s *BoltStore) Get(ctx context.Context, key string, objPtr interface{}) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(objectsBucket).Get([]byte(key))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, objPtr)
+	})
+}
+
+func (s *BoltStore) Delete(ctx context.Context, key string) error {
+	return s.write(key, func(existing []byte, rev int64) (EventType, []byte, error) {
+		if existing == nil {
+			return "", nil, ErrNotFound
+		}
+		return Deleted, tombstone(existing, rev), nil
+	})
+}
+
+func (s *BoltStore) List(ctx context.Context, keyPrefix string, opts ListOptions, listObjPtr interface{}) (ListResult, error) {
+	var result ListResult
+	// A read transaction sees a consistent snapshot, revision included.
+	err := s.db.View(func(tx *bolt.Tx) error {
+		current := decodeRevision(tx.Bucket(metaBucket).Get(revisionKey))
+		l, err := newLister(keyPrefix, opts, listObjPtr, current)
+		if err != nil {
+			return err
+		}
+
+		// Keys under the prefix share its bytes, but not every key that
+		// does is under it: "/registry/pods-x" sorts among "/registry/pods/...".
+		prefix := []byte(keyPrefix)
+		under := func(k []byte) bool { return hasKeyPrefix(string(k), keyPrefix) }
+		c := tx.Bucket(objectsBucket).Cursor()
+		k, v := c.Seek(prefix)
+		if l.startAfter != "" {
+			if k, v = c.Seek([]byte(l.startAfter)); string(k) == l.startAfter {
+				k, v = c.Next()
+			}
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if !under(k) {
+				continue
+			}
+			full, err := l.add(string(k), v)
+			if err != nil {
+				return err
+			}
+			if full {
+				more := false
+				for k, _ = c.Next(); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+					if under(k) {
+						more = true
+						break
+					}
+				}
+				result = l.result(more)
+				return nil
+			}
+		}
+		result = l.result(false)
+		return nil
+	})
+	if err != nil {
+		return ListResult{}, err
+	}
+	return result, nil
+}
+
+// Backup copies the objects in a read transaction, which sees a consistent
+// snapshot without holding up writers.
+func (s *BoltStore) Backup(ctx context.Context) (*Backup, error) {
+	b := &Backup{Objects: []BackupObject{}}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b.Revision = decodeRevision(tx.Bucket(metaBucket).Get(revisionKey))
+		return tx.Bucket(objectsBucket).ForEach(func(k, v []byte) error {
+			b.Objects = append(b.Objects, BackupObject{Key: string(k), Object: bytes.Clone(v)})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *BoltStore) Restore(ctx context.Context, b *Backup) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(objectsBucket); err != nil {
+			return err
+		}
+		objects, err := tx.CreateBucket(objectsBucket)
+		if err != nil {
+			return err
+		}
+		for _, o := range b.Objects {
+			if err := objects.Put([]byte(o.Key), o.Object); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(metaBucket).Put(revisionKey, encodeRevision(b.Revision))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.revision = b.Revision
+	s.watchCache.reset()
+	return nil
+}
+
+// Watch replays changes from the in-memory history only, so after a restart
+// watches must start from a fresh list.
+func (s *BoltStore) Watch(ctx context.Context, keyPrefix string, opts ListOptions) (WatchInterface, error) {
+	rev, err := ParseResourceVersion(opts.ResourceVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.watchCache.watch(rev, s.revision, keyPrefix, opts)
+}
+
+// Close releases the database file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,14 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/storage"
+	"github.com/abhigod/k8s-lite/internal/storage/storagetest"
+)
+
+func TestBoltStoreConformance(t *testing.T) {
+	storagetest.Run(t, func(path string) (storage.Store, error) {
+		return storage.NewBoltStore(path)
+	})
+}
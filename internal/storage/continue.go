@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// continueToken is the decoded form of ListResult.Continue.
+type continueToken struct {
+	ResourceVersion int64  `json:"rv"`
+	StartAfter      string `json:"start"` // last key returned, relative to the list prefix
+}
+
+// EncodeContinue returns the token that resumes a list of keyPrefix after
+// lastKey. resourceVersion is the version of the first page.
+func EncodeContinue(resourceVersion int64, keyPrefix, lastKey string) string {
+	data, _ := json.Marshal(continueToken{
+		ResourceVersion: resourceVersion,
+		StartAfter:      strings.TrimPrefix(lastKey, keyPrefix),
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeContinue parses a token made by EncodeContinue for keyPrefix. It
+// returns the version of the first page and the key to resume after.
+func DecodeContinue(token, keyPrefix string) (int64, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, "", ErrInvalidContinue
+	}
+	var t continueToken
+	if err := json.Unmarshal(data, &t); err != nil || t.ResourceVersion <= 0 || t.StartAfter == "" {
+		return 0, "", ErrInvalidContinue
+	}
+	// Keys aren't paths, and stores only return keys under the prefix, so
+	// the key to resume after can only skip keys, never reach other ones.
+	return t.ResourceVersion, keyPrefix + t.StartAfter, nil
+}
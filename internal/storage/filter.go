@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Matches reports whether an encoded object satisfies the selectors of opts.
+// Objects are matched on their JSON form: labels are read from
+// metadata.labels and fields are addressed by their dotted JSON path.
+func (opts ListOptions) Matches(data []byte) bool {
+	if opts.LabelSelector.Empty() && opts.FieldSelector.Empty() {
+		return true
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return false
+	}
+
+	if !opts.LabelSelector.Empty() {
+		labels := make(map[string]string)
+		if meta, ok := obj["metadata"].(map[string]interface{}); ok {
+			if l, ok := meta["labels"].(map[string]interface{}); ok {
+				for k, v := range l {
+					labels[k] = fmt.Sprint(v)
+				}
+			}
+		}
+		if !opts.LabelSelector.Matches(labels) {
+			return false
+		}
+	}
+	return opts.FieldSelector.Matches(jsonFields(obj))
+}
+
+// jsonFields exposes a decoded JSON object as fields.Fields.
+type jsonFields map[string]interface{}
+
+func (f jsonFields) Get(path string) string {
+	var cur interface{} = map[string]interface{}(f)
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		cur = m[part]
+	}
+
+	switch v := cur.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// filterEvent adapts a change for a watcher with selectors. A modified object
+// that starts matching is reported as Added, one that stops matching as
+// Deleted, and changes that match neither before nor after are dropped.
+// prev is the object before a Modified change.
+func filterEvent(opts ListOptions, eventType EventType, obj, prev []byte) (EventType, bool) {
+	if eventType != Modified {
+		return eventType, opts.Matches(obj)
+	}
+
+	now, before := opts.Matches(obj), opts.Matches(prev)
+	switch {
+	case now && before:
+		return Modified, true
+	case now:
+		return Added, true
+	case before:
+		return Deleted, true
+	}
+	return eventType, false
+}
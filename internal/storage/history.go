@@ -0,0 +1,57 @@
+package storage
+
+// historyEntry is a single change recorded by the store.
+type historyEntry struct {
+	revision  int64
+	eventType EventType
+	key       string
+	object    []byte
+	prev      []byte // the object before a Modified change
+}
+
+// eventHistory is a fixed-size ring of the most recent changes, used to
+// replay events to watchers that resume from an older resourceVersion.
+type eventHistory struct {
+	entries []historyEntry
+	start   int // index of the oldest entry
+	count   int
+}
+
+func newEventHistory(size int) *eventHistory {
+	return &eventHistory{entries: make([]historyEntry, size)}
+}
+
+func (h *eventHistory) add(e historyEntry) {
+	if len(h.entries) == 0 {
+		return
+	}
+	if h.count < len(h.entries) {
+		h.entries[(h.start+h.count)%len(h.entries)] = e
+		h.count++
+		return
+	}
+	// Full: overwrite the oldest entry.
+	h.entries[h.start] = e
+	h.start = (h.start + 1) % len(h.entries)
+}
+
+// oldest returns the revision of the oldest retained change, or 0 if the
+// history is empty.
+func (h *eventHistory) oldest() int64 {
+	if h.count == 0 {
+		return 0
+	}
+	return h.entries[h.start].revision
+}
+
+// since returns every retained change with a revision greater than rev, in order.
+func (h *eventHistory) since(rev int64) []historyEntry {
+	var out []historyEntry
+	for i := 0; i < h.count; i++ {
+		e := h.entries[(h.start+i)%len(h.entries)]
+		if e.revision > rev {
+			out = append(out, e)
+		}
+	}
+	return out
+}
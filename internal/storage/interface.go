@@ -3,17 +3,80 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/abhigod/k8s-lite/internal/fields"
+	"github.com/abhigod/k8s-lite/internal/labels"
 )
 
 var (
-	ErrNotFound = errors.New("resource not found")
-	ErrConflict = errors.New("resource conflict")
+	ErrNotFound      = errors.New("resource not found")
+	ErrAlreadyExists = errors.New("resource already exists")
+	ErrConflict      = errors.New("resource conflict")
+
+	// ErrInvalidContinue is returned by List for a malformed continue token.
+	ErrInvalidContinue = errors.New("invalid continue token")
+
+	// ErrTooLargeResourceVersion is returned by Watch for a resourceVersion
+	// the store hasn't reached.
+	ErrTooLargeResourceVersion = errors.New("too large resource version")
 )
 
+// Versioned is implemented by objects that carry a resourceVersion.
+// The store stamps a new version on every write and uses the caller's
+// version as a precondition on Update.
+type Versioned interface {
+	GetResourceVersion() string
+	SetResourceVersion(version string)
+}
+
 // ListOptions contains options for listing resources
 type ListOptions struct {
-	LabelSelector map[string]string
-	FieldSelector map[string]string
+	// LabelSelector and FieldSelector restrict List and Watch to matching
+	// objects. Empty selectors match everything.
+	LabelSelector labels.Selector
+	FieldSelector fields.Selector
+
+	// ResourceVersion, when set on Watch, replays every change made after that
+	// version before streaming new ones. Empty or "0" means "from now".
+	ResourceVersion string
+
+	// Limit caps the number of objects List returns; 0 means no limit.
+	// Objects are listed in key order.
+	Limit int64
+	// Continue resumes a List where the previous, limited one stopped.
+	Continue string
+}
+
+// ListResult describes the state of the store a List was served from.
+type ListResult struct {
+	// ResourceVersion is the store revision the list reflects. Watching from it
+	// delivers every change made after the list was taken.
+	//
+	// All pages of a paginated list report the version of the first page,
+	// but later pages are read from the live store, not a snapshot at that
+	// version, so they may already show changes made after it. A watch from
+	// the version delivers those changes again; clients apply them on top
+	// of the list as they would any other event.
+	ResourceVersion string
+
+	// Continue is set if the list was truncated by ListOptions.Limit and
+	// resumes it when passed as ListOptions.Continue.
+	Continue string
+}
+
+// ParseResourceVersion parses a resourceVersion as used in ListOptions.
+// Empty string parses as 0.
+func ParseResourceVersion(version string) (int64, error) {
+	if version == "" {
+		return 0, nil
+	}
+	rev, err := strconv.ParseInt(version, 10, 64)
+	if err != nil || rev < 0 {
+		return 0, fmt.Errorf("invalid resource version %q", version)
+	}
+	return rev, nil
 }
 
 // WatchInterface defines the interface for watching resources
@@ -34,17 +97,27 @@ const (
 )
 
 // Event represents a single event to a watched resource.
+// Events produced by a store carry the JSON encoding of the object as a
+// json.RawMessage; Error events carry an *api.Status explaining why the
+// watch was terminated.
 type Event struct {
 	Type   EventType
 	Object interface{}
 }
 
-// Store is the interface that all persistence backends must implement
+// Store is the interface that all persistence backends must implement.
+//
+// Keys are slash-separated paths such as "/registry/pods/default/web". The
+// prefix given to List and Watch selects keys as a directory would: the key
+// equal to it and the keys below it, never keys that merely share its
+// leading characters ("/registry/pods" does not cover "/registry/podsecurity").
 type Store interface {
-	// Create adds a new object to the store. Fails if it already exists.
+	// Create adds a new object to the store. Fails with ErrAlreadyExists if it already exists.
 	Create(ctx context.Context, key string, obj interface{}) error
 
-	// Update updates an existing object. Fails if it doesn't exist.
+	// Update updates an existing object. Fails with ErrNotFound if it doesn't exist.
+	// If obj carries a resourceVersion, the update only succeeds when it matches
+	// the stored one and fails with ErrConflict otherwise.
 	Update(ctx context.Context, key string, obj interface{}) error
 
 	// Get retrieves an object by key.
@@ -53,13 +126,32 @@ type Store interface {
 	// Delete removes an object by key.
 	Delete(ctx context.Context, key string) error
 
-	// List retrieves a list of objects matching the prefix key.
+	// List retrieves a list of objects matching the prefix key and the selectors in opts.
 	// listObjPtr should be a pointer to a slice of objects.
-	List(ctx context.Context, keyPrefix string, listObjPtr interface{}) error
-
-	// Watch returns a channel that receives events for changes to objects matching the key.
-	Watch(ctx context.Context, key string) (WatchInterface, error)
+	List(ctx context.Context, keyPrefix string, opts ListOptions, listObjPtr interface{}) (ListResult, error)
+
+	// Watch returns a channel that receives events for changes to objects under
+	// the key prefix. Passing the full key of an object watches just that object.
+	// With selectors, an object that starts matching is reported as Added and
+	// one that stops matching as Deleted.
+	// If opts.ResourceVersion is older than the retained history, the watch
+	// delivers a single Error event, reason Expired with code 410, and closes.
+	// If it is newer than the store's revision, Watch fails with
+	// ErrTooLargeResourceVersion.
+	Watch(ctx context.Context, keyPrefix string, opts ListOptions) (WatchInterface, error)
+
+	// Backup returns a consistent copy of every object at a single revision.
+	// Writes may continue while it is taken.
+	Backup(ctx context.Context) (*Backup, error)
+
+	// Restore replaces the whole contents of the store, and its revision, with
+	// those of b, and expires every watch. It is meant for a store that isn't
+	// serving clients yet. A store may refuse to restore over contents it
+	// already holds, leaving them as they are; restoring into an empty store
+	// always replaces its revision.
+	Restore(ctx context.Context, b *Backup) error
+
+	// Close flushes and releases the backing storage. The store must not be
+	// used afterwards.
+	Close() error
 }
-
-
-
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// hasKeyPrefix reports whether key lies under keyPrefix, treating keys as
+// slash-separated paths: "/registry/pods" covers "/registry/pods" itself and
+// everything below "/registry/pods/", but not "/registry/podsecurity". The
+// empty prefix covers every key.
+func hasKeyPrefix(key, keyPrefix string) bool {
+	if keyPrefix == "" || strings.HasSuffix(keyPrefix, "/") {
+		return strings.HasPrefix(key, keyPrefix)
+	}
+	return key == keyPrefix || strings.HasPrefix(key, keyPrefix+"/")
+}
+
+// lister builds one page of a List. Stores feed it the objects under the
+// prefix in key order, starting after startAfter, until add reports the page
+// is full.
+type lister struct {
+	keyPrefix  string
+	opts       ListOptions
+	startAfter string // empty for the first page
+	revision   int64  // the version all pages report
+
+	slice    reflect.Value
+	elemType reflect.Type
+	count    int64
+	lastKey  string
+}
+
+// newLister prepares a List into listObjPtr. current is the store revision,
+// used unless opts continues an earlier list.
+func newLister(keyPrefix string, opts ListOptions, listObjPtr interface{}, current int64) (*lister, error) {
+	// Reflect magic to append to slice
+	ptrVal := reflect.ValueOf(listObjPtr)
+	if ptrVal.Kind() != reflect.Ptr || ptrVal.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("listObjPtr must be a pointer to a slice")
+	}
+
+	l := &lister{
+		keyPrefix: keyPrefix,
+		opts:      opts,
+		revision:  current,
+		slice:     ptrVal.Elem(),
+		elemType:  ptrVal.Elem().Type().Elem(),
+	}
+	if opts.Continue != "" {
+		rev, key, err := DecodeContinue(opts.Continue, keyPrefix)
+		if err != nil {
+			return nil, err
+		}
+		l.revision, l.startAfter = rev, key
+	}
+	return l, nil
+}
+
+// add appends the object stored at key if it matches the selectors. It
+// returns true once the page is full.
+func (l *lister) add(key string, data []byte) (bool, error) {
+	if !l.opts.Matches(data) {
+		return false, nil
+	}
+	newElem := reflect.New(l.elemType).Interface()
+	if err := json.Unmarshal(data, newElem); err != nil {
+		return false, err
+	}
+	l.slice.Set(reflect.Append(l.slice, reflect.ValueOf(newElem).Elem()))
+
+	l.count++
+	l.lastKey = key
+	return l.opts.Limit > 0 && l.count == l.opts.Limit, nil
+}
+
+// result describes the page. more tells whether keys are left under the
+// prefix after the last one added.
+func (l *lister) result(more bool) ListResult {
+	res := ListResult{ResourceVersion: strconv.FormatInt(l.revision, 10)}
+	if more {
+		res.Continue = EncodeContinue(l.revision, l.keyPrefix, l.lastKey)
+	}
+	return res
+}
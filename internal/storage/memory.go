@@ -5,36 +5,63 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"reflect"
-	"strings"
+	"sort"
+	"strconv"
 	"sync"
 )
 
 // MemoryStore implements Store interface using an in-memory map.
+//
+// With a file path, the map is made durable by a snapshot at that path and a
+// write-ahead log next to it (path + ".wal"). Every change is appended and
+// fsynced to the log before it becomes visible; once the log grows past
+// snapshotEvery records, a new snapshot replaces the old one and the log is
+// emptied.
 type MemoryStore struct {
 	lock     sync.RWMutex
 	data     map[string][]byte
-	watchers []*memoryWatcher
+	revision int64 // bumped on every Create, Update and Delete
 	filePath string
+	wal      *wal // nil without a file path
+
+	watchCache *watchCache
+}
+
+// snapshotEvery is the number of logged changes after which the store writes
+// a snapshot and compacts the log.
+const snapshotEvery = 10000
+
+// memoryState is the on-disk format of a MemoryStore snapshot.
+type memoryState struct {
+	Revision int64             `json:"revision"`
+	Data     map[string][]byte `json:"data"`
 }
 
-func NewMemoryStore(filePath string) *MemoryStore {
+// NewMemoryStore returns a store persisted at filePath, or a purely
+// in-memory store if filePath is empty. It recovers the state left by a
+// previous run by loading the snapshot and replaying the log over it.
+func NewMemoryStore(filePath string) (*MemoryStore, error) {
 	store := &MemoryStore{
-		data:     make(map[string][]byte),
-		filePath: filePath,
+		data:       make(map[string][]byte),
+		filePath:   filePath,
+		watchCache: newWatchCache(),
 	}
-	if filePath != "" {
-		if err := store.load(); err != nil {
-			fmt.Printf("Warning: failed to load store from %s: %v\n", filePath, err)
-		}
+	if filePath == "" {
+		return store, nil
+	}
+
+	if err := store.load(); err != nil {
+		return nil, fmt.Errorf("failed to load snapshot %s: %v", filePath, err)
 	}
-	return store
+	w, err := openWAL(filePath+".wal", store.replay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover log %s.wal: %v", filePath, err)
+	}
+	store.wal = w
+	return store, nil
 }
 
 func (s *MemoryStore) load() error {
-	s.lock.Lock()
-	defer s.lock.Unlock()
-
 	data, err := os.ReadFile(s.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -43,21 +70,150 @@ func (s *MemoryStore) load() error {
 		return err
 	}
 
-	return json.Unmarshal(data, &s.data)
+	var state memoryState
+	if err := json.Unmarshal(data, &state); err == nil && state.Data != nil {
+		s.data = state.Data
+		s.revision = state.Revision
+		return nil
+	}
+
+	// Older files are a bare key -> object map without a revision.
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return err
+	}
+	for _, v := range s.data {
+		if rev, err := strconv.ParseInt(storedVersion(v), 10, 64); err == nil && rev > s.revision {
+			s.revision = rev
+		}
+	}
+	return nil
 }
 
-func (s *MemoryStore) sync() error {
-	if s.filePath == "" {
+// replay applies a logged change on top of the snapshot. Changes the snapshot
+// already contains are skipped; they are left behind when the process dies
+// between writing a snapshot and emptying the log.
+func (s *MemoryStore) replay(rec walRecord) error {
+	if rec.Revision <= s.revision {
 		return nil
 	}
+	if rec.Type == Deleted {
+		delete(s.data, rec.Key)
+	} else {
+		s.data[rec.Key] = rec.Object
+	}
+	s.revision = rec.Revision
+	return nil
+}
+
+// commit durably records a change carrying the next revision and then makes
+// it visible to readers and watchers. Callers must hold the write lock.
+// data is the new object, or the tombstone for Deleted changes; prev is the
+// previous object for Modified changes.
+func (s *MemoryStore) commit(eventType EventType, key string, data, prev []byte) error {
+	rev := s.revision + 1
+	if s.wal != nil {
+		rec := walRecord{Revision: rev, Type: eventType, Key: key}
+		if eventType != Deleted {
+			rec.Object = data
+		}
+		if err := s.wal.append(rec); err != nil {
+			return err
+		}
+	}
+
+	s.revision = rev
+	if eventType == Deleted {
+		delete(s.data, key)
+	} else {
+		s.data[key] = data
+	}
+	s.watchCache.notify(rev, eventType, key, data, prev)
 
-	// Simple dump
-	data, err := json.Marshal(s.data)
+	if s.wal != nil && s.wal.records >= snapshotEvery {
+		// The change is already safe in the log, so a failed snapshot
+		// only means the log keeps growing until the next attempt.
+		if err := s.snapshot(); err != nil {
+			fmt.Printf("Warning: failed to snapshot store to %s: %v\n", s.filePath, err)
+		}
+	}
+	return nil
+}
+
+// snapshot atomically replaces the snapshot with the current state and
+// empties the log. Callers must hold the write lock.
+func (s *MemoryStore) snapshot() error {
+	data, err := json.Marshal(memoryState{Revision: s.revision, Data: s.data})
 	if err != nil {
 		return err
 	}
+	if err := writeFileAtomic(s.filePath, data); err != nil {
+		return err
+	}
+	return s.wal.truncate()
+}
+
+// Close writes a final snapshot and releases the log. The store must not be
+// used afterwards.
+func (s *MemoryStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.wal == nil {
+		return nil
+	}
+	err := s.snapshot()
+	if cerr := s.wal.close(); err == nil {
+		err = cerr
+	}
+	s.wal = nil
+	return err
+}
+
+// currentRevision returns the revision of the latest change.
+func (s *MemoryStore) currentRevision() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.revision
+}
+
+// state returns a point-in-time copy of the store's contents. Stored objects
+// are never modified in place, so copying the map is enough.
+func (s *MemoryStore) state() memoryState {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	data := make(map[string][]byte, len(s.data))
+	for k, v := range s.data {
+		data[k] = v
+	}
+	return memoryState{Revision: s.revision, Data: data}
+}
+
+// restore replaces the store's contents with state. Watchers are expired,
+// since they can't be told what changed. A persisted store snapshots the new
+// contents right away, which also empties the log of the old ones.
+func (s *MemoryStore) restore(state memoryState) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.data = state.Data
+	if s.data == nil {
+		s.data = make(map[string][]byte)
+	}
+	s.revision = state.Revision
+	s.watchCache.reset()
+	if s.wal == nil {
+		return nil
+	}
+	return s.snapshot()
+}
+
+func (s *MemoryStore) Backup(ctx context.Context) (*Backup, error) {
+	return newBackup(s.state()), nil
+}
 
-	return os.WriteFile(s.filePath, data, 0644)
+func (s *MemoryStore) Restore(ctx context.Context, b *Backup) error {
+	return s.restore(b.state())
 }
 
 func (s *MemoryStore) Create(ctx context.Context, key string, obj interface{}) error {
@@ -65,35 +221,93 @@ func (s *MemoryStore) Create(ctx context.Context, key string, obj interface{}) e
 	defer s.lock.Unlock()
 
 	if _, exists := s.data[key]; exists {
-		return ErrConflict
+		return ErrAlreadyExists
 	}
 
-	data, err := json.Marshal(obj)
+	rev := s.revision + 1
+	data, err := encodeObject(obj, rev)
 	if err != nil {
 		return err
 	}
-
-	s.data[key] = data
-	s.notifyWatchers(Added, key, obj)
-	return s.sync()
+	if err := s.commit(Added, key, data, nil); err != nil {
+		return err
+	}
+	setResourceVersion(obj, rev)
+	return nil
 }
 
 func (s *MemoryStore) Update(ctx context.Context, key string, obj interface{}) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	if _, exists := s.data[key]; !exists {
+	existing, exists := s.data[key]
+	if !exists {
 		return ErrNotFound
 	}
 
-	data, err := json.Marshal(obj)
+	// Compare-and-swap when the caller tells us which version it read.
+	if v, ok := obj.(Versioned); ok && v.GetResourceVersion() != "" {
+		if v.GetResourceVersion() != storedVersion(existing) {
+			return ErrConflict
+		}
+	}
+
+	rev := s.revision + 1
+	data, err := encodeObject(obj, rev)
 	if err != nil {
 		return err
 	}
+	if err := s.commit(Modified, key, data, existing); err != nil {
+		return err
+	}
+	setResourceVersion(obj, rev)
+	return nil
+}
+
+// encodeObject marshals obj as stored at revision. obj itself keeps its
+// resourceVersion: stores set it with setResourceVersion only once the write
+// has committed, so a failed write leaves obj as the caller passed it.
+func encodeObject(obj interface{}, revision int64) ([]byte, error) {
+	v, ok := obj.(Versioned)
+	if !ok {
+		return json.Marshal(obj)
+	}
+	prev := v.GetResourceVersion()
+	v.SetResourceVersion(strconv.FormatInt(revision, 10))
+	defer v.SetResourceVersion(prev)
+	return json.Marshal(obj)
+}
+
+// setResourceVersion sets the resourceVersion of obj to revision, that of the
+// write that stored it.
+func setResourceVersion(obj interface{}, revision int64) {
+	if v, ok := obj.(Versioned); ok {
+		v.SetResourceVersion(strconv.FormatInt(revision, 10))
+	}
+}
+
+// tombstone returns the encoded object data with the revision of its
+// deletion stamped on, so watchers can resume from a deletion like from any
+// other change.
+func tombstone(data []byte, revision int64) []byte {
+	var obj map[string]interface{}
+	json.Unmarshal(data, &obj)
+	if meta, ok := obj["metadata"].(map[string]interface{}); ok {
+		meta["resourceVersion"] = strconv.FormatInt(revision, 10)
+	}
+	out, _ := json.Marshal(obj)
+	return out
+}
 
-	s.data[key] = data
-	s.notifyWatchers(Modified, key, obj)
-	return s.sync()
+// storedVersion reads metadata.resourceVersion out of an encoded object.
+func storedVersion(data []byte) string {
+	var obj struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+	}
+	json.Unmarshal(data, &obj)
+	return obj.Metadata.ResourceVersion
 }
 
 func (s *MemoryStore) Get(ctx context.Context, key string, objPtr interface{}) error {
@@ -112,90 +326,50 @@ func (s *MemoryStore) Delete(ctx context.Context, key string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	if _, exists := s.data[key]; !exists {
+	existing, exists := s.data[key]
+	if !exists {
 		return ErrNotFound
 	}
-
-	// For delete, we need the object to notify watchers.
-	// In a real generic store, we might only send the key or tombstone.
-	// Here we try to unmarshal to a generic map just to send something,
-	// or assume the caller knows what was deleted.
-	// Ideally we'd read it before deleting.
-	var oldObj map[string]interface{}
-	json.Unmarshal(s.data[key], &oldObj)
-
-	delete(s.data, key)
-	s.notifyWatchers(Deleted, key, oldObj)
-	return s.sync()
+	return s.commit(Deleted, key, tombstone(existing, s.revision+1), nil)
 }
 
-func (s *MemoryStore) List(ctx context.Context, keyPrefix string, listObjPtr interface{}) error {
+func (s *MemoryStore) List(ctx context.Context, keyPrefix string, opts ListOptions, listObjPtr interface{}) (ListResult, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
-	// Reflect magic to append to slice
-	ptrVal := reflect.ValueOf(listObjPtr)
-	if ptrVal.Kind() != reflect.Ptr || ptrVal.Elem().Kind() != reflect.Slice {
-		return fmt.Errorf("listObjPtr must be a pointer to a slice")
+	l, err := newLister(keyPrefix, opts, listObjPtr, s.revision)
+	if err != nil {
+		return ListResult{}, err
 	}
-	sliceVal := ptrVal.Elem()
-	elemType := sliceVal.Type().Elem()
 
-	for k, v := range s.data {
-		if strings.HasPrefix(k, keyPrefix) {
-			newElem := reflect.New(elemType).Interface()
-			if err := json.Unmarshal(v, newElem); err != nil {
-				return err
-			}
-			sliceVal.Set(reflect.Append(sliceVal, reflect.ValueOf(newElem).Elem()))
+	// Walk keys in order so pages are stable.
+	var keys []string
+	for k := range s.data {
+		if hasKeyPrefix(k, keyPrefix) && k > l.startAfter {
+			keys = append(keys, k)
 		}
 	}
-	return nil
-}
-
-func (s *MemoryStore) Watch(ctx context.Context, keyPrefix string) (WatchInterface, error) {
-	s.lock.Lock()
-	defer s.lock.Unlock()
-
-	w := &memoryWatcher{
-		resultChan: make(chan Event, 10),
-		keyPrefix:  keyPrefix,
-		store:      s,
-	}
-	s.watchers = append(s.watchers, w)
-	return w, nil
-}
+	sort.Strings(keys)
 
-func (s *MemoryStore) notifyWatchers(eventType EventType, key string, obj interface{}) {
-	for _, w := range s.watchers {
-		if strings.HasPrefix(key, w.keyPrefix) {
-			w.resultChan <- Event{Type: eventType, Object: obj}
+	for i, k := range keys {
+		full, err := l.add(k, s.data[k])
+		if err != nil {
+			return ListResult{}, err
+		}
+		if full {
+			return l.result(i < len(keys)-1), nil
 		}
 	}
+	return l.result(false), nil
 }
 
-type memoryWatcher struct {
-	resultChan chan Event
-	keyPrefix  string
-	store      *MemoryStore
-}
-
-func (w *memoryWatcher) Stop() {
-	w.store.lock.Lock()
-	defer w.store.lock.Unlock()
-	// Remove self from watchers list
-	for i, watcher := range w.store.watchers {
-		if watcher == w {
-			w.store.watchers = append(w.store.watchers[:i], w.store.watchers[i+1:]...)
-			break
-		}
+func (s *MemoryStore) Watch(ctx context.Context, keyPrefix string, opts ListOptions) (WatchInterface, error) {
+	rev, err := ParseResourceVersion(opts.ResourceVersion)
+	if err != nil {
+		return nil, err
 	}
-	close(w.resultChan)
-}
 
-func (w *memoryWatcher) ResultChan() <-chan Event {
-	return w.resultChan
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.watchCache.watch(rev, s.revision, keyPrefix, opts)
 }
-
-
-
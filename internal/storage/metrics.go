@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	watchersActive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "storage_watchers",
+			Help: "Number of open storage watchers",
+		},
+	)
+	watchersDropped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "storage_watchers_dropped_total",
+			Help: "Total number of watchers terminated because they fell too far behind",
+		},
+	)
+	watchQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "storage_watch_queue_depth",
+			Help: "Number of events queued for delivery across all watchers",
+		},
+	)
+)
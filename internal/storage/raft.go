@@ -0,0 +1,675 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// raftApplyTimeout bounds a single write, including finding the leader and
+// waiting for the change to be applied locally.
+const raftApplyTimeout = 10 * time.Second
+
+// raftStartTimeout bounds how long NewRaftStore waits for the cluster to
+// elect a leader and for this member to catch up with it.
+const raftStartTimeout = time.Minute
+
+var errRaftTimeout = errors.New("timed out waiting for the raft cluster")
+
+// errRestoreInitialized rejects a restore into a cluster that already holds
+// state: a restore only seeds a new cluster.
+var errRestoreInitialized = errors.New("the raft cluster is already initialized; a backup can only be restored into a new cluster")
+
+// RaftPeer is a member of a raft cluster.
+type RaftPeer struct {
+	ID   string // unique, stable name of the member
+	Addr string // host:port of its raft address, for raft and forwarded writes
+}
+
+// RaftConfig configures a RaftStore.
+type RaftConfig struct {
+	ID       string
+	BindAddr string // host:port the raft address listens on
+	Dir      string // holds the raft log and snapshots
+
+	// Secret is shared by every member. Only connections that prove they
+	// know it are served on the raft address.
+	Secret []byte
+
+	// Peers lists every member of the cluster, including this one. It is only
+	// used to bootstrap a fresh cluster; an empty list starts a single-member
+	// cluster.
+	Peers []RaftPeer
+}
+
+// RaftStore implements Store by replicating every change through raft.
+//
+// Each member applies the committed log to its own in-memory MemoryStore,
+// so all members hold the same objects at the same revisions and any of them
+// can serve reads and watches, and resume a watch started on another member.
+// Writes go through the leader: a follower forwards them to the leader's raft
+// address and returns once the change has been applied locally as well, so a
+// client sees its own writes on the member it talks to. Gets and lists
+// first wait until the local copy includes every write acknowledged before
+// them, wherever it was made, and so do watches from a resourceVersion the
+// local copy hasn't reached.
+type RaftStore struct {
+	config    RaftConfig
+	raft      *raft.Raft
+	fsm       *raftFSM
+	mux       *raftMux
+	transport *raft.NetworkTransport
+	peers     *http.Server // serves forwarded requests on the raft address
+	logs      *raftLogStore
+	client    *http.Client
+
+	// bootstrapped is set when this member started without any raft state,
+	// the only time it may restore a backup.
+	bootstrapped bool
+
+	// barrierTerm is the last term in which a barrier committed while this
+	// member led, which it needs before it can serve read indexes.
+	barrierTerm atomic.Uint64
+}
+
+// raftCommand is a change proposed to the cluster. An empty Op is a no-op
+// used to wait until this member has caught up, unless Restore is set.
+type raftCommand struct {
+	Op     EventType       `json:"op,omitempty"`
+	Key    string          `json:"key,omitempty"`
+	Object json.RawMessage `json:"object,omitempty"`
+
+	// Restore replaces the whole store; Op is empty.
+	Restore *Backup `json:"restore,omitempty"`
+}
+
+// raftResult is the outcome of applying a command, as the leader reports it
+// to forwarding followers. Error is one of the raftErrors keys or a message.
+type raftResult struct {
+	Revision int64  `json:"revision"`
+	Index    uint64 `json:"index"`
+	Error    string `json:"error,omitempty"`
+}
+
+var raftErrors = map[string]error{
+	"NotFound":      ErrNotFound,
+	"AlreadyExists": ErrAlreadyExists,
+	"Conflict":      ErrConflict,
+	"Initialized":   errRestoreInitialized,
+}
+
+func (r raftResult) err() error {
+	if r.Error == "" {
+		return nil
+	}
+	if err, ok := raftErrors[r.Error]; ok {
+		return err
+	}
+	return errors.New(r.Error)
+}
+
+func newRaftResult(rev int64, index uint64, err error) raftResult {
+	res := raftResult{Revision: rev, Index: index}
+	if err != nil {
+		res.Error = err.Error()
+		for code, e := range raftErrors {
+			if err == e {
+				res.Error = code
+			}
+		}
+	}
+	return res
+}
+
+// NewRaftStore starts this member and waits until it has joined a cluster
+// with an elected leader and caught up with the committed changes. A fresh
+// member bootstraps the cluster from config.Peers; a restarted one recovers
+// from its log and snapshots in config.Dir.
+func NewRaftStore(config RaftConfig) (*RaftStore, error) {
+	if len(config.Secret) == 0 {
+		return nil, errors.New("a raft secret is required")
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var servers []raft.Server
+	var advertise net.Addr
+	for _, p := range config.Peers {
+		servers = append(servers, raft.Server{ID: raft.ServerID(p.ID), Address: raft.ServerAddress(p.Addr)})
+		if p.ID == config.ID {
+			addr, err := net.ResolveTCPAddr("tcp", p.Addr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid raft address %q: %v", p.Addr, err)
+			}
+			advertise = addr
+		}
+	}
+	if len(config.Peers) > 0 && advertise == nil {
+		return nil, fmt.Errorf("raft peers don't include this member %q", config.ID)
+	}
+
+	peers := &raftPeerFilter{}
+	mux, err := newRaftMux(config.BindAddr, advertise, config.Secret, peers.allow)
+	if err != nil {
+		return nil, err
+	}
+	transport := raft.NewNetworkTransport(mux.raft, 3, 10*time.Second, os.Stderr)
+	if len(servers) == 0 {
+		servers = []raft.Server{{ID: raft.ServerID(config.ID), Address: transport.LocalAddr()}}
+	}
+
+	snapshots, err := raft.NewFileSnapshotStore(config.Dir, 2, os.Stderr)
+	if err != nil {
+		transport.Close()
+		return nil, err
+	}
+	logs, err := newRaftLogStore(filepath.Join(config.Dir, "raft.db"))
+	if err != nil {
+		transport.Close()
+		return nil, err
+	}
+
+	local, _ := NewMemoryStore("")
+	s := &RaftStore{
+		config:    config,
+		fsm:       newRaftFSM(local),
+		mux:       mux,
+		transport: transport,
+		logs:      logs,
+		client:    &http.Client{Transport: &http.Transport{DialContext: mux.dialHTTP}},
+	}
+	s.peers = &http.Server{Handler: s.peerHandler()}
+	go s.peers.Serve(mux.http)
+
+	rc := raft.DefaultConfig()
+	rc.LocalID = raft.ServerID(config.ID)
+	rc.LogOutput = os.Stderr
+	rc.LogLevel = "WARN"
+
+	existing, err := raft.HasExistingState(logs, logs, snapshots)
+	if err != nil {
+		s.closeTransport()
+		return nil, err
+	}
+	if !existing {
+		s.bootstrapped = true
+		err := raft.BootstrapCluster(rc, logs, logs, snapshots, transport, raft.Configuration{Servers: servers})
+		if err != nil {
+			s.closeTransport()
+			return nil, err
+		}
+	}
+
+	s.raft, err = raft.NewRaft(rc, s.fsm, logs, logs, snapshots, transport)
+	if err != nil {
+		s.closeTransport()
+		return nil, err
+	}
+	peers.setRaft(s.raft)
+
+	ctx, cancel := context.WithTimeout(context.Background(), raftStartTimeout)
+	defer cancel()
+	if _, err := s.propose(ctx, raftCommand{}); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("raft member %s failed to start: %v", config.ID, err)
+	}
+	return s, nil
+}
+
+// propose replicates cmd through the leader and waits for it to be applied
+// locally.
+func (s *RaftStore) propose(ctx context.Context, cmd raftCommand) (raftResult, error) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return raftResult{}, err
+	}
+
+	var res raftResult
+	err = s.retry(ctx, func() (bool, error) {
+		var retry bool
+		var err error
+		if s.raft.State() == raft.Leader {
+			res, retry, err = s.applyLeader(data)
+			return retry, err
+		}
+		res, retry, err = s.forward(ctx, "apply", data)
+		// A lost response may hide an applied change, so only the no-op
+		// is safe to send again.
+		return retry || (err != nil && cmd.Op == "" && cmd.Restore == nil), err
+	})
+	if err != nil {
+		return raftResult{}, err
+	}
+	return res, s.fsm.waitApplied(ctx, res.Index)
+}
+
+// readBarrier waits until this member has applied every change the cluster
+// acknowledged before the call, so that a read which follows it is
+// linearizable. It follows the raft read-index scheme: the leader confirms
+// it still leads and reports what it has applied.
+func (s *RaftStore) readBarrier(ctx context.Context) error {
+	var index uint64
+	err := s.retry(ctx, func() (bool, error) {
+		if s.raft.State() == raft.Leader {
+			var retry bool
+			var err error
+			index, retry, err = s.readIndexLeader()
+			return retry, err
+		}
+		res, _, err := s.forward(ctx, "read-index", nil)
+		index = res.Index
+		return err != nil, err
+	})
+	if err != nil {
+		return err
+	}
+	return s.fsm.waitApplied(ctx, index)
+}
+
+// retry calls fn until it succeeds or fails without asking for a retry.
+func (s *RaftStore) retry(ctx context.Context, fn func() (bool, error)) error {
+	for {
+		retry, err := fn()
+		if err == nil || !retry {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errRaftTimeout
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// applyLeader commits data while this member is the leader. It asks for a
+// retry when leadership moved before the command was accepted.
+func (s *RaftStore) applyLeader(data []byte) (raftResult, bool, error) {
+	f := s.raft.Apply(data, raftApplyTimeout)
+	if err := f.Error(); err != nil {
+		return raftResult{}, err == raft.ErrNotLeader, err
+	}
+	return f.Response().(raftResult), false, nil
+}
+
+// readIndexLeader returns the index a read must wait for while this member
+// is the leader. Once the leader has committed an entry of its own term,
+// every acknowledged write has been applied on it, so its applied index will
+// do as long as a quorum confirms it still leads. A new leader may not have
+// applied the writes its predecessor acknowledged yet, so the first read of
+// each term commits a barrier first.
+func (s *RaftStore) readIndexLeader() (uint64, bool, error) {
+	if term := s.raft.CurrentTerm(); s.barrierTerm.Load() != term {
+		if err := s.raft.Barrier(raftApplyTimeout).Error(); err != nil {
+			return 0, err == raft.ErrNotLeader || err == raft.ErrLeadershipLost, err
+		}
+		s.barrierTerm.Store(term)
+	}
+	index := s.fsm.appliedIndex()
+	if err := s.raft.VerifyLeader().Error(); err != nil {
+		return 0, err == raft.ErrNotLeader || err == raft.ErrLeadershipLost, err
+	}
+	return index, false, nil
+}
+
+// forward posts data to an endpoint the leader serves on its raft address.
+// It asks for a retry while there is no leader, or when the member it
+// reached is no longer the leader.
+func (s *RaftStore) forward(ctx context.Context, endpoint string, data []byte) (raftResult, bool, error) {
+	addr, id := s.raft.LeaderWithID()
+	if addr == "" {
+		return raftResult{}, true, errors.New("no raft leader")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+string(addr)+"/"+endpoint, bytes.NewReader(data))
+	if err != nil {
+		return raftResult{}, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return raftResult{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		return raftResult{}, true, errors.New("raft leader moved")
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return raftResult{}, false, fmt.Errorf("raft leader %s: %s", id, bytes.TrimSpace(msg))
+	}
+	var res raftResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return raftResult{}, false, err
+	}
+	return res, false, nil
+}
+
+// peerHandler serves the endpoints followers forward writes and read
+// barriers to, on the raft address.
+func (s *RaftStore) peerHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /apply", func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.serveLeader(w, func() (raftResult, bool, error) { return s.applyLeader(data) })
+	})
+	mux.HandleFunc("POST /read-index", func(w http.ResponseWriter, r *http.Request) {
+		s.serveLeader(w, func() (raftResult, bool, error) {
+			index, retry, err := s.readIndexLeader()
+			return raftResult{Index: index}, retry, err
+		})
+	})
+	return mux
+}
+
+// serveLeader answers a forwarded request with the result of fn, or with 421
+// Misdirected Request if this member isn't the leader.
+func (s *RaftStore) serveLeader(w http.ResponseWriter, fn func() (raftResult, bool, error)) {
+	if s.raft.State() != raft.Leader {
+		http.Error(w, "not the raft leader", http.StatusMisdirectedRequest)
+		return
+	}
+	res, retry, err := fn()
+	if retry {
+		http.Error(w, "not the raft leader", http.StatusMisdirectedRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// write proposes a change and stamps the resulting revision on obj.
+func (s *RaftStore) write(ctx context.Context, op EventType, key string, obj interface{}) error {
+	cmd := raftCommand{Op: op, Key: key}
+	if obj != nil {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		cmd.Object = data
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, raftApplyTimeout)
+	defer cancel()
+	res, err := s.propose(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if err := res.err(); err != nil {
+		return err
+	}
+	setResourceVersion(obj, res.Revision)
+	return nil
+}
+
+func (s *RaftStore) Create(ctx context.Context, key string, obj interface{}) error {
+	return s.write(ctx, Added, key, obj)
+}
+
+func (s *RaftStore) Update(ctx context.Context, key string, obj interface{}) error {
+	return s.write(ctx, Modified, key, obj)
+}
+
+func (s *RaftStore) Delete(ctx context.Context, key string) error {
+	return s.write(ctx, Deleted, key, nil)
+}
+
+func (s *RaftStore) Get(ctx context.Context, key string, objPtr interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, raftApplyTimeout)
+	defer cancel()
+	if err := s.readBarrier(ctx); err != nil {
+		return err
+	}
+	return s.fsm.store.Get(ctx, key, objPtr)
+}
+
+func (s *RaftStore) List(ctx context.Context, keyPrefix string, opts ListOptions, listObjPtr interface{}) (ListResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, raftApplyTimeout)
+	defer cancel()
+	if err := s.readBarrier(ctx); err != nil {
+		return ListResult{}, err
+	}
+	return s.fsm.store.List(ctx, keyPrefix, opts, listObjPtr)
+}
+
+// Backup copies this member's state once it has caught up with the cluster.
+func (s *RaftStore) Backup(ctx context.Context) (*Backup, error) {
+	ctx, cancel := context.WithTimeout(ctx, raftApplyTimeout)
+	defer cancel()
+	if err := s.readBarrier(ctx); err != nil {
+		return nil, err
+	}
+	return s.fsm.store.Backup(ctx)
+}
+
+// Restore seeds a new cluster with a backup, through the log like any other
+// change. It only succeeds on a member that started without raft state, and
+// only while the cluster is empty: once anything is stored, restoring would
+// wipe the state of every member, so a member restarted with a stale
+// --restore-from fails instead. Every member of a new cluster may restore the
+// same backup; the first one to do so seeds the cluster and the others find
+// it already applied.
+func (s *RaftStore) Restore(ctx context.Context, b *Backup) error {
+	if !s.bootstrapped {
+		return errRestoreInitialized
+	}
+	ctx, cancel := context.WithTimeout(ctx, raftApplyTimeout)
+	defer cancel()
+	res, err := s.propose(ctx, raftCommand{Restore: b})
+	if err != nil {
+		return err
+	}
+	return res.err()
+}
+
+// Watch starts from this member's copy. A watch from a resourceVersion the
+// copy hasn't reached yet, e.g. one listed on a member further ahead, first
+// waits for a read barrier, so it never replays changes the client has
+// already seen; one the cluster hasn't reached either is refused.
+func (s *RaftStore) Watch(ctx context.Context, keyPrefix string, opts ListOptions) (WatchInterface, error) {
+	rev, err := ParseResourceVersion(opts.ResourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	if rev > s.fsm.store.currentRevision() {
+		barrierCtx, cancel := context.WithTimeout(ctx, raftApplyTimeout)
+		defer cancel()
+		if err := s.readBarrier(barrierCtx); err != nil {
+			return nil, err
+		}
+	}
+	return s.fsm.store.Watch(ctx, keyPrefix, opts)
+}
+
+// Close leaves the cluster without removing this member from it, so it can
+// rejoin from its log when restarted.
+func (s *RaftStore) Close() error {
+	err := s.raft.Shutdown().Error()
+	if cerr := s.closeTransport(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *RaftStore) closeTransport() error {
+	s.peers.Close()
+	err := s.transport.Close()
+	if cerr := s.logs.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// raftFSM applies committed commands to a local MemoryStore. Raft calls
+// Apply, Snapshot and Restore from a single goroutine, in log order.
+type raftFSM struct {
+	store *MemoryStore
+	// restored is the digest of the backup the cluster was seeded with, if
+	// any.
+	restored string
+
+	mu        sync.Mutex
+	applied   uint64        // index of the last applied command
+	appliedCh chan struct{} // closed and replaced whenever applied moves
+}
+
+// raftSnapshot is the format of the FSM's snapshots.
+type raftSnapshot struct {
+	Index    uint64 `json:"index"`
+	Restored string `json:"restored,omitempty"`
+	memoryState
+}
+
+func newRaftFSM(store *MemoryStore) *raftFSM {
+	return &raftFSM{store: store, appliedCh: make(chan struct{})}
+}
+
+func (f *raftFSM) Apply(l *raft.Log) interface{} {
+	var cmd raftCommand
+	if err := json.Unmarshal(l.Data, &cmd); err != nil {
+		f.setApplied(l.Index)
+		return newRaftResult(0, l.Index, err)
+	}
+
+	ctx := context.Background()
+	var err error
+	switch cmd.Op {
+	case Added, Modified:
+		var obj rawObject
+		d := json.NewDecoder(bytes.NewReader(cmd.Object))
+		d.UseNumber()
+		if err = d.Decode(&obj); err == nil {
+			if cmd.Op == Added {
+				err = f.store.Create(ctx, cmd.Key, obj)
+			} else {
+				err = f.store.Update(ctx, cmd.Key, obj)
+			}
+		}
+	case Deleted:
+		err = f.store.Delete(ctx, cmd.Key)
+	default:
+		if cmd.Restore != nil {
+			err = f.restore(ctx, cmd.Restore)
+		}
+	}
+	f.setApplied(l.Index)
+	return newRaftResult(f.store.currentRevision(), l.Index, err)
+}
+
+// restore seeds the store with b if nothing was stored yet. Restoring the
+// backup the store was seeded with again does nothing.
+func (f *raftFSM) restore(ctx context.Context, b *Backup) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	digest := fmt.Sprintf("%x", sha256.Sum256(data))
+	if digest == f.restored {
+		return nil
+	}
+	if f.restored != "" || f.store.currentRevision() != 0 {
+		return errRestoreInitialized
+	}
+	if err := f.store.Restore(ctx, b); err != nil {
+		return err
+	}
+	f.restored = digest
+	return nil
+}
+
+func (f *raftFSM) setApplied(index uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.applied = index
+	close(f.appliedCh)
+	f.appliedCh = make(chan struct{})
+}
+
+func (f *raftFSM) appliedIndex() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.applied
+}
+
+// waitApplied blocks until the command at index has been applied locally.
+func (f *raftFSM) waitApplied(ctx context.Context, index uint64) error {
+	for {
+		f.mu.Lock()
+		applied, ch := f.applied, f.appliedCh
+		f.mu.Unlock()
+		if applied >= index {
+			return nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return errRaftTimeout
+		}
+	}
+}
+
+func (f *raftFSM) Snapshot() (raft.FSMSnapshot, error) {
+	return &raftSnapshot{Index: f.appliedIndex(), Restored: f.restored, memoryState: f.store.state()}, nil
+}
+
+func (f *raftFSM) Restore(r io.ReadCloser) error {
+	defer r.Close()
+	var snap raftSnapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return err
+	}
+	if err := f.store.restore(snap.memoryState); err != nil {
+		return err
+	}
+	f.restored = snap.Restored
+	f.setApplied(snap.Index)
+	return nil
+}
+
+func (s *raftSnapshot) Persist(sink raft.SnapshotSink) error {
+	if err := json.NewEncoder(sink).Encode(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *raftSnapshot) Release() {}
+
+// rawObject is an object as decoded from a command, with numbers kept
+// verbatim, so the FSM can stamp and check its resourceVersion.
+type rawObject map[string]interface{}
+
+func (o rawObject) GetResourceVersion() string {
+	meta, _ := o["metadata"].(map[string]interface{})
+	v, _ := meta["resourceVersion"].(string)
+	return v
+}
+
+func (o rawObject) SetResourceVersion(version string) {
+	if meta, ok := o["metadata"].(map[string]interface{}); ok {
+		meta["resourceVersion"] = version
+	}
+}
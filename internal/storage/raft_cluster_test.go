@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/hashicorp/raft"
+)
+
+// startRaftCluster starts a cluster of n members on loopback addresses.
+func startRaftCluster(t *testing.T, n int) []*RaftStore {
+	t.Helper()
+	var peers []RaftPeer
+	for i := 0; i < n; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		peers = append(peers, RaftPeer{ID: fmt.Sprintf("node%d", i+1), Addr: ln.Addr().String()})
+		ln.Close()
+	}
+
+	// Each member waits for a leader, so they have to start together.
+	stores := make([]*RaftStore, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, p := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stores[i], errs[i] = NewRaftStore(RaftConfig{ID: p.ID, BindAddr: p.Addr, Dir: t.TempDir(), Peers: peers, Secret: []byte("secret")})
+		}()
+	}
+	wg.Wait()
+	t.Cleanup(func() {
+		for _, s := range stores {
+			if s != nil {
+				s.Close()
+			}
+		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return stores
+}
+
+// leader returns the member that currently leads.
+func leader(t *testing.T, stores []*RaftStore) *RaftStore {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, s := range stores {
+			if s.raft.State() == raft.Leader {
+				return s
+			}
+		}
+	}
+	t.Fatal("no raft leader")
+	return nil
+}
+
+// A read on a new leader must see every write its predecessor acknowledged,
+// even before it has committed anything of its own.
+func TestRaftReadAfterLeaderChange(t *testing.T) {
+	stores := startRaftCluster(t, 3)
+	ctx := context.Background()
+	key := "/registry/pods/default/web"
+	if err := leader(t, stores).Create(ctx, key, &api.Pod{ObjectMeta: api.ObjectMeta{Name: "web", Namespace: "default"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		old := leader(t, stores)
+		pod := &api.Pod{ObjectMeta: api.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"round": fmt.Sprint(i)}}}
+		if err := old.Update(ctx, key, pod); err != nil {
+			t.Fatalf("round %d: update: %v", i, err)
+		}
+		if err := old.raft.LeadershipTransfer().Error(); err != nil {
+			t.Fatalf("round %d: leadership transfer: %v", i, err)
+		}
+
+		for _, s := range stores {
+			var got api.Pod
+			if err := s.Get(ctx, key, &got); err != nil {
+				t.Fatalf("round %d: get on %s: %v", i, s.config.ID, err)
+			}
+			if got.ResourceVersion != pod.ResourceVersion {
+				t.Errorf("round %d: %s read resourceVersion %s, want the acknowledged %s", i, s.config.ID, got.ResourceVersion, pod.ResourceVersion)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/storage"
+	"github.com/abhigod/k8s-lite/internal/storage/storagetest"
+)
+
+// The suite runs against a single-member cluster, which elects itself.
+func TestRaftStoreConformance(t *testing.T) {
+	storagetest.Run(t, func(path string) (storage.Store, error) {
+		return storage.NewRaftStore(storage.RaftConfig{ID: "node1", BindAddr: "127.0.0.1:0", Dir: path + ".raft", Secret: []byte("secret")})
+	})
+}
+
+// A restore only seeds a new cluster; it must never wipe one that holds state.
+func TestRaftStoreRestoreOnlySeedsNewCluster(t *testing.T) {
+	ctx := t.Context()
+	backup := func(names ...string) *storage.Backup {
+		s, _ := storage.NewMemoryStore("")
+		for _, name := range names {
+			pod := &api.Pod{ObjectMeta: api.ObjectMeta{Name: name, Namespace: "default"}}
+			if err := s.Create(ctx, "/registry/pods/default/"+name, pod); err != nil {
+				t.Fatal(err)
+			}
+		}
+		b, err := s.Backup(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	seed, other := backup("a", "b"), backup("c")
+
+	config := storage.RaftConfig{ID: "node1", BindAddr: "127.0.0.1:0", Dir: t.TempDir(), Secret: []byte("secret")}
+	s, err := storage.NewRaftStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore(ctx, seed); err != nil {
+		t.Fatalf("restore into a new cluster: %v", err)
+	}
+	if err := s.Restore(ctx, seed); err != nil {
+		t.Errorf("restoring the seed again: %v, want it to do nothing", err)
+	}
+	if err := s.Restore(ctx, other); err == nil {
+		t.Errorf("restoring another backup over the seeded cluster succeeded")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A member restarted with a stale --restore-from.
+	s, err = storage.NewRaftStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Restore(ctx, seed); err == nil {
+		t.Errorf("restore on a member with existing state succeeded")
+	}
+	var pods []api.Pod
+	if _, err := s.List(ctx, "/registry/pods/", storage.ListOptions{}, &pods); err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 2 {
+		t.Errorf("got %d pods after the refused restores, want the 2 of the seed", len(pods))
+	}
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/raft"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	raftLogsBucket   = []byte("logs")
+	raftStableBucket = []byte("stable")
+)
+
+// errRaftKeyNotFound is what raft expects from a StableStore for a missing
+// key; it compares the message.
+var errRaftKeyNotFound = errors.New("not found")
+
+// raftLogStore keeps the raft log and raft's own small state (term, vote) in
+// a bbolt database. Every write is a transaction that bbolt fsyncs on commit.
+type raftLogStore struct {
+	db *bolt.DB
+}
+
+func newRaftLogStore(path string) (*raftLogStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(raftLogsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(raftStableBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &raftLogStore{db: db}, nil
+}
+
+func (s *raftLogStore) Close() error {
+	return s.db.Close()
+}
+
+func encodeIndex(index uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, index)
+	return buf
+}
+
+// FirstIndex returns the first index written, or 0 for an empty log.
+func (s *raftLogStore) FirstIndex() (uint64, error) {
+	var index uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket(raftLogsBucket).Cursor().First(); k != nil {
+			index = binary.BigEndian.Uint64(k)
+		}
+		return nil
+	})
+	return index, err
+}
+
+// LastIndex returns the last index written, or 0 for an empty log.
+func (s *raftLogStore) LastIndex() (uint64, error) {
+	var index uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket(raftLogsBucket).Cursor().Last(); k != nil {
+			index = binary.BigEndian.Uint64(k)
+		}
+		return nil
+	})
+	return index, err
+}
+
+func (s *raftLogStore) GetLog(index uint64, log *raft.Log) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(raftLogsBucket).Get(encodeIndex(index))
+		if data == nil {
+			return raft.ErrLogNotFound
+		}
+		return json.Unmarshal(data, log)
+	})
+}
+
+func (s *raftLogStore) StoreLog(log *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{log})
+}
+
+func (s *raftLogStore) StoreLogs(logs []*raft.Log) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(raftLogsBucket)
+		for _, log := range logs {
+			data, err := json.Marshal(log)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(encodeIndex(log.Index), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRange removes the logs from min to max, inclusive.
+func (s *raftLogStore) DeleteRange(min, max uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(raftLogsBucket).Cursor()
+		for k, _ := c.Seek(encodeIndex(min)); k != nil && binary.BigEndian.Uint64(k) <= max; k, _ = c.Next() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *raftLogStore) Set(key []byte, val []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(raftStableBucket).Put(key, val)
+	})
+}
+
+func (s *raftLogStore) Get(key []byte) ([]byte, error) {
+	var val []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(raftStableBucket).Get(key); v != nil {
+			val = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, errRaftKeyNotFound
+	}
+	return val, nil
+}
+
+func (s *raftLogStore) SetUint64(key []byte, val uint64) error {
+	return s.Set(key, encodeIndex(val))
+}
+
+// GetUint64 returns 0 for a missing key, like raft's in-memory store.
+func (s *raftLogStore) GetUint64(key []byte) (uint64, error) {
+	val, err := s.Get(key)
+	if err == errRaftKeyNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(val), nil
+}
@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// Members talk to each other on a single address, the raft address: the
+// raft transport, and the HTTP endpoints followers forward writes and read
+// barriers to. Both are only for members, so neither is reachable through
+// the apiserver. raftMux drops connections from any host that isn't a member
+// of the cluster, and then challenges each connection to prove it knows the
+// secret the members share: it sends a random nonce, and the dialer answers
+// with a header byte naming the listener it wants, followed by the HMAC of
+// the nonce and header keyed with the secret. Connections that answer wrong
+// are closed before anything else is read from them.
+
+// The header bytes of raft transport connections and of peer HTTP
+// connections.
+const (
+	raftStreamHeader   = 0xfe
+	raftPeerHTTPHeader = 0xfd
+)
+
+// raftNonceSize is the length of the nonce a connection is challenged with.
+const raftNonceSize = 32
+
+// raftMuxTimeout bounds how long a new connection may take to answer its
+// challenge.
+const raftMuxTimeout = 10 * time.Second
+
+// raftMux splits the connections accepted on the raft address between the
+// raft transport and the peer HTTP server.
+type raftMux struct {
+	ln        net.Listener
+	advertise net.Addr
+	secret    []byte
+	// allow reports whether a connection from addr may be served.
+	allow func(addr net.Addr) bool
+
+	raft *raftStream
+	http *muxListener
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newRaftMux listens on bindAddr. advertise is the address other members
+// reach this one at; nil means the address listened on. Connections for
+// which allow returns false are closed unread, and those that don't prove
+// they know secret unserved.
+func newRaftMux(bindAddr string, advertise net.Addr, secret []byte, allow func(net.Addr) bool) (*raftMux, error) {
+	ln, err := net.Listen("tcp", bindAddr)
+	if err != nil {
+		return nil, err
+	}
+	if advertise == nil {
+		advertise = ln.Addr()
+	}
+	m := &raftMux{ln: ln, advertise: advertise, secret: secret, allow: allow, done: make(chan struct{})}
+	m.raft = &raftStream{muxListener{mux: m, conns: make(chan net.Conn)}}
+	m.http = &muxListener{mux: m, conns: make(chan net.Conn)}
+	go m.serve()
+	return m, nil
+}
+
+func (m *raftMux) serve() {
+	for {
+		conn, err := m.ln.Accept()
+		if err != nil {
+			m.close()
+			return
+		}
+		go m.route(conn)
+	}
+}
+
+// route challenges conn, and hands it to the listener its answer asks for.
+func (m *raftMux) route(conn net.Conn) {
+	if !m.allow(conn.RemoteAddr()) {
+		conn.Close()
+		return
+	}
+
+	nonce := make([]byte, raftNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return
+	}
+	var answer [1 + sha256.Size]byte
+	conn.SetDeadline(time.Now().Add(raftMuxTimeout))
+	if _, err := conn.Write(nonce); err != nil {
+		conn.Close()
+		return
+	}
+	if _, err := io.ReadFull(conn, answer[:]); err != nil {
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	header := answer[0]
+	if !hmac.Equal(answer[1:], raftProof(m.secret, nonce, header)) {
+		conn.Close()
+		return
+	}
+
+	var l *muxListener
+	switch header {
+	case raftStreamHeader:
+		l = &m.raft.muxListener
+	case raftPeerHTTPHeader:
+		l = m.http
+	default:
+		conn.Close()
+		return
+	}
+	select {
+	case l.conns <- conn:
+	case <-m.done:
+		conn.Close()
+	}
+}
+
+// close stops accepting connections on the raft address, for both raft and
+// HTTP.
+func (m *raftMux) close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.done)
+		err = m.ln.Close()
+	})
+	return err
+}
+
+// dial connects to the raft address at address, and answers its challenge
+// asking for the listener header names.
+func (m *raftMux) dial(ctx context.Context, address string, header byte) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, raftNonceSize)
+	conn.SetDeadline(time.Now().Add(raftMuxTimeout))
+	if _, err := io.ReadFull(conn, nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := conn.Write(append([]byte{header}, raftProof(m.secret, nonce, header)...)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// dialHTTP dials the peer HTTP server of the member at address, for an
+// http.Transport.
+func (m *raftMux) dialHTTP(ctx context.Context, network, address string) (net.Conn, error) {
+	return m.dial(ctx, address, raftPeerHTTPHeader)
+}
+
+// raftProof is the answer to the challenge nonce of a connection for the
+// listener header names, from a member that knows secret.
+func raftProof(secret, nonce []byte, header byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(nonce)
+	mac.Write([]byte{header})
+	return mac.Sum(nil)
+}
+
+// raftPeerFilter admits connections from the hosts of the members in the
+// current raft configuration, which survives restarts unlike the peers a
+// cluster was bootstrapped with. Until raft is running it admits none.
+type raftPeerFilter struct {
+	mu   sync.Mutex
+	raft *raft.Raft
+	// ips are the IPs of the hosts in addrs, the members' addresses when they
+	// were last looked up.
+	addrs []raft.ServerAddress
+	ips   []net.IP
+}
+
+func (f *raftPeerFilter) setRaft(r *raft.Raft) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.raft = r
+}
+
+func (f *raftPeerFilter) allow(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, ip := range f.members() {
+		if ip.Equal(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// members returns the IPs of the members' hosts. Host names are only looked
+// up again once the members change.
+func (f *raftPeerFilter) members() []net.IP {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.raft == nil {
+		return nil
+	}
+	future := f.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil
+	}
+	var addrs []raft.ServerAddress
+	for _, server := range future.Configuration().Servers {
+		addrs = append(addrs, server.Address)
+	}
+	if f.addrs != nil && slices.Equal(addrs, f.addrs) {
+		return f.ips
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		host, _, err := net.SplitHostPort(string(addr))
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		} else if found, err := net.LookupIP(host); err == nil {
+			ips = append(ips, found...)
+		}
+	}
+	f.addrs, f.ips = addrs, ips
+	return ips
+}
+
+// muxListener is a net.Listener of the connections routed to it by a
+// raftMux.
+type muxListener struct {
+	mux   *raftMux
+	conns chan net.Conn
+}
+
+func (l *muxListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.mux.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *muxListener) Close() error { return l.mux.close() }
+
+func (l *muxListener) Addr() net.Addr { return l.mux.advertise }
+
+// raftStream is the raft.StreamLayer of a raftMux.
+type raftStream struct {
+	muxListener
+}
+
+func (s *raftStream) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.mux.dial(ctx, string(address), raftStreamHeader)
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Only members of the cluster may reach the raft address, where a forwarded
+// write would be committed without going through the apiserver.
+func TestRaftAddressRejectsNonMembers(t *testing.T) {
+	s, err := NewRaftStore(RaftConfig{ID: "node1", BindAddr: "127.0.0.1:0", Dir: t.TempDir(), Secret: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	addr := s.mux.advertise.String()
+
+	for _, endpoint := range []string{"/read-index", "/apply"} {
+		url := "http://" + addr + endpoint
+		// The member's own client knows the secret, so it gets an answer,
+		// if only that its empty write is invalid.
+		resp, err := s.client.Post(url, "application/json", nil)
+		if err != nil {
+			t.Fatalf("request to %s from a member: %v", endpoint, err)
+		}
+		resp.Body.Close()
+
+		// A client on a member's host that doesn't know the secret, or
+		// knows another one, is refused.
+		wrong := &raftMux{secret: []byte("guess")}
+		for name, c := range map[string]*http.Client{
+			"no secret":    {},
+			"wrong secret": {Transport: &http.Transport{DialContext: wrong.dialHTTP}},
+		} {
+			if resp, err := c.Post(url, "application/json", nil); err == nil {
+				resp.Body.Close()
+				t.Errorf("request to %s with %s was served: %s", endpoint, name, resp.Status)
+			}
+		}
+	}
+
+	// Any loopback address reaches the listener, but only 127.0.0.1 is a
+	// member, so other hosts aren't even challenged.
+	dialer := &net.Dialer{LocalAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 2)}}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := conn.Read(make([]byte, raftNonceSize)); err != io.EOF {
+		t.Errorf("connection from a non-member: read %d bytes, %v; want it closed", n, err)
+	}
+}
+
+// A member's host is only looked up again once the configuration changes.
+func TestRaftPeerFilterCachesMembers(t *testing.T) {
+	s, err := NewRaftStore(RaftConfig{ID: "node1", BindAddr: "127.0.0.1:0", Dir: t.TempDir(), Secret: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	f := &raftPeerFilter{}
+	f.setRaft(s.raft)
+
+	first := f.members()
+	if len(first) != 1 || !first[0].Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Fatalf("got members %v, want 127.0.0.1", first)
+	}
+	if again := f.members(); &again[0] != &first[0] {
+		t.Error("members were resolved again for the same configuration")
+	}
+
+	if err := s.raft.AddNonvoter("node2", "127.0.0.3:7000", 0, 0).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.members(); len(got) != 2 || !got[1].Equal(net.IPv4(127, 0, 0, 3)) {
+		t.Errorf("got members %v after adding node2, want 127.0.0.1 and 127.0.0.3", got)
+	}
+}
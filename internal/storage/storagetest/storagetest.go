@@ -0,0 +1,1049 @@
+// Package storagetest is a conformance suite for storage.Store
+// implementations. Every backend must pass it: each has a test in
+// internal/storage that calls Run, so go test -race ./internal/storage/...
+// runs the suite against all of them.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/labels"
+	"github.com/abhigod/k8s-lite/internal/storage"
+)
+
+// OpenFunc opens a store persisted at path. Opening the same path again
+// after Close must give back the same contents.
+type OpenFunc func(path string) (storage.Store, error)
+
+// Case is a single conformance check. It gets a path no other case uses.
+type Case struct {
+	Name string
+	Run  func(ctx context.Context, open OpenFunc, path string) error
+}
+
+// Cases is the full suite, in the order Run executes it. Run it under the
+// race detector: ConcurrentWrites relies on it to catch unsynchronized access.
+var Cases = []Case{
+	{"CreateGet", testCreateGet},
+	{"Update", testUpdate},
+	{"Delete", testDelete},
+	{"Revisions", testRevisions},
+	{"ListOrder", testListOrder},
+	{"ListPagination", testListPagination},
+	{"ListPaginationWrites", testListPaginationWrites},
+	{"ListSelectors", testListSelectors},
+	{"ListPrefixIsolation", testListPrefixIsolation},
+	{"WatchFromRevision", testWatchFromRevision},
+	{"WatchFutureRevision", testWatchFutureRevision},
+	{"WatchOrdering", testWatchOrdering},
+	{"WatchStop", testWatchStop},
+	{"WatchSingleKey", testWatchSingleKey},
+	{"ConcurrentWrites", testConcurrentWrites},
+	{"Reopen", testReopen},
+	{"BackupRestore", testBackupRestore},
+}
+
+// Run runs every case as a subtest of t, against stores opened by open,
+// each with its own file in a temporary directory.
+func Run(t *testing.T, open OpenFunc) {
+	for _, c := range Cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if err := c.Run(t.Context(), open, filepath.Join(t.TempDir(), "store.db")); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// withStore opens a store at path for the duration of fn.
+func withStore(open OpenFunc, path string, fn func(storage.Store) error) error {
+	s, err := open(path)
+	if err != nil {
+		return fmt.Errorf("open: %v", err)
+	}
+	err = fn(s)
+	if cerr := s.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("close: %v", cerr)
+	}
+	return err
+}
+
+func podKey(ns, name string) string {
+	return "/registry/pods/" + ns + "/" + name
+}
+
+func newPod(ns, name string, lbls map[string]string) *api.Pod {
+	return &api.Pod{
+		TypeMeta:   api.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: api.ObjectMeta{Name: name, Namespace: ns, Labels: lbls},
+	}
+}
+
+// expectErr checks that err is, or wraps, want.
+func expectErr(op string, err, want error) error {
+	if !errors.Is(err, want) {
+		return fmt.Errorf("%s: got error %v, want %v", op, err, want)
+	}
+	return nil
+}
+
+func names(pods []api.Pod) []string {
+	out := make([]string, len(pods))
+	for i, p := range pods {
+		out[i] = p.Name
+	}
+	return out
+}
+
+func revision(version string) int64 {
+	rev, _ := strconv.ParseInt(version, 10, 64)
+	return rev
+}
+
+// watchTimeout bounds every wait for a watch event.
+const watchTimeout = 5 * time.Second
+
+// nextEvent waits for the next event of w, decoding its object.
+func nextEvent(w storage.WatchInterface) (storage.EventType, *api.Pod, error) {
+	select {
+	case ev, ok := <-w.ResultChan():
+		if !ok {
+			return "", nil, fmt.Errorf("watch closed unexpectedly")
+		}
+		if ev.Type == storage.Error {
+			return "", nil, fmt.Errorf("watch failed: %+v", ev.Object)
+		}
+		raw, ok := ev.Object.(json.RawMessage)
+		if !ok {
+			return "", nil, fmt.Errorf("%s event carries %T, want json.RawMessage", ev.Type, ev.Object)
+		}
+		var pod api.Pod
+		if err := json.Unmarshal(raw, &pod); err != nil {
+			return "", nil, fmt.Errorf("decode %s event: %v", ev.Type, err)
+		}
+		return ev.Type, &pod, nil
+	case <-time.After(watchTimeout):
+		return "", nil, fmt.Errorf("timed out waiting for a watch event")
+	}
+}
+
+func testCreateGet(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		pod := newPod("default", "a", map[string]string{"app": "web"})
+		if err := s.Create(ctx, podKey("default", "a"), pod); err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+		if pod.ResourceVersion == "" {
+			return fmt.Errorf("create did not stamp a resourceVersion")
+		}
+
+		var got api.Pod
+		if err := s.Get(ctx, podKey("default", "a"), &got); err != nil {
+			return fmt.Errorf("get: %v", err)
+		}
+		if got.Name != "a" || got.Labels["app"] != "web" || got.ResourceVersion != pod.ResourceVersion {
+			return fmt.Errorf("get returned %+v, want %+v", got.ObjectMeta, pod.ObjectMeta)
+		}
+
+		if err := expectErr("create duplicate", s.Create(ctx, podKey("default", "a"), newPod("default", "a", nil)), storage.ErrAlreadyExists); err != nil {
+			return err
+		}
+		return expectErr("get missing", s.Get(ctx, podKey("default", "missing"), &got), storage.ErrNotFound)
+	})
+}
+
+func testUpdate(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		key := podKey("default", "a")
+		pod := newPod("default", "a", nil)
+		if err := s.Create(ctx, key, pod); err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+		stale := pod.ResourceVersion
+
+		pod.Labels = map[string]string{"v": "2"}
+		if err := s.Update(ctx, key, pod); err != nil {
+			return fmt.Errorf("update with current version: %v", err)
+		}
+		if pod.ResourceVersion == stale {
+			return fmt.Errorf("update did not bump the resourceVersion")
+		}
+
+		// A writer that read the old version loses.
+		old := newPod("default", "a", map[string]string{"v": "stale"})
+		old.ResourceVersion = stale
+		if err := expectErr("update with stale version", s.Update(ctx, key, old), storage.ErrConflict); err != nil {
+			return err
+		}
+
+		// Without a version, the update is unconditional.
+		if err := s.Update(ctx, key, newPod("default", "a", map[string]string{"v": "3"})); err != nil {
+			return fmt.Errorf("unconditional update: %v", err)
+		}
+		var got api.Pod
+		if err := s.Get(ctx, key, &got); err != nil {
+			return fmt.Errorf("get: %v", err)
+		}
+		if got.Labels["v"] != "3" {
+			return fmt.Errorf("got labels %v after update, want v=3", got.Labels)
+		}
+
+		return expectErr("update missing", s.Update(ctx, podKey("default", "missing"), newPod("default", "missing", nil)), storage.ErrNotFound)
+	})
+}
+
+func testDelete(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		key := podKey("default", "a")
+		if err := s.Create(ctx, key, newPod("default", "a", nil)); err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+		if err := s.Delete(ctx, key); err != nil {
+			return fmt.Errorf("delete: %v", err)
+		}
+		var got api.Pod
+		if err := expectErr("get deleted", s.Get(ctx, key, &got), storage.ErrNotFound); err != nil {
+			return err
+		}
+		if err := expectErr("delete twice", s.Delete(ctx, key), storage.ErrNotFound); err != nil {
+			return err
+		}
+		// The key is free again.
+		if err := s.Create(ctx, key, newPod("default", "a", nil)); err != nil {
+			return fmt.Errorf("recreate: %v", err)
+		}
+		return nil
+	})
+}
+
+func testRevisions(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		var last int64
+		step := func(op string, version string) error {
+			rev := revision(version)
+			if rev <= last {
+				return fmt.Errorf("%s: revision %d does not follow %d", op, rev, last)
+			}
+			last = rev
+			return nil
+		}
+
+		a, b := newPod("default", "a", nil), newPod("default", "b", nil)
+		if err := s.Create(ctx, podKey("default", "a"), a); err != nil {
+			return fmt.Errorf("create a: %v", err)
+		}
+		if err := step("create a", a.ResourceVersion); err != nil {
+			return err
+		}
+		if err := s.Create(ctx, podKey("default", "b"), b); err != nil {
+			return fmt.Errorf("create b: %v", err)
+		}
+		if err := step("create b", b.ResourceVersion); err != nil {
+			return err
+		}
+		if err := s.Update(ctx, podKey("default", "a"), a); err != nil {
+			return fmt.Errorf("update a: %v", err)
+		}
+		if err := step("update a", a.ResourceVersion); err != nil {
+			return err
+		}
+		if err := s.Delete(ctx, podKey("default", "b")); err != nil {
+			return fmt.Errorf("delete b: %v", err)
+		}
+
+		// Failed writes leave the revision alone; the delete counts.
+		s.Create(ctx, podKey("default", "a"), newPod("default", "a", nil))
+		var pods []api.Pod
+		res, err := s.List(ctx, "/registry/pods/", storage.ListOptions{}, &pods)
+		if err != nil {
+			return fmt.Errorf("list: %v", err)
+		}
+		if got := revision(res.ResourceVersion); got != last+1 {
+			return fmt.Errorf("list revision is %d after a delete at %d, want %d", got, last, last+1)
+		}
+		return nil
+	})
+}
+
+func testListOrder(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		for _, name := range []string{"c", "a", "e", "b", "d"} {
+			if err := s.Create(ctx, podKey("default", name), newPod("default", name, nil)); err != nil {
+				return fmt.Errorf("create %s: %v", name, err)
+			}
+		}
+		var pods []api.Pod
+		if _, err := s.List(ctx, "/registry/pods/default/", storage.ListOptions{}, &pods); err != nil {
+			return fmt.Errorf("list: %v", err)
+		}
+		if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(names(pods), want) {
+			return fmt.Errorf("list returned %v, want %v", names(pods), want)
+		}
+		return nil
+	})
+}
+
+func testListPagination(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		var want []string
+		for i := 0; i < 7; i++ {
+			name := fmt.Sprintf("p%d", i)
+			want = append(want, name)
+			if err := s.Create(ctx, podKey("default", name), newPod("default", name, nil)); err != nil {
+				return fmt.Errorf("create %s: %v", name, err)
+			}
+		}
+
+		var all []string
+		var firstRV string
+		opts := storage.ListOptions{Limit: 3}
+		for page := 0; ; page++ {
+			if page > len(want) {
+				return fmt.Errorf("pagination did not terminate")
+			}
+			var pods []api.Pod
+			res, err := s.List(ctx, "/registry/pods/default/", opts, &pods)
+			if err != nil {
+				return fmt.Errorf("list page %d: %v", page, err)
+			}
+			if int64(len(pods)) > opts.Limit {
+				return fmt.Errorf("page %d has %d items, over the limit of %d", page, len(pods), opts.Limit)
+			}
+			if page == 0 {
+				firstRV = res.ResourceVersion
+				// Writes between pages don't shift the remaining ones.
+				if err := s.Create(ctx, podKey("default", "a-early"), newPod("default", "a-early", nil)); err != nil {
+					return fmt.Errorf("create between pages: %v", err)
+				}
+			} else if res.ResourceVersion != firstRV {
+				return fmt.Errorf("page %d reports version %s, want the first page's %s", page, res.ResourceVersion, firstRV)
+			}
+			all = append(all, names(pods)...)
+			if res.Continue == "" {
+				break
+			}
+			opts.Continue = res.Continue
+		}
+		if !reflect.DeepEqual(all, want) {
+			return fmt.Errorf("pages returned %v, want %v", all, want)
+		}
+
+		var pods []api.Pod
+		_, err := s.List(ctx, "/registry/pods/default/", storage.ListOptions{Continue: "not-a-token"}, &pods)
+		return expectErr("list with a bad continue token", err, storage.ErrInvalidContinue)
+	})
+}
+
+// testListPaginationWrites checks what a list whose pages straddle writes
+// promises: later pages may already show those writes, and a watch from the
+// version the pages report delivers every one of them, so a client that
+// applies the watch to the combined pages ends up with the stored state.
+func testListPaginationWrites(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		for i := 0; i < 6; i++ {
+			name := fmt.Sprintf("p%d", i)
+			if err := s.Create(ctx, podKey("default", name), newPod("default", name, nil)); err != nil {
+				return fmt.Errorf("create %s: %v", name, err)
+			}
+		}
+
+		seen := make(map[string]string) // name -> resourceVersion
+		var order []string
+		opts := storage.ListOptions{Limit: 3}
+		var listRV string
+		for page := 0; ; page++ {
+			var pods []api.Pod
+			res, err := s.List(ctx, "/registry/pods/default/", opts, &pods)
+			if err != nil {
+				return fmt.Errorf("list page %d: %v", page, err)
+			}
+			for _, p := range pods {
+				seen[p.Name] = p.ResourceVersion
+				order = append(order, p.Name)
+			}
+			if page == 0 {
+				listRV = res.ResourceVersion
+				// One change each to a listed key, a key still to come and
+				// a new key on the next page.
+				p1 := newPod("default", "p1", map[string]string{"changed": "true"})
+				if err := s.Update(ctx, podKey("default", "p1"), p1); err != nil {
+					return fmt.Errorf("update p1: %v", err)
+				}
+				if err := s.Delete(ctx, podKey("default", "p5")); err != nil {
+					return fmt.Errorf("delete p5: %v", err)
+				}
+				if err := s.Create(ctx, podKey("default", "p3x"), newPod("default", "p3x", nil)); err != nil {
+					return fmt.Errorf("create p3x: %v", err)
+				}
+			} else if res.ResourceVersion != listRV {
+				return fmt.Errorf("page %d reports version %s, want the first page's %s", page, res.ResourceVersion, listRV)
+			}
+			if res.Continue == "" {
+				break
+			}
+			opts.Continue = res.Continue
+		}
+		if want := []string{"p0", "p1", "p2", "p3", "p3x", "p4"}; !reflect.DeepEqual(order, want) {
+			return fmt.Errorf("pages returned %v, want %v", order, want)
+		}
+
+		w, err := s.Watch(ctx, "/registry/pods/default/", storage.ListOptions{ResourceVersion: listRV})
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+		defer w.Stop()
+		for i := 0; i < 3; i++ {
+			eventType, pod, err := nextEvent(w)
+			if err != nil {
+				return err
+			}
+			if eventType == storage.Deleted {
+				delete(seen, pod.Name)
+			} else {
+				seen[pod.Name] = pod.ResourceVersion
+			}
+		}
+
+		var pods []api.Pod
+		if _, err := s.List(ctx, "/registry/pods/default/", storage.ListOptions{}, &pods); err != nil {
+			return fmt.Errorf("list: %v", err)
+		}
+		want := make(map[string]string)
+		for _, p := range pods {
+			want[p.Name] = p.ResourceVersion
+		}
+		if !reflect.DeepEqual(seen, want) {
+			return fmt.Errorf("pages and watch add up to %v, want the stored %v", seen, want)
+		}
+		return nil
+	})
+}
+
+func testListSelectors(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		for i, app := range []string{"web", "db", "web", "cache"} {
+			name := fmt.Sprintf("p%d", i)
+			if err := s.Create(ctx, podKey("default", name), newPod("default", name, map[string]string{"app": app})); err != nil {
+				return fmt.Errorf("create %s: %v", name, err)
+			}
+		}
+		sel, err := labels.Parse("app in (web,cache)")
+		if err != nil {
+			return err
+		}
+
+		var pods []api.Pod
+		if _, err := s.List(ctx, "/registry/pods/", storage.ListOptions{LabelSelector: sel}, &pods); err != nil {
+			return fmt.Errorf("list: %v", err)
+		}
+		if want := []string{"p0", "p2", "p3"}; !reflect.DeepEqual(names(pods), want) {
+			return fmt.Errorf("selector list returned %v, want %v", names(pods), want)
+		}
+		return nil
+	})
+}
+
+func testWatchFromRevision(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		// An empty store lists at version 0, which watches read as "from now".
+		if err := s.Create(ctx, podKey("default", "seed"), newPod("default", "seed", nil)); err != nil {
+			return fmt.Errorf("create seed: %v", err)
+		}
+		var pods []api.Pod
+		res, err := s.List(ctx, "/registry/pods/", storage.ListOptions{}, &pods)
+		if err != nil {
+			return fmt.Errorf("list: %v", err)
+		}
+
+		key := podKey("default", "a")
+		pod := newPod("default", "a", nil)
+		if err := s.Create(ctx, key, pod); err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+		if err := s.Update(ctx, key, pod); err != nil {
+			return fmt.Errorf("update: %v", err)
+		}
+		if err := s.Delete(ctx, key); err != nil {
+			return fmt.Errorf("delete: %v", err)
+		}
+
+		// Watching from the list's version replays everything after it.
+		w, err := s.Watch(ctx, "/registry/pods/", storage.ListOptions{ResourceVersion: res.ResourceVersion})
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+		defer w.Stop()
+		for _, want := range []storage.EventType{storage.Added, storage.Modified, storage.Deleted} {
+			select {
+			case ev, ok := <-w.ResultChan():
+				if !ok {
+					return fmt.Errorf("watch closed, want %s", want)
+				}
+				if ev.Type != want {
+					return fmt.Errorf("got %s event, want %s", ev.Type, want)
+				}
+			case <-time.After(5 * time.Second):
+				return fmt.Errorf("timed out waiting for %s", want)
+			}
+		}
+		return nil
+	})
+}
+
+// testWatchFutureRevision checks that a watch from a version the store
+// hasn't reached is refused, rather than started from the current one, which
+// would skip every change up to the version asked for.
+func testWatchFutureRevision(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		if err := s.Create(ctx, podKey("default", "a"), newPod("default", "a", nil)); err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+		var pods []api.Pod
+		res, err := s.List(ctx, "/registry/pods/", storage.ListOptions{}, &pods)
+		if err != nil {
+			return fmt.Errorf("list: %v", err)
+		}
+		future := strconv.FormatInt(revision(res.ResourceVersion)+5, 10)
+		w, err := s.Watch(ctx, "/registry/pods/", storage.ListOptions{ResourceVersion: future})
+		if err == nil {
+			w.Stop()
+		}
+		if !errors.Is(err, storage.ErrTooLargeResourceVersion) {
+			return fmt.Errorf("watch from %s at version %s: got %v, want %v", future, res.ResourceVersion, err, storage.ErrTooLargeResourceVersion)
+		}
+
+		// The current version itself is fine.
+		w, err = s.Watch(ctx, "/registry/pods/", storage.ListOptions{ResourceVersion: res.ResourceVersion})
+		if err != nil {
+			return fmt.Errorf("watch from the current version: %v", err)
+		}
+		w.Stop()
+		return nil
+	})
+}
+
+func testListPrefixIsolation(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		keys := []string{
+			"/registry/pods/default/a",
+			"/registry/pods/kube-system/b",
+			"/registry/podsecurity/default/x",
+			"/registry/pods-extra/default/y",
+			"/registry/poddisruptionbudgets/default/z",
+		}
+		for _, key := range keys {
+			if err := s.Create(ctx, key, newPod("", filepath.Base(key), nil)); err != nil {
+				return fmt.Errorf("create %s: %v", key, err)
+			}
+		}
+
+		// No trailing slash: the prefix is still a directory.
+		w, err := s.Watch(ctx, "/registry/pods", storage.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+		defer w.Stop()
+
+		for prefix, want := range map[string][]string{
+			"/registry/pods":              {"a", "b"},
+			"/registry/pods/":             {"a", "b"},
+			"/registry/pods/default":      {"a"},
+			"/registry/pods/default/":     {"a"},
+			"/registry/pods/kube-system/": {"b"},
+			"/registry/podsecurity/":      {"x"},
+			"/registry/pods/other/":       {},
+		} {
+			var pods []api.Pod
+			if _, err := s.List(ctx, prefix, storage.ListOptions{}, &pods); err != nil {
+				return fmt.Errorf("list %s: %v", prefix, err)
+			}
+			if got := names(pods); !reflect.DeepEqual(got, want) {
+				return fmt.Errorf("list %s returned %v, want %v", prefix, got, want)
+			}
+		}
+
+		// A continue token can't lead a list out of its prefix either.
+		var pods []api.Pod
+		token := storage.EncodeContinue(1, "/registry/pods/default/", "/registry/pods/default/../kube-system/")
+		if _, err := s.List(ctx, "/registry/pods/default/", storage.ListOptions{Continue: token}, &pods); err != nil {
+			return fmt.Errorf("list with a token naming a key outside the prefix: %v", err)
+		}
+		if got := names(pods); !reflect.DeepEqual(got, []string{"a"}) {
+			return fmt.Errorf("list with a token naming a key outside the prefix returned %v, want [a]", got)
+		}
+
+		// Changes to look-alike prefixes never reach the watch.
+		if err := s.Delete(ctx, "/registry/podsecurity/default/x"); err != nil {
+			return fmt.Errorf("delete: %v", err)
+		}
+		if err := s.Create(ctx, "/registry/pods-extra/default/y2", newPod("", "y2", nil)); err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+		if err := s.Create(ctx, "/registry/pods/default/c", newPod("", "c", nil)); err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+		eventType, pod, err := nextEvent(w)
+		if err != nil {
+			return err
+		}
+		if eventType != storage.Added || pod.Name != "c" {
+			return fmt.Errorf("watch on /registry/pods got %s %s, want ADDED c", eventType, pod.Name)
+		}
+		return nil
+	})
+}
+
+func testWatchOrdering(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		w, err := s.Watch(ctx, "/registry/pods/", storage.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+		defer w.Stop()
+
+		// Interleave changes to a few keys; the watch must replay them in
+		// the order they were made.
+		type change struct {
+			eventType storage.EventType
+			name      string
+		}
+		var want []change
+		pods := map[string]*api.Pod{}
+		for i := 0; i < 200; i++ {
+			name := fmt.Sprintf("p%d", i%5)
+			key := podKey("default", name)
+			var err error
+			var eventType storage.EventType
+			switch pod, ok := pods[name]; {
+			case !ok:
+				pods[name] = newPod("default", name, nil)
+				eventType, err = storage.Added, s.Create(ctx, key, pods[name])
+			case i%7 == 0:
+				delete(pods, name)
+				eventType, err = storage.Deleted, s.Delete(ctx, key)
+			default:
+				pod.Labels = map[string]string{"i": strconv.Itoa(i)}
+				eventType, err = storage.Modified, s.Update(ctx, key, pod)
+			}
+			if err != nil {
+				return fmt.Errorf("change %d to %s: %v", i, name, err)
+			}
+			want = append(want, change{eventType, name})
+		}
+
+		var last int64
+		for i, c := range want {
+			eventType, pod, err := nextEvent(w)
+			if err != nil {
+				return fmt.Errorf("event %d: %v", i, err)
+			}
+			if eventType != c.eventType || pod.Name != c.name {
+				return fmt.Errorf("event %d is %s %s, want %s %s", i, eventType, pod.Name, c.eventType, c.name)
+			}
+			rev := revision(pod.ResourceVersion)
+			if rev <= last {
+				return fmt.Errorf("event %d has version %d, not after %d", i, rev, last)
+			}
+			last = rev
+		}
+		return nil
+	})
+}
+
+func testWatchStop(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		w, err := s.Watch(ctx, "/registry/pods/", storage.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+		// Nobody reads the watch while it fills up; writers must not block.
+		for i := 0; i < 50; i++ {
+			name := fmt.Sprintf("p%d", i)
+			if err := s.Create(ctx, podKey("default", name), newPod("default", name, nil)); err != nil {
+				return fmt.Errorf("create %s: %v", name, err)
+			}
+		}
+
+		w.Stop()
+		w.Stop() // stopping twice is harmless
+		deadline := time.After(watchTimeout)
+		for ok := true; ok; {
+			select {
+			case _, ok = <-w.ResultChan():
+			case <-deadline:
+				return fmt.Errorf("result channel still open after Stop")
+			}
+		}
+
+		// Writes after Stop go through and a new watch works.
+		done := make(chan error, 1)
+		go func() { done <- s.Create(ctx, podKey("default", "after"), newPod("default", "after", nil)) }()
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("create after stop: %v", err)
+			}
+		case <-time.After(watchTimeout):
+			return fmt.Errorf("create blocked after the watch was stopped")
+		}
+
+		w2, err := s.Watch(ctx, "/registry/pods/", storage.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("second watch: %v", err)
+		}
+		defer w2.Stop()
+		if err := s.Delete(ctx, podKey("default", "after")); err != nil {
+			return fmt.Errorf("delete: %v", err)
+		}
+		eventType, pod, err := nextEvent(w2)
+		if err != nil {
+			return err
+		}
+		if eventType != storage.Deleted || pod.Name != "after" {
+			return fmt.Errorf("second watch got %s %s, want DELETED after", eventType, pod.Name)
+		}
+		return nil
+	})
+}
+
+func testWatchSingleKey(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		key := "/registry/leases/kube-system/scheduler"
+		lock := newPod("kube-system", "scheduler", nil)
+		if err := s.Create(ctx, key, lock); err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+
+		w, err := s.Watch(ctx, key, storage.ListOptions{ResourceVersion: lock.ResourceVersion})
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+		defer w.Stop()
+
+		// Neighbours, including one whose name extends the watched one, stay
+		// out of the watch.
+		for _, other := range []string{"scheduler-2", "controller-manager"} {
+			if err := s.Create(ctx, "/registry/leases/kube-system/"+other, newPod("kube-system", other, nil)); err != nil {
+				return fmt.Errorf("create %s: %v", other, err)
+			}
+		}
+		lock.Labels = map[string]string{"holder": "b"}
+		if err := s.Update(ctx, key, lock); err != nil {
+			return fmt.Errorf("update: %v", err)
+		}
+		if err := s.Delete(ctx, key); err != nil {
+			return fmt.Errorf("delete: %v", err)
+		}
+
+		for _, want := range []storage.EventType{storage.Modified, storage.Deleted} {
+			eventType, pod, err := nextEvent(w)
+			if err != nil {
+				return err
+			}
+			if eventType != want || pod.Name != "scheduler" {
+				return fmt.Errorf("single-key watch got %s %s, want %s scheduler", eventType, pod.Name, want)
+			}
+		}
+
+		var leases []api.Pod
+		if _, err := s.List(ctx, "/registry/leases/kube-system/scheduler", storage.ListOptions{}, &leases); err != nil {
+			return fmt.Errorf("list: %v", err)
+		}
+		if len(leases) != 0 {
+			return fmt.Errorf("listing the deleted key returned %v", names(leases))
+		}
+		return nil
+	})
+}
+
+func testConcurrentWrites(ctx context.Context, open OpenFunc, path string) error {
+	return withStore(open, path, func(s storage.Store) error {
+		const workers, perWorker = 8, 25
+
+		// A shared counter, incremented with compare-and-swap retries. Lost
+		// updates show up as a wrong final count.
+		counterKey := podKey("default", "counter")
+		if err := s.Create(ctx, counterKey, newPod("default", "counter", map[string]string{"n": "0"})); err != nil {
+			return fmt.Errorf("create counter: %v", err)
+		}
+
+		w, err := s.Watch(ctx, "/registry/pods/", storage.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+		defer w.Stop()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, workers*2)
+		for i := 0; i < workers; i++ {
+			wg.Add(2)
+			go func(worker int) {
+				defer wg.Done()
+				for j := 0; j < perWorker; j++ {
+					name := fmt.Sprintf("w%d-%d", worker, j)
+					if err := s.Create(ctx, podKey("default", name), newPod("default", name, nil)); err != nil {
+						errs <- fmt.Errorf("create %s: %v", name, err)
+						return
+					}
+					var pods []api.Pod
+					if _, err := s.List(ctx, "/registry/pods/default/", storage.ListOptions{Limit: 10}, &pods); err != nil {
+						errs <- fmt.Errorf("list: %v", err)
+						return
+					}
+				}
+			}(i)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < perWorker; j++ {
+					for {
+						var pod api.Pod
+						if err := s.Get(ctx, counterKey, &pod); err != nil {
+							errs <- fmt.Errorf("get counter: %v", err)
+							return
+						}
+						n, _ := strconv.Atoi(pod.Labels["n"])
+						pod.Labels["n"] = strconv.Itoa(n + 1)
+						err := s.Update(ctx, counterKey, &pod)
+						if err == nil {
+							break
+						}
+						if !errors.Is(err, storage.ErrConflict) {
+							errs <- fmt.Errorf("update counter: %v", err)
+							return
+						}
+					}
+				}
+			}()
+		}
+
+		// Drain the watch while the writers run, checking that versions only
+		// go up.
+		total := workers * perWorker * 2
+		watchErr := make(chan error, 1)
+		go func() {
+			var last int64
+			for i := 0; i < total; i++ {
+				_, pod, err := nextEvent(w)
+				if err != nil {
+					watchErr <- fmt.Errorf("event %d of %d: %v", i, total, err)
+					return
+				}
+				rev := revision(pod.ResourceVersion)
+				if rev <= last {
+					watchErr <- fmt.Errorf("event %d has version %d, not after %d", i, rev, last)
+					return
+				}
+				last = rev
+			}
+			watchErr <- nil
+		}()
+
+		wg.Wait()
+		close(errs)
+		if err := <-errs; err != nil {
+			return err
+		}
+		if err := <-watchErr; err != nil {
+			return err
+		}
+
+		var pods []api.Pod
+		if _, err := s.List(ctx, "/registry/pods/default/", storage.ListOptions{}, &pods); err != nil {
+			return fmt.Errorf("list: %v", err)
+		}
+		if want := workers*perWorker + 1; len(pods) != want {
+			return fmt.Errorf("store has %d objects, want %d", len(pods), want)
+		}
+		var counter api.Pod
+		if err := s.Get(ctx, counterKey, &counter); err != nil {
+			return fmt.Errorf("get counter: %v", err)
+		}
+		if want := strconv.Itoa(workers * perWorker); counter.Labels["n"] != want {
+			return fmt.Errorf("counter is %s, want %s: updates were lost", counter.Labels["n"], want)
+		}
+		return nil
+	})
+}
+
+func testReopen(ctx context.Context, open OpenFunc, path string) error {
+	var lastRV string
+	err := withStore(open, path, func(s storage.Store) error {
+		for _, name := range []string{"a", "b", "c"} {
+			if err := s.Create(ctx, podKey("default", name), newPod("default", name, nil)); err != nil {
+				return fmt.Errorf("create %s: %v", name, err)
+			}
+		}
+		if err := s.Delete(ctx, podKey("default", "b")); err != nil {
+			return fmt.Errorf("delete: %v", err)
+		}
+		c := newPod("default", "c", map[string]string{"v": "2"})
+		if err := s.Update(ctx, podKey("default", "c"), c); err != nil {
+			return fmt.Errorf("update: %v", err)
+		}
+		lastRV = c.ResourceVersion
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return withStore(open, path, func(s storage.Store) error {
+		var pods []api.Pod
+		res, err := s.List(ctx, "/registry/pods/", storage.ListOptions{}, &pods)
+		if err != nil {
+			return fmt.Errorf("list after reopen: %v", err)
+		}
+		if want := []string{"a", "c"}; !reflect.DeepEqual(names(pods), want) {
+			return fmt.Errorf("reopened store has %v, want %v", names(pods), want)
+		}
+		if res.ResourceVersion != lastRV {
+			return fmt.Errorf("reopened store is at version %s, want %s", res.ResourceVersion, lastRV)
+		}
+		if pods[1].Labels["v"] != "2" {
+			return fmt.Errorf("reopened store lost an update: labels %v", pods[1].Labels)
+		}
+
+		// New writes continue from the recovered revision.
+		pod := newPod("default", "d", nil)
+		if err := s.Create(ctx, podKey("default", "d"), pod); err != nil {
+			return fmt.Errorf("create after reopen: %v", err)
+		}
+		if revision(pod.ResourceVersion) <= revision(lastRV) {
+			return fmt.Errorf("revision went back from %s to %s after reopen", lastRV, pod.ResourceVersion)
+		}
+		return nil
+	})
+}
+
+func testBackupRestore(ctx context.Context, open OpenFunc, path string) error {
+	var backup *storage.Backup
+	var lastRV string
+	err := withStore(open, path, func(s storage.Store) error {
+		for _, name := range []string{"a", "b", "c"} {
+			if err := s.Create(ctx, podKey("default", name), newPod("default", name, nil)); err != nil {
+				return fmt.Errorf("create %s: %v", name, err)
+			}
+		}
+		if err := s.Delete(ctx, podKey("default", "b")); err != nil {
+			return fmt.Errorf("delete: %v", err)
+		}
+		node := newPod("", "node1", nil)
+		if err := s.Create(ctx, "/registry/nodes/node1", node); err != nil {
+			return fmt.Errorf("create node: %v", err)
+		}
+		lastRV = node.ResourceVersion
+
+		var err error
+		if backup, err = s.Backup(ctx); err != nil {
+			return fmt.Errorf("backup: %v", err)
+		}
+
+		// Later writes don't leak into the backup.
+		if err := s.Create(ctx, podKey("default", "d"), newPod("default", "d", nil)); err != nil {
+			return fmt.Errorf("create after backup: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if backup.Revision != revision(lastRV) {
+		return fmt.Errorf("backup is at revision %d, want %s", backup.Revision, lastRV)
+	}
+	var keys []string
+	for _, o := range backup.Objects {
+		keys = append(keys, o.Key)
+	}
+	want := []string{"/registry/nodes/node1", podKey("default", "a"), podKey("default", "c")}
+	if !reflect.DeepEqual(keys, want) {
+		return fmt.Errorf("backup has keys %v, want %v", keys, want)
+	}
+	if err := backup.Validate(); err != nil {
+		return fmt.Errorf("backup doesn't validate: %v", err)
+	}
+
+	// Restoring over existing contents either replaces them or fails
+	// without touching them.
+	existing := []string{"x", "y", "z", "zz", "zzz", "zzzz"}
+	err = withStore(open, path+".existing", func(s storage.Store) error {
+		for _, name := range existing {
+			if err := s.Create(ctx, podKey("default", name), newPod("default", name, nil)); err != nil {
+				return fmt.Errorf("create %s: %v", name, err)
+			}
+		}
+		want := []string{"a", "c"}
+		if err := s.Restore(ctx, backup); err != nil {
+			want = existing
+		}
+		var pods []api.Pod
+		if _, err := s.List(ctx, "/registry/pods/", storage.ListOptions{}, &pods); err != nil {
+			return fmt.Errorf("list after restore: %v", err)
+		}
+		if !reflect.DeepEqual(names(pods), want) {
+			return fmt.Errorf("store has %v after restoring over %v, want %v", names(pods), existing, want)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Restoring into an empty store always works, and ends running watches.
+	target := path + ".restored"
+	err = withStore(open, target, func(s storage.Store) error {
+		w, err := s.Watch(ctx, "/registry/pods/", storage.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+		defer w.Stop()
+
+		if err := s.Restore(ctx, backup); err != nil {
+			return fmt.Errorf("restore: %v", err)
+		}
+		if _, _, err := nextEvent(w); err == nil {
+			return fmt.Errorf("watch started before the restore kept running")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The restored contents and revision survive a reopen.
+	return withStore(open, target, func(s storage.Store) error {
+		var pods []api.Pod
+		res, err := s.List(ctx, "/registry/pods/", storage.ListOptions{}, &pods)
+		if err != nil {
+			return fmt.Errorf("list after restore: %v", err)
+		}
+		if want := []string{"a", "c"}; !reflect.DeepEqual(names(pods), want) {
+			return fmt.Errorf("restored store has %v, want %v", names(pods), want)
+		}
+		if res.ResourceVersion != lastRV {
+			return fmt.Errorf("restored store is at version %s, want %s", res.ResourceVersion, lastRV)
+		}
+		if err := s.Get(ctx, "/registry/nodes/node1", &api.Pod{}); err != nil {
+			return fmt.Errorf("get restored node: %v", err)
+		}
+
+		pod := newPod("default", "e", nil)
+		if err := s.Create(ctx, podKey("default", "e"), pod); err != nil {
+			return fmt.Errorf("create after restore: %v", err)
+		}
+		if revision(pod.ResourceVersion) <= revision(lastRV) {
+			return fmt.Errorf("revision went back from %s to %s after restore", lastRV, pod.ResourceVersion)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// The write-ahead log is a sequence of records, each framed as
+//
+//	length (4 bytes, big endian) | CRC-32C of payload (4 bytes) | payload
+//
+// where the payload is a JSON-encoded walRecord. A record is only
+// acknowledged once it has been fsynced, so after a crash the log ends with
+// either a complete record or a torn one, which recovery discards.
+
+const walHeaderSize = 8
+
+// maxWALRecordSize guards recovery against reading a garbage length.
+const maxWALRecordSize = 64 << 20
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornRecord marks the end of the usable log.
+var errTornRecord = errors.New("torn or corrupt WAL record")
+
+// walRecord is a single change. Object is empty for deletions.
+type walRecord struct {
+	Revision int64     `json:"rev"`
+	Type     EventType `json:"type"`
+	Key      string    `json:"key"`
+	Object   []byte    `json:"object,omitempty"`
+}
+
+// wal is an append-only log of changes made since the last snapshot.
+type wal struct {
+	file    *os.File
+	size    int64 // offset just past the last good record
+	records int   // appended since the last truncate
+}
+
+// openWAL opens the log at path and calls fn for every intact record in
+// order. A torn or corrupt tail is cut off so new records can be appended
+// after the last good one.
+func openWAL(path string, fn func(walRecord) error) (*wal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &wal{file: f}
+	var good int64
+	r := bufio.NewReader(f)
+	for {
+		rec, n, err := readWALRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err == errTornRecord {
+			fmt.Printf("Warning: discarding torn WAL tail in %s at offset %d\n", path, good)
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if err := fn(rec); err != nil {
+			f.Close()
+			return nil, err
+		}
+		good += n
+		w.records++
+	}
+
+	if err := w.reset(good); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// readWALRecord reads the next record and its size on disk. It returns
+// io.EOF at a clean end of the log and errTornRecord for a partial or
+// corrupt record.
+func readWALRecord(r io.Reader) (walRecord, int64, error) {
+	var header [walHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return walRecord{}, 0, io.EOF
+		}
+		if err == io.ErrUnexpectedEOF {
+			return walRecord{}, 0, errTornRecord
+		}
+		return walRecord{}, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxWALRecordSize {
+		return walRecord{}, 0, errTornRecord
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return walRecord{}, 0, errTornRecord
+		}
+		return walRecord{}, 0, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return walRecord{}, 0, errTornRecord
+	}
+
+	var rec walRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return walRecord{}, 0, errTornRecord
+	}
+	return rec, int64(walHeaderSize + size), nil
+}
+
+// append writes rec and waits for it to reach the disk.
+func (w *wal) append(rec walRecord) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, walHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[walHeaderSize:], payload)
+
+	_, err = w.file.Write(buf)
+	if err == nil {
+		err = w.file.Sync()
+	}
+	if err != nil {
+		// Drop whatever part of the record made it out, so it can't hide
+		// the records appended after it.
+		w.reset(w.size)
+		return err
+	}
+	w.size += int64(len(buf))
+	w.records++
+	return nil
+}
+
+// truncate empties the log once its records are covered by a snapshot.
+func (w *wal) truncate() error {
+	if err := w.reset(0); err != nil {
+		return err
+	}
+	w.records = 0
+	return w.file.Sync()
+}
+
+// reset cuts the log at size and moves the write offset there.
+func (w *wal) reset(size int64) error {
+	if err := w.file.Truncate(size); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	w.size = size
+	return nil
+}
+
+func (w *wal) close() error {
+	return w.file.Close()
+}
+
+// writeFileAtomic replaces path with data so that readers, and a crash at any
+// point, see either the old or the new contents in full.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir makes a rename in dir durable. Windows can't sync directories and
+// doesn't need to.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// historySize is the number of recent changes kept for watch resumption.
+const historySize = 1000
+
+// watchQueueLimit is the number of undelivered events a watcher may have
+// before it is considered too slow and terminated. It leaves room for a full
+// history replay plus a burst of live changes.
+const watchQueueLimit = 2 * historySize
+
+// watchCache keeps the recent changes of a store and fans new ones out to
+// its watchers. It is shared by the Store implementations, which call notify
+// for every committed change in revision order. A store must not run notify
+// and watch concurrently, or a watcher could miss the change in between.
+type watchCache struct {
+	lock     sync.Mutex // guards watchers against Stop
+	history  *eventHistory
+	watchers []*cacheWatcher
+}
+
+func newWatchCache() *watchCache {
+	return &watchCache{history: newEventHistory(historySize)}
+}
+
+// notify records the change made at revision and fans it out.
+// prev is the previous object for Modified changes, used for selector filtering.
+// It never blocks: a watcher whose queue is full is dropped instead.
+func (c *watchCache) notify(revision int64, eventType EventType, key string, data, prev []byte) {
+	c.history.add(historyEntry{revision: revision, eventType: eventType, key: key, object: data, prev: prev})
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	active := c.watchers[:0]
+	for _, w := range c.watchers {
+		if hasKeyPrefix(key, w.keyPrefix) {
+			if t, ok := filterEvent(w.opts, eventType, data, prev); ok && !w.enqueue(Event{Type: t, Object: json.RawMessage(data)}) {
+				continue
+			}
+		}
+		active = append(active, w)
+	}
+	// Clear the tail so dropped watchers can be garbage collected.
+	for i := len(active); i < len(c.watchers); i++ {
+		c.watchers[i] = nil
+	}
+	c.watchers = active
+}
+
+// watch starts a watcher on keyPrefix that first replays the changes made
+// after rev, where current is the store's revision. A rev older than the
+// history yields a watcher that only reports 410 Expired; a rev above current
+// is refused.
+func (c *watchCache) watch(rev, current int64, keyPrefix string, opts ListOptions) (WatchInterface, error) {
+	// A watch from a revision that doesn't exist yet would silently skip
+	// every change up to it.
+	if rev > current {
+		return nil, fmt.Errorf("%w: %d, current: %d", ErrTooLargeResourceVersion, rev, current)
+	}
+
+	// Changes older than the history can't be replayed; the client has to relist.
+	oldest := c.history.oldest()
+	if oldest == 0 {
+		oldest = current + 1
+	}
+	if rev > 0 && rev < oldest-1 {
+		w := &cacheWatcher{resultChan: make(chan Event, 1)}
+		w.resultChan <- Event{Type: Error, Object: expiredStatus(fmt.Sprintf("too old resource version: %d (%d)", rev, oldest-1))}
+		close(w.resultChan)
+		return w, nil
+	}
+
+	w := newCacheWatcher(c, keyPrefix, opts)
+	if rev > 0 {
+		for _, e := range c.history.since(rev) {
+			if !hasKeyPrefix(e.key, keyPrefix) {
+				continue
+			}
+			if eventType, ok := filterEvent(opts, e.eventType, e.object, e.prev); ok {
+				w.queue = append(w.queue, Event{Type: eventType, Object: json.RawMessage(e.object)})
+			}
+		}
+		watchQueueDepth.Add(float64(len(w.queue)))
+	}
+	c.lock.Lock()
+	c.watchers = append(c.watchers, w)
+	c.lock.Unlock()
+	go w.run()
+	return w, nil
+}
+
+// reset forgets the history and expires every watcher. It is used when the
+// store's contents are replaced wholesale, which no event sequence describes.
+func (c *watchCache) reset() {
+	c.history = newEventHistory(historySize)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, w := range c.watchers {
+		w.mu.Lock()
+		if !w.terminated {
+			w.expire("store contents were replaced, please relist")
+			select {
+			case w.wake <- struct{}{}:
+			default:
+			}
+		}
+		w.mu.Unlock()
+	}
+	c.watchers = nil
+}
+
+// cacheWatcher buffers events in its own queue, which a goroutine drains
+// into resultChan, so a slow consumer never stalls writers.
+type cacheWatcher struct {
+	resultChan chan Event
+	keyPrefix  string
+	opts       ListOptions
+	cache      *watchCache // nil for watchers that were expired on creation
+
+	mu         sync.Mutex
+	queue      []Event
+	terminated bool          // no more events are accepted; drain and close
+	wake       chan struct{} // signals run that the queue changed
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+}
+
+func newCacheWatcher(c *watchCache, keyPrefix string, opts ListOptions) *cacheWatcher {
+	watchersActive.Inc()
+	return &cacheWatcher{
+		resultChan: make(chan Event),
+		keyPrefix:  keyPrefix,
+		opts:       opts,
+		cache:      c,
+		wake:       make(chan struct{}, 1),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// enqueue adds an event for delivery. If the queue is full, the pending events
+// are discarded, an Error event is queued in their place and enqueue returns
+// false; the caller must then forget the watcher.
+func (w *cacheWatcher) enqueue(ev Event) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.terminated {
+		return false
+	}
+
+	if len(w.queue) >= watchQueueLimit {
+		watchersDropped.Inc()
+		w.expire("watcher fell too far behind and was terminated, please relist")
+	} else {
+		w.queue = append(w.queue, ev)
+		watchQueueDepth.Inc()
+	}
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return !w.terminated
+}
+
+// expire discards the pending events and queues a 410 Expired error in their
+// place, after which the watcher accepts nothing more. Callers must hold w.mu.
+func (w *cacheWatcher) expire(message string) {
+	watchQueueDepth.Sub(float64(len(w.queue)))
+	w.terminated = true
+	w.queue = []Event{{Type: Error, Object: expiredStatus(message)}}
+	watchQueueDepth.Inc()
+}
+
+// run delivers queued events until the watcher is stopped, or until it has
+// been terminated and the queue is drained.
+func (w *cacheWatcher) run() {
+	defer func() {
+		w.mu.Lock()
+		watchQueueDepth.Sub(float64(len(w.queue)))
+		w.queue = nil
+		w.mu.Unlock()
+		watchersActive.Dec()
+		close(w.resultChan)
+	}()
+
+	for {
+		w.mu.Lock()
+		if len(w.queue) == 0 {
+			terminated := w.terminated
+			w.mu.Unlock()
+			if terminated {
+				return
+			}
+			select {
+			case <-w.wake:
+				continue
+			case <-w.stopCh:
+				return
+			}
+		}
+		ev := w.queue[0]
+		w.queue[0] = Event{}
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+		watchQueueDepth.Dec()
+
+		select {
+		case w.resultChan <- ev:
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+func (w *cacheWatcher) Stop() {
+	if w.cache == nil {
+		return
+	}
+	w.cache.lock.Lock()
+	defer w.cache.lock.Unlock()
+	// Remove self from watchers list
+	for i, watcher := range w.cache.watchers {
+		if watcher == w {
+			w.cache.watchers = append(w.cache.watchers[:i], w.cache.watchers[i+1:]...)
+			break
+		}
+	}
+	w.stopOnce.Do(func() { close(w.stopCh) })
+}
+
+func (w *cacheWatcher) ResultChan() <-chan Event {
+	return w.resultChan
+}
+
+// expiredStatus is the Status of the Error event that ends a watch which
+// can't be resumed, because of message. Its watcher has to relist.
+func expiredStatus(message string) *api.Status {
+	return &api.Status{
+		TypeMeta: api.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   api.StatusFailure,
+		Message:  message,
+		Reason:   api.StatusReasonExpired,
+		Code:     http.StatusGone,
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+func TestWatchExpired(t *testing.T) {
+	tests := []struct {
+		name string
+		// watch starts a watcher on c, whose revision is current, and makes it
+		// expire.
+		watch func(c *watchCache, current int64) WatchInterface
+	}{
+		{
+			name: "resumed from before the history",
+			watch: func(c *watchCache, current int64) WatchInterface {
+				w, _ := c.watch(1, current, "/registry/pods", ListOptions{})
+				return w
+			},
+		},
+		{
+			name: "too slow",
+			watch: func(c *watchCache, current int64) WatchInterface {
+				w, _ := c.watch(0, current, "/registry/pods", ListOptions{})
+				for rev := current + 1; rev <= current+watchQueueLimit+1; rev++ {
+					c.notify(rev, Added, fmt.Sprintf("/registry/pods/default/p%d", rev), []byte(`{}`), nil)
+				}
+				return w
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newWatchCache()
+			current := int64(historySize + 2)
+			for rev := int64(1); rev <= current; rev++ {
+				c.notify(rev, Added, fmt.Sprintf("/registry/pods/default/p%d", rev), []byte(`{}`), nil)
+			}
+			w := tt.watch(c, current)
+			defer w.Stop()
+
+			// A slow watcher may still deliver the event it was sending when
+			// it expired.
+			var last Event
+			timeout := time.After(5 * time.Second)
+			for done := false; !done; {
+				select {
+				case ev, ok := <-w.ResultChan():
+					if !ok {
+						done = true
+						break
+					}
+					last = ev
+				case <-timeout:
+					t.Fatal("timed out waiting for the watch to end")
+				}
+			}
+
+			status, ok := last.Object.(*api.Status)
+			if last.Type != Error || !ok {
+				t.Fatalf("watch ended with a %s event of %T, want an Error event of *api.Status", last.Type, last.Object)
+			}
+			if status.Kind != "Status" || status.Status != api.StatusFailure || status.Reason != api.StatusReasonExpired || status.Code != http.StatusGone {
+				t.Errorf("got %+v, want a 410 Expired Status", status)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package validation
+
+import "github.com/abhigod/k8s-lite/internal/api"
+
+// Defaults of fields that are left empty. The namespace of an object
+// defaults to the one in the request path, which the apiserver sets before
+// setting the others.
+const (
+	DefaultRestartPolicy  = "Always"
+	DefaultProtocol       = "TCP"
+	DefaultStrategyType   = "RollingUpdate"
+	DefaultMaxUnavailable = "25%"
+	DefaultMaxSurge       = "25%"
+)
+
+// SetPodDefaults fills in the empty fields of pod that have a default.
+func SetPodDefaults(pod *api.Pod) {
+	setPodSpecDefaults(&pod.Spec)
+}
+
+// SetReplicaSetDefaults fills in the empty fields of rs that have a default.
+func SetReplicaSetDefaults(rs *api.ReplicaSet) {
+	setPodSpecDefaults(&rs.Spec.Template.Spec)
+}
+
+// SetDeploymentDefaults fills in the empty fields of d that have a default.
+func SetDeploymentDefaults(d *api.Deployment) {
+	setPodSpecDefaults(&d.Spec.Template.Spec)
+	setStrategyDefaults(&d.Spec.Strategy)
+}
+
+// SetServiceDefaults fills in the empty fields of svc that have a default.
+func SetServiceDefaults(svc *api.Service) {
+	for i := range svc.Spec.Ports {
+		if svc.Spec.Ports[i].Protocol == "" {
+			svc.Spec.Ports[i].Protocol = DefaultProtocol
+		}
+	}
+}
+
+func setPodSpecDefaults(spec *api.PodSpec) {
+	if spec.RestartPolicy == "" {
+		spec.RestartPolicy = DefaultRestartPolicy
+	}
+	for i := range spec.Containers {
+		for j := range spec.Containers[i].Ports {
+			if spec.Containers[i].Ports[j].Protocol == "" {
+				spec.Containers[i].Ports[j].Protocol = DefaultProtocol
+			}
+		}
+	}
+}
+
+func setStrategyDefaults(strategy *api.DeploymentStrategy) {
+	if strategy.Type == "" {
+		strategy.Type = DefaultStrategyType
+	}
+	if strategy.Type != DefaultStrategyType {
+		return
+	}
+	if strategy.RollingUpdate == nil {
+		strategy.RollingUpdate = &api.RollingUpdateDeployment{}
+	}
+	if strategy.RollingUpdate.MaxUnavailable == nil {
+		strategy.RollingUpdate.MaxUnavailable = &api.IntOrString{Type: 1, StrVal: DefaultMaxUnavailable}
+	}
+	if strategy.RollingUpdate.MaxSurge == nil {
+		strategy.RollingUpdate.MaxSurge = &api.IntOrString{Type: 1, StrVal: DefaultMaxSurge}
+	}
+}
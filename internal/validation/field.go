@@ -0,0 +1,136 @@
+// Package validation checks API objects before the apiserver stores them and
+// fills in the defaults of fields left empty. Every problem found is reported
+// as an Error naming the field, so a client sees all of them at once.
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// Path is the path of a field, e.g. "spec.containers[0].name".
+type Path string
+
+// NewPath returns the path of a top-level field.
+func NewPath(name string) Path { return Path(name) }
+
+func (p Path) Child(name string) Path {
+	if p == "" {
+		return Path(name)
+	}
+	return p + "." + Path(name)
+}
+
+func (p Path) Index(i int) Path { return Path(fmt.Sprintf("%s[%d]", p, i)) }
+
+func (p Path) Key(key string) Path { return Path(fmt.Sprintf("%s[%s]", p, key)) }
+
+func (p Path) String() string { return string(p) }
+
+// ErrorType says what is wrong with a field. It is the type of the cause the
+// field is reported as, see Error.Cause.
+type ErrorType = api.CauseType
+
+const (
+	ErrorTypeRequired     = api.CauseTypeFieldValueRequired
+	ErrorTypeInvalid      = api.CauseTypeFieldValueInvalid
+	ErrorTypeNotSupported = api.CauseTypeFieldValueNotSupported
+	ErrorTypeDuplicate    = api.CauseTypeFieldValueDuplicate
+	ErrorTypeForbidden    = api.CauseTypeFieldValueForbidden
+)
+
+// Error is one invalid field.
+type Error struct {
+	Type     ErrorType
+	Field    string
+	BadValue interface{}
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	return e.Field + ": " + e.Message()
+}
+
+// Message describes the problem without naming the field, e.g.
+// `Invalid value: -1: must be greater than or equal to 0`.
+func (e *Error) Message() string {
+	var msg string
+	switch e.Type {
+	case ErrorTypeRequired:
+		msg = "Required value"
+	case ErrorTypeForbidden:
+		msg = "Forbidden"
+	case ErrorTypeDuplicate:
+		msg = fmt.Sprintf("Duplicate value: %s", formatValue(e.BadValue))
+	case ErrorTypeNotSupported:
+		msg = fmt.Sprintf("Unsupported value: %s", formatValue(e.BadValue))
+	default:
+		msg = fmt.Sprintf("Invalid value: %s", formatValue(e.BadValue))
+	}
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// Cause returns e as it is reported to clients, as a cause of an Invalid
+// api.Status.
+func (e *Error) Cause() api.StatusCause {
+	return api.StatusCause{Type: e.Type, Message: e.Message(), Field: e.Field}
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func Required(field Path, detail string) *Error {
+	return &Error{Type: ErrorTypeRequired, Field: field.String(), Detail: detail}
+}
+
+func Invalid(field Path, value interface{}, detail string) *Error {
+	return &Error{Type: ErrorTypeInvalid, Field: field.String(), BadValue: value, Detail: detail}
+}
+
+func NotSupported(field Path, value interface{}, supported []string) *Error {
+	quoted := make([]string, len(supported))
+	for i, s := range supported {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return &Error{Type: ErrorTypeNotSupported, Field: field.String(), BadValue: value, Detail: "supported values: " + strings.Join(quoted, ", ")}
+}
+
+func Duplicate(field Path, value interface{}) *Error {
+	return &Error{Type: ErrorTypeDuplicate, Field: field.String(), BadValue: value}
+}
+
+func Forbidden(field Path, detail string) *Error {
+	return &Error{Type: ErrorTypeForbidden, Field: field.String(), Detail: detail}
+}
+
+// ErrorList is every invalid field of an object.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	if len(l) == 1 {
+		return l[0].Error()
+	}
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return "[" + strings.Join(msgs, ", ") + "]"
+}
+
+// Causes returns every error of l as a cause of an Invalid api.Status.
+func (l ErrorList) Causes() []api.StatusCause {
+	causes := make([]api.StatusCause, len(l))
+	for i, e := range l {
+		causes[i] = e.Cause()
+	}
+	return causes
+}
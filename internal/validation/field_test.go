@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// The causes of an Invalid status are what clients parse, so their encoding
+// must not change.
+func TestErrorListCauses(t *testing.T) {
+	errs := ErrorList{
+		Required(NewPath("spec").Child("containers"), ""),
+		Invalid(NewPath("spec").Child("replicas"), -1, "must be greater than or equal to 0"),
+		NotSupported(NewPath("spec").Child("type"), "LoadBalancer", supportedServiceTypes),
+	}
+	data, err := json.Marshal(errs.Causes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[` +
+		`{"reason":"FieldValueRequired","message":"Required value","field":"spec.containers"},` +
+		`{"reason":"FieldValueInvalid","message":"Invalid value: -1: must be greater than or equal to 0","field":"spec.replicas"},` +
+		`{"reason":"FieldValueNotSupported","message":"Unsupported value: \"LoadBalancer\": supported values: \"ClusterIP\", \"NodePort\"","field":"spec.type"}` +
+		`]`
+	if string(data) != want {
+		t.Errorf("got causes\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		path Path
+		want string
+	}{
+		{NewPath("spec"), "spec"},
+		{NewPath("spec").Child("containers").Index(0).Child("name"), "spec.containers[0].name"},
+		{NewPath("metadata").Child("labels").Key("app"), "metadata.labels[app]"},
+		{Path("").Child("spec"), "spec"},
+	}
+	for _, tt := range tests {
+		if got := tt.path.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
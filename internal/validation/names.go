@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	dns1123LabelMaxLength     = 63
+	dns1123SubdomainMaxLength = 253
+	labelValueMaxLength       = 63
+	qualifiedNameMaxLength    = 63
+)
+
+var (
+	dns1123LabelPattern     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	dns1123SubdomainPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	qualifiedNamePattern    = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$`)
+	percentPattern          = regexp.MustCompile(`^[0-9]+%$`)
+)
+
+// IsDNS1123Label returns why value isn't a DNS label as in RFC 1123, e.g.
+// "my-name", or nothing if it is one.
+func IsDNS1123Label(value string) []string {
+	var errs []string
+	if len(value) > dns1123LabelMaxLength {
+		errs = append(errs, fmt.Sprintf("must be no more than %d characters", dns1123LabelMaxLength))
+	}
+	if !dns1123LabelPattern.MatchString(value) {
+		errs = append(errs, "a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character")
+	}
+	return errs
+}
+
+// IsDNS1123Subdomain returns why value isn't a DNS subdomain as in RFC 1123,
+// e.g. "example.com", or nothing if it is one.
+func IsDNS1123Subdomain(value string) []string {
+	var errs []string
+	if len(value) > dns1123SubdomainMaxLength {
+		errs = append(errs, fmt.Sprintf("must be no more than %d characters", dns1123SubdomainMaxLength))
+	}
+	if !dns1123SubdomainPattern.MatchString(value) {
+		errs = append(errs, "a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character")
+	}
+	return errs
+}
+
+// IsQualifiedName returns why value can't be a label or annotation key, e.g.
+// "app" or "example.com/app", or nothing if it can.
+func IsQualifiedName(value string) []string {
+	name := value
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		prefix := value[:i]
+		name = value[i+1:]
+		if prefix == "" {
+			return []string{"prefix part must be non-empty"}
+		}
+		if msgs := IsDNS1123Subdomain(prefix); len(msgs) > 0 {
+			return []string{"prefix part " + msgs[0]}
+		}
+	}
+	var errs []string
+	if name == "" {
+		return append(errs, "name part must be non-empty")
+	}
+	if len(name) > qualifiedNameMaxLength {
+		errs = append(errs, fmt.Sprintf("name part must be no more than %d characters", qualifiedNameMaxLength))
+	}
+	if !qualifiedNamePattern.MatchString(name) {
+		errs = append(errs, "name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character")
+	}
+	return errs
+}
+
+// IsValidLabelValue returns why value can't be a label value, or nothing if
+// it can. The empty string is a valid value.
+func IsValidLabelValue(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var errs []string
+	if len(value) > labelValueMaxLength {
+		errs = append(errs, fmt.Sprintf("must be no more than %d characters", labelValueMaxLength))
+	}
+	if !qualifiedNamePattern.MatchString(value) {
+		errs = append(errs, "a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character")
+	}
+	return errs
+}
+
+// IsValidPortNum returns why port isn't a port number, or nothing if it is.
+func IsValidPortNum(port int) []string {
+	if port < 1 || port > 65535 {
+		return []string{"must be between 1 and 65535, inclusive"}
+	}
+	return nil
+}
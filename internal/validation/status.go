@@ -0,0 +1,121 @@
+package validation
+
+import (
+	"net"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// The status validators check what a status write, through the status
+// subresource, may set. Everything else of the object is as stored.
+
+var (
+	supportedPodPhases       = []string{"Pending", "Running", "Succeeded", "Failed", "Unknown"}
+	supportedConditionStatus = []string{"True", "False", "Unknown"}
+)
+
+func ValidatePodStatus(pod *api.Pod) ErrorList {
+	var errs ErrorList
+	statusPath := NewPath("status")
+	if pod.Status.Phase != "" && !contains(supportedPodPhases, pod.Status.Phase) {
+		errs = append(errs, NotSupported(statusPath.Child("phase"), pod.Status.Phase, supportedPodPhases))
+	}
+	types := make(map[string]bool)
+	for i, c := range pod.Status.Conditions {
+		errs = append(errs, validateCondition(c.Type, c.Status, types, statusPath.Child("conditions").Index(i))...)
+	}
+	for _, ip := range []struct {
+		name, value string
+	}{{"hostIP", pod.Status.HostIP}, {"podIP", pod.Status.PodIP}} {
+		if ip.value != "" && net.ParseIP(ip.value) == nil {
+			errs = append(errs, Invalid(statusPath.Child(ip.name), ip.value, "must be a valid IP address"))
+		}
+	}
+	for i, cs := range pod.Status.ContainerStatuses {
+		csPath := statusPath.Child("containerStatuses").Index(i)
+		if cs.Name == "" {
+			errs = append(errs, Required(csPath.Child("name"), ""))
+		}
+		if cs.RestartCount < 0 {
+			errs = append(errs, Invalid(csPath.Child("restartCount"), cs.RestartCount, "must be greater than or equal to 0"))
+		}
+	}
+	return errs
+}
+
+func ValidateNodeStatus(node *api.Node) ErrorList {
+	var errs ErrorList
+	types := make(map[string]bool)
+	for i, c := range node.Status.Conditions {
+		errs = append(errs, validateCondition(c.Type, c.Status, types, NewPath("status").Child("conditions").Index(i))...)
+	}
+	return errs
+}
+
+func ValidateReplicaSetStatus(rs *api.ReplicaSet) ErrorList {
+	statusPath := NewPath("status")
+	errs := validateNonNegative(rs.Status.ObservedGeneration, statusPath.Child("observedGeneration"))
+	for _, c := range []struct {
+		name  string
+		value int32
+	}{
+		{"replicas", rs.Status.Replicas},
+		{"fullyLabeledReplicas", rs.Status.FullyLabeledReplicas},
+		{"readyReplicas", rs.Status.ReadyReplicas},
+		{"availableReplicas", rs.Status.AvailableReplicas},
+	} {
+		errs = append(errs, validateNonNegative(int64(c.value), statusPath.Child(c.name))...)
+	}
+	return errs
+}
+
+func ValidateDeploymentStatus(d *api.Deployment) ErrorList {
+	statusPath := NewPath("status")
+	errs := validateNonNegative(d.Status.ObservedGeneration, statusPath.Child("observedGeneration"))
+	for _, c := range []struct {
+		name  string
+		value int32
+	}{
+		{"replicas", d.Status.Replicas},
+		{"updatedReplicas", d.Status.UpdatedReplicas},
+		{"readyReplicas", d.Status.ReadyReplicas},
+		{"availableReplicas", d.Status.AvailableReplicas},
+		{"unavailableReplicas", d.Status.UnavailableReplicas},
+	} {
+		errs = append(errs, validateNonNegative(int64(c.value), statusPath.Child(c.name))...)
+	}
+	return errs
+}
+
+func ValidateServiceStatus(svc *api.Service) ErrorList {
+	var errs ErrorList
+	for i, ing := range svc.Status.LoadBalancer.Ingress {
+		if ing.IP != "" && net.ParseIP(ing.IP) == nil {
+			errs = append(errs, Invalid(NewPath("status").Child("loadBalancer").Child("ingress").Index(i).Child("ip"), ing.IP, "must be a valid IP address"))
+		}
+	}
+	return errs
+}
+
+// validateCondition checks a condition of type typ, recording its type in
+// seen so that a type can only appear once.
+func validateCondition(typ, status string, seen map[string]bool, path Path) ErrorList {
+	var errs ErrorList
+	if typ == "" {
+		errs = append(errs, Required(path.Child("type"), ""))
+	} else if seen[typ] {
+		errs = append(errs, Duplicate(path.Child("type"), typ))
+	}
+	seen[typ] = true
+	if !contains(supportedConditionStatus, status) {
+		errs = append(errs, NotSupported(path.Child("status"), status, supportedConditionStatus))
+	}
+	return errs
+}
+
+func validateNonNegative(value int64, path Path) ErrorList {
+	if value < 0 {
+		return ErrorList{Invalid(path, value, "must be greater than or equal to 0")}
+	}
+	return nil
+}
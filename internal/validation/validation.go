@@ -0,0 +1,347 @@
+package validation
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+	"github.com/abhigod/k8s-lite/internal/labels"
+)
+
+var (
+	supportedRestartPolicies = []string{"Always", "OnFailure", "Never"}
+	supportedProtocols       = []string{"TCP", "UDP"}
+	supportedServiceTypes    = []string{"ClusterIP", "NodePort"}
+	supportedStrategyTypes   = []string{"RollingUpdate", "Recreate"}
+)
+
+// ValidateObjectMeta checks the name, namespace, labels and annotations of an
+// object. nameFn is IsDNS1123Label or IsDNS1123Subdomain, whichever the kind
+// requires of its names.
+func ValidateObjectMeta(meta *api.ObjectMeta, namespaced bool, nameFn func(string) []string, path Path) ErrorList {
+	var errs ErrorList
+	if meta.Name == "" {
+		errs = append(errs, Required(path.Child("name"), "name is required"))
+	} else {
+		for _, msg := range nameFn(meta.Name) {
+			errs = append(errs, Invalid(path.Child("name"), meta.Name, msg))
+		}
+	}
+	if namespaced {
+		for _, msg := range IsDNS1123Label(meta.Namespace) {
+			errs = append(errs, Invalid(path.Child("namespace"), meta.Namespace, msg))
+		}
+	} else if meta.Namespace != "" {
+		errs = append(errs, Forbidden(path.Child("namespace"), "not allowed on this type"))
+	}
+	errs = append(errs, validateLabels(meta.Labels, path.Child("labels"))...)
+	for _, k := range sortedKeys(meta.Annotations) {
+		for _, msg := range IsQualifiedName(k) {
+			errs = append(errs, Invalid(path.Child("annotations"), k, msg))
+		}
+	}
+	return errs
+}
+
+func validateLabels(lbls map[string]string, path Path) ErrorList {
+	var errs ErrorList
+	for _, k := range sortedKeys(lbls) {
+		for _, msg := range IsQualifiedName(k) {
+			errs = append(errs, Invalid(path, k, msg))
+		}
+		for _, msg := range IsValidLabelValue(lbls[k]) {
+			errs = append(errs, Invalid(path.Key(k), lbls[k], msg))
+		}
+	}
+	return errs
+}
+
+func ValidateNamespace(ns *api.Namespace) ErrorList {
+	return ValidateObjectMeta(&ns.ObjectMeta, false, IsDNS1123Label, NewPath("metadata"))
+}
+
+func ValidatePod(pod *api.Pod) ErrorList {
+	errs := ValidateObjectMeta(&pod.ObjectMeta, true, IsDNS1123Subdomain, NewPath("metadata"))
+	return append(errs, validatePodSpec(&pod.Spec, NewPath("spec"))...)
+}
+
+func validatePodSpec(spec *api.PodSpec, path Path) ErrorList {
+	var errs ErrorList
+	if len(spec.Containers) == 0 {
+		errs = append(errs, Required(path.Child("containers"), "at least one container is required"))
+	}
+	names := make(map[string]bool)
+	for i, c := range spec.Containers {
+		cPath := path.Child("containers").Index(i)
+		if c.Name == "" {
+			errs = append(errs, Required(cPath.Child("name"), ""))
+		} else {
+			for _, msg := range IsDNS1123Label(c.Name) {
+				errs = append(errs, Invalid(cPath.Child("name"), c.Name, msg))
+			}
+			if names[c.Name] {
+				errs = append(errs, Duplicate(cPath.Child("name"), c.Name))
+			}
+			names[c.Name] = true
+		}
+		if c.Image == "" {
+			errs = append(errs, Required(cPath.Child("image"), ""))
+		}
+		for j, p := range c.Ports {
+			pPath := cPath.Child("ports").Index(j)
+			for _, msg := range IsValidPortNum(p.ContainerPort) {
+				errs = append(errs, Invalid(pPath.Child("containerPort"), p.ContainerPort, msg))
+			}
+			errs = append(errs, validateProtocol(p.Protocol, pPath.Child("protocol"))...)
+		}
+		errs = append(errs, validateProbe(c.LivenessProbe, cPath.Child("livenessProbe"))...)
+		errs = append(errs, validateProbe(c.ReadinessProbe, cPath.Child("readinessProbe"))...)
+	}
+	if !contains(supportedRestartPolicies, spec.RestartPolicy) {
+		errs = append(errs, NotSupported(path.Child("restartPolicy"), spec.RestartPolicy, supportedRestartPolicies))
+	}
+	return errs
+}
+
+func validateProbe(probe *api.Probe, path Path) ErrorList {
+	if probe == nil {
+		return nil
+	}
+	var errs ErrorList
+	switch {
+	case probe.HTTPGet != nil && probe.TCPSocket != nil:
+		errs = append(errs, Forbidden(path.Child("tcpSocket"), "may not specify more than 1 handler type"))
+	case probe.HTTPGet != nil:
+		errs = append(errs, validatePortNumOrName(probe.HTTPGet.Port, path.Child("httpGet").Child("port"))...)
+	case probe.TCPSocket != nil:
+		errs = append(errs, validatePortNumOrName(probe.TCPSocket.Port, path.Child("tcpSocket").Child("port"))...)
+	default:
+		errs = append(errs, Required(path, "must specify a handler type"))
+	}
+	if probe.InitialDelaySeconds < 0 {
+		errs = append(errs, Invalid(path.Child("initialDelaySeconds"), probe.InitialDelaySeconds, "must be greater than or equal to 0"))
+	}
+	if probe.PeriodSeconds < 0 {
+		errs = append(errs, Invalid(path.Child("periodSeconds"), probe.PeriodSeconds, "must be greater than or equal to 0"))
+	}
+	return errs
+}
+
+func validatePortNumOrName(port api.IntOrString, path Path) ErrorList {
+	if port.Type == 0 {
+		var errs ErrorList
+		for _, msg := range IsValidPortNum(int(port.IntVal)) {
+			errs = append(errs, Invalid(path, port.IntVal, msg))
+		}
+		return errs
+	}
+	if port.StrVal == "" {
+		return ErrorList{Required(path, "must be a port number or name")}
+	}
+	return nil
+}
+
+func validateProtocol(protocol string, path Path) ErrorList {
+	if !contains(supportedProtocols, protocol) {
+		return ErrorList{NotSupported(path, protocol, supportedProtocols)}
+	}
+	return nil
+}
+
+func ValidateNode(node *api.Node) ErrorList {
+	errs := ValidateObjectMeta(&node.ObjectMeta, false, IsDNS1123Subdomain, NewPath("metadata"))
+	if node.Spec.PodCIDR != "" {
+		if _, _, err := net.ParseCIDR(node.Spec.PodCIDR); err != nil {
+			errs = append(errs, Invalid(NewPath("spec").Child("podCIDR"), node.Spec.PodCIDR, "must be a CIDR, e.g. 10.244.1.0/24"))
+		}
+	}
+	return errs
+}
+
+func ValidateReplicaSet(rs *api.ReplicaSet) ErrorList {
+	errs := ValidateObjectMeta(&rs.ObjectMeta, true, IsDNS1123Subdomain, NewPath("metadata"))
+	specPath := NewPath("spec")
+	errs = append(errs, validateReplicas(rs.Spec.Replicas, specPath.Child("replicas"))...)
+	return append(errs, validateSelectorAndTemplate(rs.Spec.Selector, &rs.Spec.Template, specPath)...)
+}
+
+func ValidateDeployment(d *api.Deployment) ErrorList {
+	errs := ValidateObjectMeta(&d.ObjectMeta, true, IsDNS1123Subdomain, NewPath("metadata"))
+	specPath := NewPath("spec")
+	errs = append(errs, validateReplicas(d.Spec.Replicas, specPath.Child("replicas"))...)
+	errs = append(errs, validateSelectorAndTemplate(d.Spec.Selector, &d.Spec.Template, specPath)...)
+	return append(errs, validateStrategy(&d.Spec.Strategy, specPath.Child("strategy"))...)
+}
+
+func validateReplicas(replicas *int32, path Path) ErrorList {
+	if replicas != nil && *replicas < 0 {
+		return ErrorList{Invalid(path, *replicas, "must be greater than or equal to 0")}
+	}
+	return nil
+}
+
+// validateSelectorAndTemplate checks the selector of a workload and the pod
+// template it creates pods from, which must be selected by it.
+func validateSelectorAndTemplate(selector api.LabelSelector, template *api.PodTemplateSpec, specPath Path) ErrorList {
+	var errs ErrorList
+	templatePath := specPath.Child("template")
+	sel, err := labels.SelectorFromLabelSelector(selector)
+	if err != nil {
+		errs = append(errs, Invalid(specPath.Child("selector"), selector, err.Error()))
+	} else if sel.Empty() {
+		errs = append(errs, Required(specPath.Child("selector"), "empty selector is not allowed"))
+	} else if !sel.Matches(template.Labels) {
+		errs = append(errs, Invalid(templatePath.Child("metadata").Child("labels"), template.Labels, "`selector` does not match template `labels`"))
+	}
+	errs = append(errs, validateLabels(template.Labels, templatePath.Child("metadata").Child("labels"))...)
+	errs = append(errs, validatePodSpec(&template.Spec, templatePath.Child("spec"))...)
+	if rp := template.Spec.RestartPolicy; rp != "Always" && contains(supportedRestartPolicies, rp) {
+		errs = append(errs, NotSupported(templatePath.Child("spec").Child("restartPolicy"), rp, []string{"Always"}))
+	}
+	return errs
+}
+
+func validateStrategy(strategy *api.DeploymentStrategy, path Path) ErrorList {
+	var errs ErrorList
+	if !contains(supportedStrategyTypes, strategy.Type) {
+		errs = append(errs, NotSupported(path.Child("type"), strategy.Type, supportedStrategyTypes))
+	}
+	ru := strategy.RollingUpdate
+	if ru == nil {
+		return errs
+	}
+	if strategy.Type == "Recreate" {
+		return append(errs, Forbidden(path.Child("rollingUpdate"), "may not be specified when strategy `type` is 'Recreate'"))
+	}
+	ruPath := path.Child("rollingUpdate")
+	errs = append(errs, validateIntOrPercent(ru.MaxUnavailable, ruPath.Child("maxUnavailable"))...)
+	errs = append(errs, validateIntOrPercent(ru.MaxSurge, ruPath.Child("maxSurge"))...)
+	if isZero(ru.MaxUnavailable) && isZero(ru.MaxSurge) {
+		errs = append(errs, Invalid(ruPath.Child("maxUnavailable"), ru.MaxUnavailable.String(), "may not be 0 when `maxSurge` is 0"))
+	}
+	return errs
+}
+
+// validateIntOrPercent checks a non-negative count, or a percentage of up to
+// 100%.
+func validateIntOrPercent(v *api.IntOrString, path Path) ErrorList {
+	if v == nil {
+		return nil
+	}
+	if v.Type == 0 {
+		if v.IntVal < 0 {
+			return ErrorList{Invalid(path, v.IntVal, "must be greater than or equal to 0")}
+		}
+		return nil
+	}
+	if !percentPattern.MatchString(v.StrVal) {
+		return ErrorList{Invalid(path, v.StrVal, "must be an integer or percentage (e.g '5%')")}
+	}
+	var pct int
+	fmt.Sscanf(v.StrVal, "%d%%", &pct)
+	if pct > 100 {
+		return ErrorList{Invalid(path, v.StrVal, "must not be greater than 100%")}
+	}
+	return nil
+}
+
+func isZero(v *api.IntOrString) bool {
+	return v != nil && (v.Type == 0 && v.IntVal == 0 || v.Type == 1 && strings.TrimLeft(v.StrVal, "0") == "%")
+}
+
+func ValidateService(svc *api.Service) ErrorList {
+	errs := ValidateObjectMeta(&svc.ObjectMeta, true, IsDNS1123Label, NewPath("metadata"))
+	specPath := NewPath("spec")
+	if svc.Spec.Type != "" && !contains(supportedServiceTypes, svc.Spec.Type) {
+		errs = append(errs, NotSupported(specPath.Child("type"), svc.Spec.Type, supportedServiceTypes))
+	}
+	if ip := svc.Spec.ClusterIP; ip != "" && ip != "None" && net.ParseIP(ip) == nil {
+		errs = append(errs, Invalid(specPath.Child("clusterIP"), ip, "must be empty, 'None' or a valid IP address"))
+	}
+	errs = append(errs, validateLabels(svc.Spec.Selector, specPath.Child("selector"))...)
+
+	if len(svc.Spec.Ports) == 0 {
+		errs = append(errs, Required(specPath.Child("ports"), ""))
+	}
+	names := make(map[string]bool)
+	for i, p := range svc.Spec.Ports {
+		pPath := specPath.Child("ports").Index(i)
+		if p.Name == "" && len(svc.Spec.Ports) > 1 {
+			errs = append(errs, Required(pPath.Child("name"), "must be specified when there is more than one port"))
+		} else if p.Name != "" {
+			for _, msg := range IsDNS1123Label(p.Name) {
+				errs = append(errs, Invalid(pPath.Child("name"), p.Name, msg))
+			}
+			if names[p.Name] {
+				errs = append(errs, Duplicate(pPath.Child("name"), p.Name))
+			}
+			names[p.Name] = true
+		}
+		for _, msg := range IsValidPortNum(int(p.Port)) {
+			errs = append(errs, Invalid(pPath.Child("port"), p.Port, msg))
+		}
+		errs = append(errs, validateProtocol(p.Protocol, pPath.Child("protocol"))...)
+		// A targetPort of 0 means the same as port.
+		if p.TargetPort.Type != 0 || p.TargetPort.IntVal != 0 {
+			errs = append(errs, validatePortNumOrName(p.TargetPort, pPath.Child("targetPort"))...)
+		}
+		if p.NodePort != 0 {
+			for _, msg := range IsValidPortNum(int(p.NodePort)) {
+				errs = append(errs, Invalid(pPath.Child("nodePort"), p.NodePort, msg))
+			}
+		}
+	}
+	return errs
+}
+
+func ValidateEndpoints(ep *api.Endpoints) ErrorList {
+	errs := ValidateObjectMeta(&ep.ObjectMeta, true, IsDNS1123Subdomain, NewPath("metadata"))
+	for i, subset := range ep.Subsets {
+		sPath := NewPath("subsets").Index(i)
+		for j, addr := range subset.Addresses {
+			if net.ParseIP(addr.IP) == nil {
+				errs = append(errs, Invalid(sPath.Child("addresses").Index(j).Child("ip"), addr.IP, "must be a valid IP address"))
+			}
+		}
+		for j, p := range subset.Ports {
+			pPath := sPath.Child("ports").Index(j)
+			for _, msg := range IsValidPortNum(int(p.Port)) {
+				errs = append(errs, Invalid(pPath.Child("port"), p.Port, msg))
+			}
+			if p.Protocol != "" {
+				errs = append(errs, validateProtocol(p.Protocol, pPath.Child("protocol"))...)
+			}
+		}
+	}
+	return errs
+}
+
+func ValidateLease(lease *api.Lease) ErrorList {
+	errs := ValidateObjectMeta(&lease.ObjectMeta, true, IsDNS1123Subdomain, NewPath("metadata"))
+	if d := lease.Spec.LeaseDurationSeconds; d != nil && *d <= 0 {
+		errs = append(errs, Invalid(NewPath("spec").Child("leaseDurationSeconds"), *d, "must be greater than 0"))
+	}
+	return errs
+}
+
+// sortedKeys returns the keys of m in order, so errors come out in the same
+// order every time.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
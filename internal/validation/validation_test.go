@@ -0,0 +1,336 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abhigod/k8s-lite/internal/api"
+)
+
+// fieldErrors summarizes errs as the type and field of each.
+func fieldErrors(errs ErrorList) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, string(e.Type)+" "+e.Field)
+	}
+	return out
+}
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func validPodSpec() api.PodSpec {
+	return api.PodSpec{
+		Containers: []api.Container{{
+			Name:  "web",
+			Image: "nginx",
+			Ports: []api.ContainerPort{{ContainerPort: 80, Protocol: "TCP"}},
+		}},
+		RestartPolicy: "Always",
+	}
+}
+
+func TestValidatePod(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(pod *api.Pod)
+		want   []string
+	}{
+		{name: "valid", update: func(pod *api.Pod) {}},
+		{
+			name:   "missing name and containers",
+			update: func(pod *api.Pod) { pod.Name, pod.Spec.Containers = "", nil },
+			want:   []string{"FieldValueRequired metadata.name", "FieldValueRequired spec.containers"},
+		},
+		{
+			name:   "invalid name",
+			update: func(pod *api.Pod) { pod.Name = "Web_1" },
+			want:   []string{"FieldValueInvalid metadata.name"},
+		},
+		{
+			name: "duplicate container names",
+			update: func(pod *api.Pod) {
+				pod.Spec.Containers = append(pod.Spec.Containers, api.Container{Name: "web", Image: "nginx"})
+			},
+			want: []string{"FieldValueDuplicate spec.containers[1].name"},
+		},
+		{
+			name: "bad port and protocol",
+			update: func(pod *api.Pod) {
+				pod.Spec.Containers[0].Ports[0] = api.ContainerPort{ContainerPort: 70000, Protocol: "SCTP"}
+			},
+			want: []string{"FieldValueInvalid spec.containers[0].ports[0].containerPort", "FieldValueNotSupported spec.containers[0].ports[0].protocol"},
+		},
+		{
+			name: "probe with two handlers",
+			update: func(pod *api.Pod) {
+				pod.Spec.Containers[0].LivenessProbe = &api.Probe{
+					HTTPGet:   &api.HTTPGetAction{Port: api.IntOrString{IntVal: 80}},
+					TCPSocket: &api.TCPSocketAction{Port: api.IntOrString{IntVal: 80}},
+				}
+			},
+			want: []string{"FieldValueForbidden spec.containers[0].livenessProbe.tcpSocket"},
+		},
+		{
+			name:   "unknown restart policy",
+			update: func(pod *api.Pod) { pod.Spec.RestartPolicy = "Sometimes" },
+			want:   []string{"FieldValueNotSupported spec.restartPolicy"},
+		},
+		{
+			name:   "bad label",
+			update: func(pod *api.Pod) { pod.Labels = map[string]string{"app": "-web"} },
+			want:   []string{"FieldValueInvalid metadata.labels[app]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &api.Pod{ObjectMeta: api.ObjectMeta{Name: "web", Namespace: "default"}, Spec: validPodSpec()}
+			tt.update(pod)
+			if got := fieldErrors(ValidatePod(pod)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDeployment(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(d *api.Deployment)
+		want   []string
+	}{
+		{name: "valid", update: func(d *api.Deployment) {}},
+		{
+			name:   "negative replicas",
+			update: func(d *api.Deployment) { d.Spec.Replicas = int32Ptr(-1) },
+			want:   []string{"FieldValueInvalid spec.replicas"},
+		},
+		{
+			name:   "empty selector",
+			update: func(d *api.Deployment) { d.Spec.Selector = api.LabelSelector{} },
+			want:   []string{"FieldValueRequired spec.selector"},
+		},
+		{
+			name:   "selector doesn't match the template",
+			update: func(d *api.Deployment) { d.Spec.Selector.MatchLabels = map[string]string{"app": "api"} },
+			want:   []string{"FieldValueInvalid spec.template.metadata.labels"},
+		},
+		{
+			name:   "template restart policy",
+			update: func(d *api.Deployment) { d.Spec.Template.Spec.RestartPolicy = "Never" },
+			want:   []string{"FieldValueNotSupported spec.template.spec.restartPolicy"},
+		},
+		{
+			name: "rolling update with nothing to roll",
+			update: func(d *api.Deployment) {
+				zero := &api.IntOrString{IntVal: 0}
+				d.Spec.Strategy.RollingUpdate = &api.RollingUpdateDeployment{MaxUnavailable: zero, MaxSurge: zero}
+			},
+			want: []string{"FieldValueInvalid spec.strategy.rollingUpdate.maxUnavailable"},
+		},
+		{
+			name: "percentage over 100",
+			update: func(d *api.Deployment) {
+				d.Spec.Strategy.RollingUpdate = &api.RollingUpdateDeployment{MaxSurge: &api.IntOrString{Type: 1, StrVal: "150%"}}
+			},
+			want: []string{"FieldValueInvalid spec.strategy.rollingUpdate.maxSurge"},
+		},
+		{
+			name: "rolling update with Recreate",
+			update: func(d *api.Deployment) {
+				d.Spec.Strategy = api.DeploymentStrategy{Type: "Recreate", RollingUpdate: &api.RollingUpdateDeployment{}}
+			},
+			want: []string{"FieldValueForbidden spec.strategy.rollingUpdate"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &api.Deployment{
+				ObjectMeta: api.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: api.DeploymentSpec{
+					Replicas: int32Ptr(3),
+					Selector: api.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					Template: api.PodTemplateSpec{
+						ObjectMeta: api.ObjectMeta{Labels: map[string]string{"app": "web"}},
+						Spec:       validPodSpec(),
+					},
+					Strategy: api.DeploymentStrategy{Type: "RollingUpdate"},
+				},
+			}
+			tt.update(d)
+			if got := fieldErrors(ValidateDeployment(d)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateService(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(svc *api.Service)
+		want   []string
+	}{
+		{name: "valid", update: func(svc *api.Service) {}},
+		{
+			name:   "no ports",
+			update: func(svc *api.Service) { svc.Spec.Ports = nil },
+			want:   []string{"FieldValueRequired spec.ports"},
+		},
+		{
+			name: "unnamed ports",
+			update: func(svc *api.Service) {
+				svc.Spec.Ports = append(svc.Spec.Ports, api.ServicePort{Port: 443, Protocol: "TCP"})
+			},
+			want: []string{"FieldValueRequired spec.ports[0].name", "FieldValueRequired spec.ports[1].name"},
+		},
+		{
+			name:   "unknown type",
+			update: func(svc *api.Service) { svc.Spec.Type = "LoadBalancer" },
+			want:   []string{"FieldValueNotSupported spec.type"},
+		},
+		{
+			name:   "bad cluster IP",
+			update: func(svc *api.Service) { svc.Spec.ClusterIP = "10.0.0" },
+			want:   []string{"FieldValueInvalid spec.clusterIP"},
+		},
+		{
+			name:   "headless",
+			update: func(svc *api.Service) { svc.Spec.ClusterIP = "None" },
+		},
+		{
+			name:   "bad node port",
+			update: func(svc *api.Service) { svc.Spec.Ports[0].NodePort = -1 },
+			want:   []string{"FieldValueInvalid spec.ports[0].nodePort"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &api.Service{
+				ObjectMeta: api.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: api.ServiceSpec{
+					Selector: map[string]string{"app": "web"},
+					Ports:    []api.ServicePort{{Port: 80, Protocol: "TCP"}},
+				},
+			}
+			tt.update(svc)
+			if got := fieldErrors(ValidateService(svc)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateObjectMeta(t *testing.T) {
+	tests := []struct {
+		name       string
+		meta       api.ObjectMeta
+		namespaced bool
+		want       []string
+	}{
+		{name: "namespaced", meta: api.ObjectMeta{Name: "web", Namespace: "default"}, namespaced: true},
+		{name: "cluster-scoped", meta: api.ObjectMeta{Name: "node1"}},
+		{
+			name: "namespace on a cluster-scoped object",
+			meta: api.ObjectMeta{Name: "node1", Namespace: "default"},
+			want: []string{"FieldValueForbidden metadata.namespace"},
+		},
+		{
+			name:       "bad namespace",
+			meta:       api.ObjectMeta{Name: "web", Namespace: "Default"},
+			namespaced: true,
+			want:       []string{"FieldValueInvalid metadata.namespace"},
+		},
+		{
+			name:       "bad annotation key",
+			meta:       api.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{"a b": ""}},
+			namespaced: true,
+			want:       []string{"FieldValueInvalid metadata.annotations"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldErrors(ValidateObjectMeta(&tt.meta, tt.namespaced, IsDNS1123Subdomain, NewPath("metadata")))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		errs ErrorList
+		want []string
+	}{
+		{
+			name: "valid pod",
+			errs: ValidatePodStatus(&api.Pod{Status: api.PodStatus{
+				Phase:      "Running",
+				PodIP:      "10.244.1.5",
+				Conditions: []api.PodCondition{{Type: "Ready", Status: "True"}, {Type: api.PodScheduled, Status: "True"}},
+			}}),
+		},
+		{
+			name: "invalid pod",
+			errs: ValidatePodStatus(&api.Pod{Status: api.PodStatus{
+				Phase:      "Done",
+				PodIP:      "10.244.1",
+				Conditions: []api.PodCondition{{Type: "Ready", Status: "Yes"}, {Type: "Ready", Status: "True"}},
+			}}),
+			want: []string{
+				"FieldValueNotSupported status.phase",
+				"FieldValueNotSupported status.conditions[0].status",
+				"FieldValueDuplicate status.conditions[1].type",
+				"FieldValueInvalid status.podIP",
+			},
+		},
+		{
+			name: "node condition without a type",
+			errs: ValidateNodeStatus(&api.Node{Status: api.NodeStatus{Conditions: []api.NodeCondition{{Status: "True"}}}}),
+			want: []string{"FieldValueRequired status.conditions[0].type"},
+		},
+		{
+			name: "negative replica set counts",
+			errs: ValidateReplicaSetStatus(&api.ReplicaSet{Status: api.ReplicaSetStatus{Replicas: 3, ReadyReplicas: -1}}),
+			want: []string{"FieldValueInvalid status.readyReplicas"},
+		},
+		{
+			name: "negative observed generation",
+			errs: ValidateDeploymentStatus(&api.Deployment{Status: api.DeploymentStatus{ObservedGeneration: -1}}),
+			want: []string{"FieldValueInvalid status.observedGeneration"},
+		},
+		{
+			name: "bad load balancer IP",
+			errs: ValidateServiceStatus(&api.Service{Status: api.ServiceStatus{LoadBalancer: api.LoadBalancerStatus{
+				Ingress: []api.LoadBalancerIngress{{IP: "1.2.3.4"}, {IP: "lb"}},
+			}}}),
+			want: []string{"FieldValueInvalid status.loadBalancer.ingress[1].ip"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldErrors(tt.errs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDeploymentDefaults(t *testing.T) {
+	d := &api.Deployment{Spec: api.DeploymentSpec{Template: api.PodTemplateSpec{Spec: api.PodSpec{
+		Containers: []api.Container{{Name: "web", Ports: []api.ContainerPort{{ContainerPort: 80}}}},
+	}}}}
+	SetDeploymentDefaults(d)
+
+	if got := d.Spec.Template.Spec.RestartPolicy; got != DefaultRestartPolicy {
+		t.Errorf("restartPolicy defaulted to %q, want %q", got, DefaultRestartPolicy)
+	}
+	if got := d.Spec.Template.Spec.Containers[0].Ports[0].Protocol; got != DefaultProtocol {
+		t.Errorf("protocol defaulted to %q, want %q", got, DefaultProtocol)
+	}
+	ru := d.Spec.Strategy.RollingUpdate
+	if d.Spec.Strategy.Type != DefaultStrategyType || ru == nil || ru.MaxSurge.StrVal != DefaultMaxSurge || ru.MaxUnavailable.StrVal != DefaultMaxUnavailable {
+		t.Errorf("strategy defaulted to %+v", d.Spec.Strategy)
+	}
+}
@@ -0,0 +1,173 @@
+package workqueue
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	queueDepth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "workqueue_depth",
+			Help: "Number of keys waiting to be processed",
+		},
+		[]string{"name"},
+	)
+	queueRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workqueue_retries_total",
+			Help: "Total number of keys requeued after a failure",
+		},
+		[]string{"name"},
+	)
+)
+
+const (
+	baseDelay = 100 * time.Millisecond
+	maxDelay  = time.Minute
+)
+
+// Queue is a rate-limited work queue of object keys.
+//
+// A key is never queued twice: adding a key that is already waiting is a
+// no-op, and adding a key that is being processed queues it again only once
+// Done is called, so a single key is never handled by two workers at a time.
+type Queue struct {
+	name string
+
+	cond       *sync.Cond
+	queue      []string
+	dirty      map[string]struct{} // waiting to be processed
+	processing map[string]struct{} // handed out by Get, not yet Done
+	failures   map[string]int
+	shutdown   bool
+}
+
+func New(name string) *Queue {
+	return &Queue{
+		name:       name,
+		cond:       sync.NewCond(&sync.Mutex{}),
+		dirty:      make(map[string]struct{}),
+		processing: make(map[string]struct{}),
+		failures:   make(map[string]int),
+	}
+}
+
+// Add queues key for processing.
+func (q *Queue) Add(key string) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	if q.shutdown {
+		return
+	}
+	if _, ok := q.dirty[key]; ok {
+		return
+	}
+	q.dirty[key] = struct{}{}
+	if _, ok := q.processing[key]; ok {
+		return // Done will requeue it
+	}
+	q.queue = append(q.queue, key)
+	queueDepth.WithLabelValues(q.name).Inc()
+	q.cond.Signal()
+}
+
+// AddAfter queues key once the delay has passed.
+func (q *Queue) AddAfter(key string, delay time.Duration) {
+	if delay <= 0 {
+		q.Add(key)
+		return
+	}
+	time.AfterFunc(delay, func() { q.Add(key) })
+}
+
+// AddRateLimited requeues key after an exponential backoff based on how many
+// times it has failed since the last Forget.
+func (q *Queue) AddRateLimited(key string) {
+	queueRetries.WithLabelValues(q.name).Inc()
+	q.AddAfter(key, q.when(key))
+}
+
+// when records another failure of key and returns how long to wait before
+// retrying it: baseDelay, doubled for every earlier failure, up to maxDelay.
+func (q *Queue) when(key string) time.Duration {
+	q.cond.L.Lock()
+	n := q.failures[key]
+	q.failures[key] = n + 1
+	q.cond.L.Unlock()
+
+	if n >= 20 {
+		return maxDelay
+	}
+	if d := baseDelay << n; d < maxDelay {
+		return d
+	}
+	return maxDelay
+}
+
+// Forget clears the failure history of key, resetting its backoff.
+func (q *Queue) Forget(key string) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	delete(q.failures, key)
+}
+
+// NumRequeues returns how many times key has been requeued since the last Forget.
+func (q *Queue) NumRequeues(key string) int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.failures[key]
+}
+
+// Get blocks until a key is available. The caller must call Done with the
+// key when finished. shutdown is true once the queue has been shut down.
+func (q *Queue) Get() (key string, shutdown bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	for len(q.queue) == 0 && !q.shutdown {
+		q.cond.Wait()
+	}
+	if len(q.queue) == 0 {
+		return "", true
+	}
+
+	key = q.queue[0]
+	q.queue = q.queue[1:]
+	queueDepth.WithLabelValues(q.name).Dec()
+	delete(q.dirty, key)
+	q.processing[key] = struct{}{}
+	return key, false
+}
+
+// Done marks key as processed, requeueing it if it was added meanwhile.
+func (q *Queue) Done(key string) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	delete(q.processing, key)
+	if _, ok := q.dirty[key]; ok && !q.shutdown {
+		q.queue = append(q.queue, key)
+		queueDepth.WithLabelValues(q.name).Inc()
+		q.cond.Signal()
+	}
+}
+
+// Len returns the number of keys waiting to be processed.
+func (q *Queue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return len(q.queue)
+}
+
+// ShutDown stops accepting new keys and wakes up all workers blocked in Get.
+func (q *Queue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.shutdown = true
+	q.cond.Broadcast()
+}
@@ -0,0 +1,161 @@
+package workqueue
+
+import (
+	"testing"
+	"time"
+)
+
+// get returns the next key, failing the test if none arrives within a second.
+func get(t *testing.T, q *Queue) string {
+	t.Helper()
+	keys := make(chan string, 1)
+	go func() {
+		key, _ := q.Get()
+		keys <- key
+	}()
+	select {
+	case key := <-keys:
+		return key
+	case <-time.After(time.Second):
+		q.ShutDown() // unblock the Get above
+		t.Fatal("no key was queued")
+		return ""
+	}
+}
+
+func TestAddDeduplicatesWaitingKeys(t *testing.T) {
+	q := New("test")
+	q.Add("a")
+	q.Add("b")
+	q.Add("a")
+	if n := q.Len(); n != 2 {
+		t.Fatalf("got %d keys queued, want 2", n)
+	}
+	if key := get(t, q); key != "a" {
+		t.Errorf("got %q first, want a", key)
+	}
+	if key := get(t, q); key != "b" {
+		t.Errorf("got %q second, want b", key)
+	}
+	if n := q.Len(); n != 0 {
+		t.Errorf("got %d keys queued after both were taken, want 0", n)
+	}
+}
+
+func TestAddWhileProcessingRequeuesOnDone(t *testing.T) {
+	q := New("test")
+	q.Add("a")
+	key := get(t, q)
+
+	// Added twice while a worker has it: queued once, and only after Done.
+	q.Add("a")
+	q.Add("a")
+	if n := q.Len(); n != 0 {
+		t.Fatalf("got %d keys queued while a is processed, want 0", n)
+	}
+	q.Done(key)
+	if n := q.Len(); n != 1 {
+		t.Fatalf("got %d keys queued after Done, want 1", n)
+	}
+	if key := get(t, q); key != "a" {
+		t.Errorf("got %q, want a", key)
+	}
+	q.Done("a")
+	if n := q.Len(); n != 0 {
+		t.Errorf("got %d keys queued after the second Done, want 0", n)
+	}
+}
+
+func TestAddAfter(t *testing.T) {
+	q := New("test")
+	const delay = 100 * time.Millisecond
+	start := time.Now()
+	q.AddAfter("a", delay)
+	if n := q.Len(); n != 0 {
+		t.Fatalf("got %d keys queued before the delay, want 0", n)
+	}
+	if key := get(t, q); key != "a" {
+		t.Fatalf("got %q, want a", key)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("a was queued after %v, want at least %v", elapsed, delay)
+	}
+
+	q.AddAfter("b", 0)
+	if n := q.Len(); n != 1 {
+		t.Errorf("got %d keys queued after a zero delay, want 1", n)
+	}
+}
+
+func TestRateLimitBackoff(t *testing.T) {
+	q := New("test")
+	want := baseDelay
+	for i := 0; i < 30; i++ {
+		if got := q.when("a"); got != want {
+			t.Fatalf("failure %d: got delay %v, want %v", i+1, got, want)
+		}
+		if want *= 2; want > maxDelay {
+			want = maxDelay
+		}
+	}
+	if n := q.NumRequeues("a"); n != 30 {
+		t.Errorf("got %d requeues, want 30", n)
+	}
+
+	// Keys back off independently.
+	if got := q.when("b"); got != baseDelay {
+		t.Errorf("first failure of b: got delay %v, want %v", got, baseDelay)
+	}
+
+	q.Forget("a")
+	if n := q.NumRequeues("a"); n != 0 {
+		t.Errorf("got %d requeues after Forget, want 0", n)
+	}
+	if got := q.when("a"); got != baseDelay {
+		t.Errorf("failure after Forget: got delay %v, want %v", got, baseDelay)
+	}
+}
+
+func TestAddRateLimited(t *testing.T) {
+	q := New("test")
+	start := time.Now()
+	q.AddRateLimited("a")
+	if key := get(t, q); key != "a" {
+		t.Fatalf("got %q, want a", key)
+	}
+	if elapsed := time.Since(start); elapsed < baseDelay {
+		t.Errorf("a was requeued after %v, want at least %v", elapsed, baseDelay)
+	}
+	if n := q.NumRequeues("a"); n != 1 {
+		t.Errorf("got %d requeues, want 1", n)
+	}
+}
+
+func TestShutDownUnblocksGet(t *testing.T) {
+	q := New("test")
+	done := make(chan bool)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, shutdown := q.Get()
+			done <- shutdown
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	q.ShutDown()
+	for i := 0; i < 2; i++ {
+		select {
+		case shutdown := <-done:
+			if !shutdown {
+				t.Error("Get returned a key from an empty queue")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("ShutDown did not unblock Get")
+		}
+	}
+
+	q.Add("a")
+	if n := q.Len(); n != 0 {
+		t.Errorf("got %d keys queued after ShutDown, want 0", n)
+	}
+}